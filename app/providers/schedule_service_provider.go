@@ -2,6 +2,7 @@ package providers
 
 import (
 	"github.com/zatrano/framework/core"
+	"github.com/zatrano/framework/core/orm"
 )
 
 // ScheduleServiceProvider registers scheduled tasks.
@@ -12,4 +13,7 @@ func (p *ScheduleServiceProvider) Register(app *core.Application) {}
 func (p *ScheduleServiceProvider) Boot(app *core.Application) {
 	// Register scheduled commands here, e.g.:
 	// app.Scheduler().Command("reports", fn).Daily()
+
+	// Prune models registered with orm.RegisterPrunable every day.
+	orm.SchedulePrune(app.Scheduler())
 }
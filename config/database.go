@@ -5,7 +5,10 @@ import "github.com/zatrano/framework/core/env"
 // Database returns database configuration.
 func Database() map[string]any {
 	return map[string]any{
-		"default": env.Get("DB_CONNECTION", "sqlite"),
+		"default":              env.Get("DB_CONNECTION", "sqlite"),
+		"slow_query_ms":        env.GetInt("DB_SLOW_QUERY_MS", 1000),
+		"prevent_lazy_loading": env.GetBool("DB_PREVENT_LAZY_LOADING", false),
+		"sticky_window_ms":     env.GetInt("DB_STICKY_WINDOW_MS", 5000),
 		"connections": map[string]any{
 			"sqlite": map[string]any{
 				"driver":   "sqlite",
@@ -18,6 +21,9 @@ func Database() map[string]any {
 				"database": env.Get("DB_DATABASE", "zatrano"),
 				"username": env.Get("DB_USERNAME", "root"),
 				"password": env.Get("DB_PASSWORD", ""),
+				"read":     env.Get("DB_READ_HOSTS", ""),
+				"write":    env.Get("DB_WRITE_HOSTS", ""),
+				"sticky":   env.GetBool("DB_STICKY", false),
 			},
 			"pgsql": map[string]any{
 				"driver":   "pgsql",
@@ -26,6 +32,9 @@ func Database() map[string]any {
 				"database": env.Get("DB_DATABASE", "zatrano"),
 				"username": env.Get("DB_USERNAME", "root"),
 				"password": env.Get("DB_PASSWORD", ""),
+				"read":     env.Get("DB_READ_HOSTS", ""),
+				"write":    env.Get("DB_WRITE_HOSTS", ""),
+				"sticky":   env.GetBool("DB_STICKY", false),
 			},
 		},
 	}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -30,6 +31,7 @@ import (
 	"github.com/zatrano/framework/core/database"
 	"github.com/zatrano/framework/core/database/migration"
 	"github.com/zatrano/framework/core/database/query"
+	"github.com/zatrano/framework/core/database/schema"
 	"github.com/zatrano/framework/core/database/seeder"
 	"github.com/zatrano/framework/core/docs"
 	"github.com/zatrano/framework/core/encryption"
@@ -260,7 +262,31 @@ func (app *Application) Migrator() (*migration.Migrator, error) {
 	if err != nil {
 		return nil, err
 	}
-	return migration.NewMigrator(db, driver, app.migrations), nil
+	migrator := migration.NewMigrator(db, driver, app.migrations)
+	migrator.SetSchemaPath(app.SchemaDumpPath())
+	return migrator, nil
+}
+
+// SchemaDumpPath returns database/schema/<connection>.sql for the default connection.
+func (app *Application) SchemaDumpPath() string {
+	connection := app.config.GetString("database.default", "sqlite")
+	return app.BasePath("database", "schema", connection+".sql")
+}
+
+// Schema returns a schema builder for the named connection (or the default one).
+func (app *Application) Schema(name ...string) (*schema.Builder, error) {
+	if err := app.ensureDatabase(); err != nil {
+		return nil, err
+	}
+	db, err := app.db.Connection(name...)
+	if err != nil {
+		return nil, err
+	}
+	driver, err := app.db.DriverName(name...)
+	if err != nil {
+		return nil, err
+	}
+	return schema.New(db, driver), nil
 }
 
 // Environment returns the current environment name.
@@ -393,6 +419,7 @@ func (app *Application) Bootstrap() error {
 		trustedproxy.FromEnv(),
 		app.exceptionMiddleware(),
 		middleware.RequestID,
+		app.databaseMiddleware(),
 		middleware.SecurityHeaders,
 		observability.Timing(app.metrics, func(format string, args ...any) {
 			if app.logger != nil {
@@ -650,13 +677,18 @@ func (app *Application) bootDatabase() error {
 			Username: asString(cfgMap["username"]),
 			Password: asString(cfgMap["password"]),
 			Charset:  asString(cfgMap["charset"]),
+			Read:     asStringList(cfgMap["read"]),
+			Write:    asStringList(cfgMap["write"]),
+			Sticky:   asBool(cfgMap["sticky"]),
 		}
 	}
 
 	app.db = database.NewManager(database.Config{
-		Default:     defaultConn,
-		Connections: connections,
+		Default:            defaultConn,
+		Connections:        connections,
+		SlowQueryThreshold: time.Duration(app.config.GetInt("database.slow_query_ms", 1000)) * time.Millisecond,
 	}, app.basePath)
+	app.db.SetLogger(app.logger)
 	app.container.Instance("db", app.db)
 
 	db, err := app.db.DB()
@@ -668,6 +700,16 @@ func (app *Application) bootDatabase() error {
 		return err
 	}
 	orm.Configure(db, driver)
+	orm.SetQueryListener(app.db.QueryListener())
+	orm.PreventLazyLoading(app.config.GetBool("database.prevent_lazy_loading", false))
+	query.SetStickyWindow(time.Duration(app.config.GetInt("database.sticky_window_ms", 5000)) * time.Millisecond)
+	if cfg := connections[defaultConn]; len(cfg.Read) > 0 {
+		reader, err := app.db.ReadConnection()
+		if err != nil {
+			return err
+		}
+		orm.SetReadConnection(reader, cfg.Sticky)
+	}
 	if app.events != nil {
 		orm.SetDispatcher(app.events)
 	}
@@ -686,6 +728,58 @@ func (app *Application) ensureDatabase() error {
 	return nil
 }
 
+// databaseMiddleware tracks writes per request so sticky connections read their own writes.
+//
+// Queries given req.Context() (orm.SaveContext, WithContext) stick only after
+// their own request wrote. orm.Save, orm.Find and other helpers without a
+// context fall back to the sticky window: they read from the primary for
+// database.sticky_window_ms after any write.
+func (app *Application) databaseMiddleware() routing.MiddlewareFunc {
+	return func(next routing.HandlerFunc) routing.HandlerFunc {
+		return func(req *http.Request) *http.Response {
+			req.SetContext(query.TrackWrites(req.Context()))
+			return next(req)
+		}
+	}
+}
+
+func asStringList(value any) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s := asString(item); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		out := make([]string, 0)
+		for _, part := range strings.Split(asString(v), ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+		return out
+	}
+}
+
+func asBool(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	default:
+		return false
+	}
+}
+
 func asString(value any) string {
 	if value == nil {
 		return ""
@@ -21,6 +21,10 @@ func registerDatabaseCommands(console *Application, app *core.Application) {
 		&MakeModelCommand{app: app},
 		&MakeMigrationCommand{app: app},
 		&MakeSeederCommand{app: app},
+		&DBShowCommand{app: app},
+		&DBTableCommand{app: app},
+		&SchemaDumpCommand{app: app},
+		&ModelPruneCommand{app: app},
 	)
 }
 
@@ -28,8 +32,10 @@ type MigrateCommand struct {
 	app *core.Application
 }
 
-func (c *MigrateCommand) Name() string        { return "migrate" }
-func (c *MigrateCommand) Description() string { return "Run the database migrations" }
+func (c *MigrateCommand) Name() string { return "migrate" }
+func (c *MigrateCommand) Description() string {
+	return "Run the database migrations (--pretend prints the SQL)"
+}
 func (c *MigrateCommand) Handle(args []string) error {
 	if err := c.app.Bootstrap(); err != nil {
 		return err
@@ -38,6 +44,7 @@ func (c *MigrateCommand) Handle(args []string) error {
 	if err != nil {
 		return err
 	}
+	migrator.SetPretend(hasFlag(args, "--pretend"))
 	return migrator.Migrate()
 }
 
@@ -55,6 +62,7 @@ func (c *MigrateRollbackCommand) Handle(args []string) error {
 	if err != nil {
 		return err
 	}
+	migrator.SetPretend(hasFlag(args, "--pretend"))
 	return migrator.Rollback()
 }
 
@@ -278,3 +286,12 @@ func toExported(name string) string {
 	}
 	return strings.Join(parts, "")
 }
+
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag {
+			return true
+		}
+	}
+	return false
+}
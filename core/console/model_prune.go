@@ -0,0 +1,70 @@
+package console
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zatrano/framework/core"
+	"github.com/zatrano/framework/core/orm"
+)
+
+type ModelPruneCommand struct{ app *core.Application }
+
+func (c *ModelPruneCommand) Name() string { return "model:prune" }
+func (c *ModelPruneCommand) Description() string {
+	return "Prune models registered with orm.RegisterPrunable (--model=Post, --chunk=1000, --pretend)"
+}
+func (c *ModelPruneCommand) Handle(args []string) error {
+	var names []string
+	chunk := orm.DefaultPruneChunk
+	pretend := false
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--pretend":
+			pretend = true
+		case args[i] == "--model" && i+1 < len(args):
+			names = append(names, strings.Split(args[i+1], ",")...)
+			i++
+		case strings.HasPrefix(args[i], "--model="):
+			names = append(names, strings.Split(strings.TrimPrefix(args[i], "--model="), ",")...)
+		case args[i] == "--chunk" && i+1 < len(args):
+			if n, err := strconv.Atoi(args[i+1]); err == nil {
+				chunk = n
+			}
+			i++
+		case strings.HasPrefix(args[i], "--chunk="):
+			if n, err := strconv.Atoi(strings.TrimPrefix(args[i], "--chunk=")); err == nil {
+				chunk = n
+			}
+		}
+	}
+	if err := c.app.Bootstrap(); err != nil {
+		return err
+	}
+
+	found := false
+	for _, model := range orm.PrunableModels() {
+		if !orm.PrunableNameMatches(model.Name, names) {
+			continue
+		}
+		found = true
+		if pretend {
+			n, err := model.Pretend()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%d [%s] record(s) will be pruned.\n", n, model.Name)
+			continue
+		}
+		n, err := model.Prune(chunk)
+		if err != nil {
+			return fmt.Errorf("prune [%s]: %w", model.Name, err)
+		}
+		fmt.Printf("%d [%s] record(s) pruned.\n", n, model.Name)
+	}
+	if !found {
+		fmt.Println("No prunable models found.")
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package console
+
+import (
+	"fmt"
+
+	"github.com/zatrano/framework/core"
+)
+
+type QueueFailedCommand struct {
+	app *core.Application
+}
+
+func (c *QueueFailedCommand) Name() string        { return "queue:failed" }
+func (c *QueueFailedCommand) Description() string { return "List all of the failed queue jobs" }
+func (c *QueueFailedCommand) Handle(args []string) error {
+	if err := c.app.Bootstrap(); err != nil {
+		return err
+	}
+	jobs, err := c.app.Queue().FailedJobStore().All()
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		fmt.Println("No failed jobs found.")
+		return nil
+	}
+	for _, job := range jobs {
+		fmt.Printf("%d\t%s\t%s\t%s\t%s\t%s\n", job.ID, job.UUID, job.FailedAt.Format("2006-01-02 15:04:05"), job.Queue, job.Job.Name, job.Error)
+	}
+	return nil
+}
+
+type QueueRetryCommand struct {
+	app *core.Application
+}
+
+func (c *QueueRetryCommand) Name() string { return "queue:retry" }
+func (c *QueueRetryCommand) Description() string {
+	return "Push failed queue jobs back onto the queue (queue:retry {id|uuid|all})"
+}
+func (c *QueueRetryCommand) Handle(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("failed job id, uuid or \"all\" required")
+	}
+	if err := c.app.Bootstrap(); err != nil {
+		return err
+	}
+	manager := c.app.Queue()
+	ids := args
+	if args[0] == "all" {
+		jobs, err := manager.FailedJobStore().All()
+		if err != nil {
+			return err
+		}
+		ids = make([]string, 0, len(jobs))
+		for _, job := range jobs {
+			ids = append(ids, job.UUID)
+		}
+	}
+	for _, id := range ids {
+		if err := manager.RetryFailed(id); err != nil {
+			return err
+		}
+		fmt.Printf("Failed job [%s] pushed back onto the queue.\n", id)
+	}
+	return nil
+}
+
+type QueueForgetCommand struct {
+	app *core.Application
+}
+
+func (c *QueueForgetCommand) Name() string { return "queue:forget" }
+func (c *QueueForgetCommand) Description() string {
+	return "Delete a failed queue job (queue:forget {id|uuid})"
+}
+func (c *QueueForgetCommand) Handle(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("failed job id or uuid required")
+	}
+	if err := c.app.Bootstrap(); err != nil {
+		return err
+	}
+	if err := c.app.Queue().FailedJobStore().Forget(args[0]); err != nil {
+		return err
+	}
+	fmt.Println("Failed job deleted successfully.")
+	return nil
+}
+
+type QueueFlushCommand struct {
+	app *core.Application
+}
+
+func (c *QueueFlushCommand) Name() string        { return "queue:flush" }
+func (c *QueueFlushCommand) Description() string { return "Flush all of the failed queue jobs" }
+func (c *QueueFlushCommand) Handle(args []string) error {
+	if err := c.app.Bootstrap(); err != nil {
+		return err
+	}
+	if err := c.app.Queue().FailedJobStore().Flush(); err != nil {
+		return err
+	}
+	fmt.Println("All failed jobs deleted successfully.")
+	return nil
+}
@@ -0,0 +1,145 @@
+package console
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/zatrano/framework/core"
+)
+
+type DBShowCommand struct{ app *core.Application }
+
+func (c *DBShowCommand) Name() string        { return "db:show" }
+func (c *DBShowCommand) Description() string { return "List the tables of a database connection" }
+func (c *DBShowCommand) Handle(args []string) error {
+	if err := c.app.Bootstrap(); err != nil {
+		return err
+	}
+	connection, _ := connectionOption(args)
+	builder, err := c.app.Schema(connection)
+	if err != nil {
+		return err
+	}
+	tables, err := builder.GetTables()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TABLE\tCOLUMNS\tINDEXES\tFOREIGN KEYS")
+	for _, table := range tables {
+		columns, err := builder.GetColumns(table.Name)
+		if err != nil {
+			return err
+		}
+		indexes, err := builder.GetIndexes(table.Name)
+		if err != nil {
+			return err
+		}
+		foreigns, err := builder.GetForeignKeys(table.Name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", table.Name, len(columns), len(indexes), len(foreigns))
+	}
+	return w.Flush()
+}
+
+type DBTableCommand struct{ app *core.Application }
+
+func (c *DBTableCommand) Name() string { return "db:table" }
+func (c *DBTableCommand) Description() string {
+	return "Show the columns, indexes and foreign keys of a table"
+}
+func (c *DBTableCommand) Handle(args []string) error {
+	connection, rest := connectionOption(args)
+	if len(rest) == 0 {
+		return fmt.Errorf("table name required")
+	}
+	table := rest[0]
+	if err := c.app.Bootstrap(); err != nil {
+		return err
+	}
+	builder, err := c.app.Schema(connection)
+	if err != nil {
+		return err
+	}
+	if ok, err := builder.HasTable(table); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("table [%s] does not exist", table)
+	}
+	columns, err := builder.GetColumns(table)
+	if err != nil {
+		return err
+	}
+	indexes, err := builder.GetIndexes(table)
+	if err != nil {
+		return err
+	}
+	foreigns, err := builder.GetForeignKeys(table)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COLUMN\tTYPE\tNULLABLE\tDEFAULT\tATTRIBUTES")
+	for _, col := range columns {
+		dflt := "-"
+		if col.Default != nil {
+			dflt = *col.Default
+		}
+		attrs := make([]string, 0, 2)
+		if col.AutoIncrement {
+			attrs = append(attrs, "autoincrement")
+		}
+		if col.Comment != "" {
+			attrs = append(attrs, "comment: "+col.Comment)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\n", col.Name, col.Type, col.Nullable, dflt, strings.Join(attrs, ", "))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "INDEX\tTYPE\tCOLUMNS")
+	for _, idx := range indexes {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", idx.Name, idx.Type, strings.Join(idx.Columns, ", "))
+	}
+	if len(foreigns) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "FOREIGN KEY\tCOLUMNS\tREFERENCES\tON DELETE\tON UPDATE")
+		for _, fk := range foreigns {
+			refs := fk.ReferencedColumns
+			if len(refs) == 0 {
+				refs = []string{"id"}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s(%s)\t%s\t%s\n", fk.Name, strings.Join(fk.Columns, ", "),
+				fk.ReferencedTable, strings.Join(refs, ", "), orDash(fk.OnDeleteAction), orDash(fk.OnUpdateAction))
+		}
+	}
+	return w.Flush()
+}
+
+// connectionOption extracts --connection=name / --connection name from args.
+func connectionOption(args []string) (string, []string) {
+	connection := ""
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch {
+		case strings.HasPrefix(args[i], "--connection="):
+			connection = strings.TrimPrefix(args[i], "--connection=")
+		case args[i] == "--connection" && i+1 < len(args):
+			connection = args[i+1]
+			i++
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return connection, rest
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,104 @@
+package console
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zatrano/framework/core"
+)
+
+type SchemaDumpCommand struct{ app *core.Application }
+
+func (c *SchemaDumpCommand) Name() string { return "schema:dump" }
+func (c *SchemaDumpCommand) Description() string {
+	return "Dump the database schema and migration history (--prune deletes dumped migrations)"
+}
+func (c *SchemaDumpCommand) Handle(args []string) error {
+	if err := c.app.Bootstrap(); err != nil {
+		return err
+	}
+	migrator, err := c.app.Migrator()
+	if err != nil {
+		return err
+	}
+	path := c.app.SchemaDumpPath()
+	dumped, err := migrator.Dump(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Database schema dumped: %s\n", path)
+	if !hasFlag(args, "--prune") {
+		return nil
+	}
+
+	dir := c.app.BasePath("database", "migrations")
+	pruned := 0
+	for _, name := range dumped {
+		file := filepath.Join(dir, name+".go")
+		if err := os.Remove(file); err == nil {
+			pruned++
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := writeMigrationRegistry(dir); err != nil {
+		return err
+	}
+	fmt.Printf("Pruned %d migration(s); database/migrations/migrations.go regenerated.\n", pruned)
+	return nil
+}
+
+// writeMigrationRegistry rewrites migrations.go to list every migration type
+// (a type with an Up method) left in dir, in file name order.
+func writeMigrationRegistry(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	types := make([]string, 0, len(files))
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if filepath.Base(file) == "migrations.go" || strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		parsed, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return err
+		}
+		for _, decl := range parsed.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Name.Name != "Up" || len(fn.Recv.List) == 0 {
+				continue
+			}
+			recv := fn.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			if ident, ok := recv.(*ast.Ident); ok {
+				types = append(types, ident.Name)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("package migrations\n\nimport \"github.com/zatrano/framework/core/database/migration\"\n\n")
+	buf.WriteString("// All returns application migrations in order.\nfunc All() []migration.Migration {\n\treturn []migration.Migration{\n")
+	for _, name := range types {
+		fmt.Fprintf(&buf, "\t\t&%s{},\n", name)
+	}
+	buf.WriteString("\t}\n}\n")
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "migrations.go"), src, 0o644)
+}
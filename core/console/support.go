@@ -1,18 +1,27 @@
 package console
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/zatrano/framework/core"
+	"github.com/zatrano/framework/core/queue"
 )
 
 func registerSupportCommands(console *Application, app *core.Application) {
 	console.Register(
 		&QueueWorkCommand{app: app},
+		&QueueFailedCommand{app: app},
+		&QueueRetryCommand{app: app},
+		&QueueForgetCommand{app: app},
+		&QueueFlushCommand{app: app},
 		&CacheClearCommand{app: app},
 		&MakeJobCommand{app: app},
 		&MakeMailCommand{app: app},
@@ -25,46 +34,89 @@ type QueueWorkCommand struct {
 	app *core.Application
 }
 
-func (c *QueueWorkCommand) Name() string        { return "queue:work" }
-func (c *QueueWorkCommand) Description() string { return "Start processing jobs on the queue" }
+func (c *QueueWorkCommand) Name() string { return "queue:work" }
+func (c *QueueWorkCommand) Description() string {
+	return "Start processing jobs on the queue (--queue=high,default --concurrency=N --sleep=3 --max-jobs=N --max-time=3600 --memory=128 --stop-when-empty --once)"
+}
 func (c *QueueWorkCommand) Handle(args []string) error {
 	if err := c.app.Bootstrap(); err != nil {
 		return err
 	}
 	once := false
-	queueName := ""
+	opts := queue.WorkerOptions{}
 	for i := 0; i < len(args); i++ {
-		if args[i] == "--once" {
-			once = true
-		}
-		if (args[i] == "--queue" || args[i] == "-q") && i+1 < len(args) {
-			queueName = args[i+1]
+		arg, value, hasValue := strings.Cut(args[i], "=")
+		if !hasValue && (arg == "--queue" || arg == "-q" || arg == "--concurrency" || arg == "--sleep" ||
+			arg == "--max-jobs" || arg == "--max-time" || arg == "--memory") && i+1 < len(args) {
+			value = args[i+1]
 			i++
 		}
+		switch arg {
+		case "--once":
+			once = true
+		case "--stop-when-empty":
+			opts.StopWhenEmpty = true
+		case "--queue", "-q":
+			opts.Queues = strings.Split(value, ",")
+		case "--concurrency":
+			opts.Concurrency, _ = strconv.Atoi(value)
+		case "--sleep":
+			opts.Sleep = parseSeconds(value)
+		case "--max-jobs":
+			opts.MaxJobs, _ = strconv.Atoi(value)
+		case "--max-time":
+			opts.MaxTime = parseSeconds(value)
+		case "--memory":
+			opts.MemoryMB, _ = strconv.ParseUint(value, 10, 64)
+		}
 	}
 
-	for {
-		err := c.app.Queue().Work(queueName)
-		if err != nil {
-			if strings.Contains(err.Error(), "no rows") || err.Error() == "sql: no rows in result set" {
-				if once {
-					fmt.Println("No jobs available.")
-					return nil
-				}
-				time.Sleep(time.Second)
-				continue
-			}
-			fmt.Printf("Job failed: %v\n", err)
-			if once {
-				return err
-			}
-			continue
+	if once {
+		var failure error
+		worker := queue.NewWorker(c.app.Queue(), queue.WorkerOptions{
+			Queues:        opts.Queues,
+			MaxJobs:       1,
+			StopWhenEmpty: true,
+			OnError:       func(err error) { failure = err },
+		})
+		worker.Run(context.Background())
+		if failure != nil {
+			fmt.Printf("Job failed: %v\n", failure)
+			return failure
 		}
-		fmt.Println("Processed a job.")
-		if once {
+		if worker.Processed() == 0 {
+			fmt.Println("No jobs available.")
 			return nil
 		}
+		fmt.Println("Processed a job.")
+		return nil
+	}
+
+	c.app.Events().Listen(queue.EventJobProcessed, func(event any) error {
+		if e, ok := event.(queue.JobProcessed); ok {
+			fmt.Printf("Processed [%s] on [%s] in %s.\n", e.Job.Name, e.Queue, e.Duration.Round(time.Millisecond))
+		}
+		return nil
+	})
+	opts.OnError = func(err error) {
+		fmt.Printf("Job failed: %v\n", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	worker := queue.NewWorker(c.app.Queue(), opts)
+	worker.Run(ctx)
+	fmt.Printf("Worker stopped after %d job(s).\n", worker.Processed())
+	return nil
+}
+
+// parseSeconds reads a duration such as "500ms" or "2m", or a bare number
+// of seconds.
+func parseSeconds(value string) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil {
+		return d
 	}
+	n, _ := strconv.Atoi(value)
+	return time.Duration(n) * time.Second
 }
 
 type CacheClearCommand struct {
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zatrano/framework/core/database/query"
+	"github.com/zatrano/framework/core/log"
+	"github.com/zatrano/framework/core/observability"
+)
+
+// QueryExecuted is dispatched after every statement run through a manager-bound builder.
+type QueryExecuted struct {
+	Connection string
+	SQL        string
+	Bindings   []any
+	Duration   time.Duration
+	Err        error
+}
+
+type queryLogKey struct{}
+
+type queryLog struct {
+	mu      sync.Mutex
+	entries []QueryExecuted
+}
+
+func (l *queryLog) add(event QueryExecuted) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, event)
+}
+
+func (l *queryLog) all() []QueryExecuted {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]QueryExecuted{}, l.entries...)
+}
+
+// WithQueryLog returns a context that records every query executed with it.
+// Bind it to builders via WithContext and read the entries with QueryLogFromContext.
+func WithQueryLog(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, queryLogKey{}, &queryLog{})
+}
+
+// QueryLogFromContext returns the queries recorded on a WithQueryLog context.
+func QueryLogFromContext(ctx context.Context) []QueryExecuted {
+	if ctx == nil {
+		return nil
+	}
+	if l, ok := ctx.Value(queryLogKey{}).(*queryLog); ok {
+		return l.all()
+	}
+	return nil
+}
+
+// Listen registers fn to receive every executed query.
+func (m *Manager) Listen(fn func(QueryExecuted)) {
+	if fn == nil {
+		return
+	}
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// SetLogger sets the logger used for slow query warnings.
+func (m *Manager) SetLogger(logger *log.Logger) {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	m.logger = logger
+}
+
+// SetMetrics feeds query timings into metrics.
+func (m *Manager) SetMetrics(metrics *observability.Metrics) {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	m.metrics = metrics
+}
+
+// SetSlowQueryThreshold overrides Config.SlowQueryThreshold; zero disables slow query logging.
+func (m *Manager) SetSlowQueryThreshold(threshold time.Duration) {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	m.slow = threshold
+}
+
+// EnableQueryLog starts recording every executed query in memory.
+func (m *Manager) EnableQueryLog() {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	m.logging = true
+}
+
+// DisableQueryLog stops recording queries; already logged entries are kept.
+func (m *Manager) DisableQueryLog() {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	m.logging = false
+}
+
+// QueryLog returns the queries recorded since EnableQueryLog.
+func (m *Manager) QueryLog() []QueryExecuted {
+	m.eventsMu.RLock()
+	defer m.eventsMu.RUnlock()
+	return append([]QueryExecuted{}, m.queryLog...)
+}
+
+// FlushQueryLog clears recorded queries.
+func (m *Manager) FlushQueryLog() {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	m.queryLog = nil
+}
+
+// QueryListener returns a query.Builder listener that reports to this manager
+// under the named connection (or the default one).
+func (m *Manager) QueryListener(name ...string) func(query.Executed) {
+	connName := m.connectionName(name...)
+	return func(e query.Executed) {
+		m.dispatchQuery(e.Context, QueryExecuted{
+			Connection: connName,
+			SQL:        e.SQL,
+			Bindings:   e.Bindings,
+			Duration:   e.Duration,
+			Err:        e.Err,
+		})
+	}
+}
+
+func (m *Manager) dispatchQuery(ctx context.Context, event QueryExecuted) {
+	m.eventsMu.Lock()
+	if m.logging {
+		m.queryLog = append(m.queryLog, event)
+	}
+	listeners := append([]func(QueryExecuted){}, m.listeners...)
+	logger, metrics, threshold := m.logger, m.metrics, m.slow
+	m.eventsMu.Unlock()
+
+	if ctx != nil {
+		if l, ok := ctx.Value(queryLogKey{}).(*queryLog); ok {
+			l.add(event)
+		}
+	}
+
+	slow := threshold > 0 && event.Duration >= threshold
+	if metrics != nil {
+		metrics.ObserveQuery(event.Duration, slow)
+	}
+	if slow && logger != nil {
+		logger.Warningf("slow query on [%s] (%.2fms): %s %v",
+			event.Connection, float64(event.Duration.Microseconds())/1000.0, event.SQL, event.Bindings)
+	}
+	for _, fn := range listeners {
+		fn(event)
+	}
+}
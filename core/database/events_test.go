@@ -0,0 +1,77 @@
+package database_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zatrano/framework/core/database"
+	"github.com/zatrano/framework/core/observability"
+)
+
+func TestQueryListenersAndLogs(t *testing.T) {
+	dir := t.TempDir()
+	mgr := database.NewManager(database.Config{
+		Default: "sqlite",
+		Connections: map[string]database.ConnectionConfig{
+			"sqlite": {Driver: "sqlite", Database: filepath.Join(dir, "events.sqlite")},
+		},
+		SlowQueryThreshold: time.Nanosecond,
+	}, dir)
+	defer mgr.Close()
+
+	db, err := mgr.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+
+	var heard []database.QueryExecuted
+	mgr.Listen(func(e database.QueryExecuted) { heard = append(heard, e) })
+	metrics := observability.New()
+	mgr.SetMetrics(metrics)
+	mgr.EnableQueryLog()
+
+	items, err := mgr.Table("items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := items.Insert(map[string]any{"name": "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := database.WithQueryLog(context.Background())
+	items, _ = mgr.Table("items")
+	if _, err := items.WithContext(ctx).Where("name", "a").Get(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(heard) != 2 || heard[0].Connection != "sqlite" || !strings.HasPrefix(heard[1].SQL, "SELECT") {
+		t.Fatalf("heard=%+v", heard)
+	}
+	if heard[1].Bindings[0] != "a" || heard[1].Duration <= 0 {
+		t.Fatalf("select event=%+v", heard[1])
+	}
+	if log := mgr.QueryLog(); len(log) != 2 {
+		t.Fatalf("query log=%+v", log)
+	}
+	if reqLog := database.QueryLogFromContext(ctx); len(reqLog) != 1 || !strings.Contains(reqLog[0].SQL, "WHERE name = ?") {
+		t.Fatalf("request log=%+v", reqLog)
+	}
+	snap := metrics.Snapshot()
+	if snap["queries"] != int64(2) || snap["slow_queries"] != int64(2) {
+		t.Fatalf("metrics=%+v", snap)
+	}
+
+	mgr.FlushQueryLog()
+	mgr.DisableQueryLog()
+	items, _ = mgr.Table("items")
+	_, _ = items.Count()
+	if log := mgr.QueryLog(); len(log) != 0 {
+		t.Fatalf("disabled query log=%+v", log)
+	}
+}
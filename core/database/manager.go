@@ -1,12 +1,19 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/zatrano/framework/core/database/query"
+	"github.com/zatrano/framework/core/log"
+	"github.com/zatrano/framework/core/observability"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
@@ -17,6 +24,8 @@ import (
 type Config struct {
 	Default     string
 	Connections map[string]ConnectionConfig
+	// SlowQueryThreshold logs queries at or above this duration; zero disables it.
+	SlowQueryThreshold time.Duration
 }
 
 // ConnectionConfig describes a single connection.
@@ -28,6 +37,15 @@ type ConnectionConfig struct {
 	Username string
 	Password string
 	Charset  string
+	// Read lists replica hosts; SELECTs built through the manager are spread across them.
+	Read []string
+	// Write lists primary hosts; one is picked when the connection opens.
+	Write []string
+	// Sticky sends reads to the primary after a write on the same TrackWrites
+	// context; the HTTP kernel tracks each request's context. Reads without
+	// one go to the primary for the sticky window after any write (see
+	// query.SetStickyWindow and database.sticky_window_ms).
+	Sticky bool
 }
 
 // Manager manages database connections.
@@ -35,7 +53,16 @@ type Manager struct {
 	mu          sync.RWMutex
 	config      Config
 	connections map[string]*sql.DB
+	readers     map[string]*readPool
 	basePath    string
+
+	eventsMu  sync.RWMutex
+	listeners []func(QueryExecuted)
+	logging   bool
+	queryLog  []QueryExecuted
+	logger    *log.Logger
+	metrics   *observability.Metrics
+	slow      time.Duration
 }
 
 // NewManager creates a database manager.
@@ -43,16 +70,15 @@ func NewManager(cfg Config, basePath string) *Manager {
 	return &Manager{
 		config:      cfg,
 		connections: make(map[string]*sql.DB),
+		readers:     make(map[string]*readPool),
 		basePath:    basePath,
+		slow:        cfg.SlowQueryThreshold,
 	}
 }
 
-// Connection returns a named connection or the default one.
+// Connection returns the write side of a named connection or the default one.
 func (m *Manager) Connection(name ...string) (*sql.DB, error) {
-	connName := m.config.Default
-	if len(name) > 0 && name[0] != "" {
-		connName = name[0]
-	}
+	connName := m.connectionName(name...)
 
 	m.mu.RLock()
 	if db, ok := m.connections[connName]; ok {
@@ -73,6 +99,9 @@ func (m *Manager) Connection(name ...string) (*sql.DB, error) {
 		return nil, fmt.Errorf("database connection [%s] not configured", connName)
 	}
 
+	if len(cfg.Write) > 0 {
+		cfg.Host = cfg.Write[rand.IntN(len(cfg.Write))]
+	}
 	db, err := m.open(cfg)
 	if err != nil {
 		return nil, err
@@ -81,12 +110,52 @@ func (m *Manager) Connection(name ...string) (*sql.DB, error) {
 	return db, nil
 }
 
+// ReadConnection returns the read side of a named connection. Each statement
+// picks one of the configured Read hosts; without replicas the writer is returned.
+func (m *Manager) ReadConnection(name ...string) (query.DBTX, error) {
+	connName := m.connectionName(name...)
+	writer, err := m.Connection(connName)
+	if err != nil {
+		return nil, err
+	}
+	cfg := m.config.Connections[connName]
+	if len(cfg.Read) == 0 {
+		return writer, nil
+	}
+
+	m.mu.RLock()
+	if pool, ok := m.readers[connName]; ok {
+		m.mu.RUnlock()
+		return pool, nil
+	}
+	m.mu.RUnlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if pool, ok := m.readers[connName]; ok {
+		return pool, nil
+	}
+	pool := &readPool{}
+	for _, host := range cfg.Read {
+		hostCfg := cfg
+		hostCfg.Host = host
+		db, err := m.open(hostCfg)
+		if err != nil {
+			_ = pool.close()
+			return nil, err
+		}
+		pool.dbs = append(pool.dbs, db)
+	}
+	m.readers[connName] = pool
+	return pool, nil
+}
+
 // DB returns the default connection.
 func (m *Manager) DB() (*sql.DB, error) {
 	return m.Connection()
 }
 
-// Transaction runs fn inside a database transaction.
+// Transaction runs fn inside a database transaction on the write connection.
 // Commits on nil error; rolls back otherwise (including panics).
 func (m *Manager) Transaction(fn func(tx *sql.Tx) error, name ...string) (err error) {
 	db, err := m.Connection(name...)
@@ -121,6 +190,12 @@ func (m *Manager) Close() error {
 		}
 		delete(m.connections, name)
 	}
+	for name, pool := range m.readers {
+		if err := pool.close(); err != nil && first == nil {
+			first = err
+		}
+		delete(m.readers, name)
+	}
 	return first
 }
 
@@ -181,10 +256,7 @@ func (m *Manager) dsn(cfg ConnectionConfig) (string, string, error) {
 
 // DriverName returns the driver for a connection.
 func (m *Manager) DriverName(name ...string) (string, error) {
-	connName := m.config.Default
-	if len(name) > 0 && name[0] != "" {
-		connName = name[0]
-	}
+	connName := m.connectionName(name...)
 	cfg, ok := m.config.Connections[connName]
 	if !ok {
 		return "", fmt.Errorf("database connection [%s] not configured", connName)
@@ -192,6 +264,48 @@ func (m *Manager) DriverName(name ...string) (string, error) {
 	return strings.ToLower(cfg.Driver), nil
 }
 
+func (m *Manager) connectionName(name ...string) string {
+	if len(name) > 0 && name[0] != "" {
+		return name[0]
+	}
+	return m.config.Default
+}
+
+// readPool spreads statements across replica connections.
+type readPool struct {
+	dbs []*sql.DB
+}
+
+func (p *readPool) pick() *sql.DB {
+	return p.dbs[rand.IntN(len(p.dbs))]
+}
+
+func (p *readPool) Exec(query string, args ...any) (sql.Result, error) {
+	return p.pick().Exec(query, args...)
+}
+
+func (p *readPool) Query(query string, args ...any) (*sql.Rows, error) {
+	return p.pick().Query(query, args...)
+}
+
+func (p *readPool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return p.pick().ExecContext(ctx, query, args...)
+}
+
+func (p *readPool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return p.pick().QueryContext(ctx, query, args...)
+}
+
+func (p *readPool) close() error {
+	var first error
+	for _, db := range p.dbs {
+		if err := db.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
 func defaultPort(port, fallback string) string {
 	if port == "" {
 		return fallback
@@ -0,0 +1,123 @@
+package migration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// statementSeparator ends every statement in a schema dump.
+const statementSeparator = ";\n\n"
+
+// SetSchemaPath sets the schema dump loaded before migrating an empty database.
+func (m *Migrator) SetSchemaPath(path string) {
+	m.schemaPath = path
+}
+
+// Dump writes the current schema and migration history to path and returns
+// the names of the migrations it contains.
+func (m *Migrator) Dump(path string) ([]string, error) {
+	if err := m.repository.CreateRepository(); err != nil {
+		return nil, err
+	}
+	statements, err := m.schema.Dump("migrations", "migration_locks")
+	if err != nil {
+		return nil, err
+	}
+	ran, err := m.repository.Ran()
+	if err != nil {
+		return nil, err
+	}
+	batches, err := m.repository.Batches()
+	if err != nil {
+		return nil, err
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "-- %s schema dump generated %s\n\n", m.driver, time.Now().UTC().Format(time.RFC3339))
+	for _, statement := range statements {
+		out.WriteString(strings.TrimSpace(statement) + statementSeparator)
+	}
+	for _, name := range ran {
+		fmt.Fprintf(&out, "INSERT INTO migrations (migration, batch) VALUES ('%s', %d)%s",
+			strings.ReplaceAll(name, "'", "''"), batches[name], statementSeparator)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(out.String()), 0o644); err != nil {
+		return nil, err
+	}
+	return ran, nil
+}
+
+// loadSchemaDump runs the schema dump when the database has no tables besides
+// the migration bookkeeping ones. It reports whether a dump was loaded.
+func (m *Migrator) loadSchemaDump() (bool, error) {
+	if m.schemaPath == "" {
+		return false, nil
+	}
+	content, err := os.ReadFile(m.schemaPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	empty, err := m.databaseIsEmpty()
+	if err != nil || !empty {
+		return false, err
+	}
+
+	fmt.Printf("Loading schema: %s\n", m.schemaPath)
+	var statements []string
+	for _, statement := range strings.Split(string(content), statementSeparator) {
+		statement = strings.TrimSpace(stripComments(statement))
+		if statement == "" {
+			continue
+		}
+		if m.pretend {
+			fmt.Printf("schema: %s\n", statement)
+			continue
+		}
+		statements = append(statements, statement)
+	}
+	if m.pretend {
+		return true, nil
+	}
+	if err := m.schema.Load(statements); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (m *Migrator) databaseIsEmpty() (bool, error) {
+	ran, err := m.ran()
+	if err != nil || len(ran) > 0 {
+		return false, err
+	}
+	tables, err := m.schema.GetTables()
+	if err != nil {
+		return false, err
+	}
+	for _, t := range tables {
+		if t.Name != "migrations" && t.Name != "migration_locks" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func stripComments(statement string) string {
+	lines := strings.Split(statement, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
@@ -0,0 +1,87 @@
+package migration_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zatrano/framework/core/database/migration"
+	"github.com/zatrano/framework/core/database/schema"
+)
+
+func TestSchemaDumpLoadsBeforeNewerMigrations(t *testing.T) {
+	users := &testMigration{name: "users", up: func(s *schema.Builder) error {
+		return s.Create("users", func(table *schema.Blueprint) {
+			table.ID()
+			table.String("email").Unique()
+		})
+	}}
+	posts := &testMigration{name: "posts", up: func(s *schema.Builder) error {
+		return s.Create("posts", func(table *schema.Blueprint) {
+			table.ID()
+			table.ForeignID("user_id").Constrained()
+			table.String("title").Index()
+		})
+	}}
+	path := filepath.Join(t.TempDir(), "schema", "sqlite.sql")
+
+	source := openDB(t)
+	dumped, err := migration.NewMigrator(source, "sqlite", []migration.Migration{users, posts}).Dump(path)
+	if err != nil || len(dumped) != 0 {
+		t.Fatalf("empty dump=%v err=%v", dumped, err)
+	}
+	if err := migration.NewMigrator(source, "sqlite", []migration.Migration{users, posts}).Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	dumped, err = migration.NewMigrator(source, "sqlite", nil).Dump(path)
+	if err != nil || strings.Join(dumped, ",") != "users,posts" {
+		t.Fatalf("dumped=%v err=%v", dumped, err)
+	}
+	content, _ := os.ReadFile(path)
+	if strings.Contains(string(content), "CREATE TABLE IF NOT EXISTS migrations") ||
+		!strings.Contains(string(content), "CREATE INDEX posts_title_index") ||
+		!strings.Contains(string(content), "INSERT INTO migrations (migration, batch) VALUES ('posts', 1)") {
+		t.Fatalf("dump:\n%s", content)
+	}
+
+	// users and posts were pruned; only comments is new.
+	commentsRan := false
+	comments := &testMigration{name: "comments", up: func(s *schema.Builder) error {
+		commentsRan = true
+		return s.Create("comments", func(table *schema.Blueprint) {
+			table.ID()
+			table.ForeignID("post_id").Constrained()
+		})
+	}}
+	target := openDB(t)
+	m := migration.NewMigrator(target, "sqlite", []migration.Migration{comments})
+	m.SetSchemaPath(path)
+	if err := m.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if !commentsRan {
+		t.Fatal("newer migration should run after the dump")
+	}
+	b := schema.New(target, "sqlite")
+	for _, table := range []string{"users", "posts", "comments"} {
+		if ok, _ := b.HasTable(table); !ok {
+			t.Fatalf("table %s missing", table)
+		}
+	}
+	var batch int
+	if err := target.QueryRow(`SELECT batch FROM migrations WHERE migration = 'comments'`).Scan(&batch); err != nil || batch != 2 {
+		t.Fatalf("comments batch=%d err=%v", batch, err)
+	}
+
+	// A non-empty database ignores the dump; fresh drops everything and reloads it.
+	if err := m.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Fresh(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := b.HasTable("comments"); !ok {
+		t.Fatal("fresh should reload the dump and rerun comments")
+	}
+}
@@ -0,0 +1,94 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	lockName = "zatrano_migrations"
+	// lockKey is the pg_advisory_lock key (crc32 of lockName).
+	lockKey int64 = 877849626
+	// staleLockAfter releases a sqlite lock row left behind by a crashed process.
+	staleLockAfter = 15 * time.Minute
+)
+
+// lock acquires the migration lock and returns its release func. PostgreSQL
+// and MySQL use session-level advisory locks held on a dedicated connection;
+// SQLite uses a row in migration_locks.
+func (m *Migrator) lock() (func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.lockTimeout)
+	defer cancel()
+
+	switch m.driver {
+	case "pgsql", "postgres", "postgresql":
+		conn, err := m.db.Conn(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("acquire migration lock: %w", err)
+		}
+		return func() {
+			_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+			conn.Close()
+		}, nil
+	case "mysql":
+		conn, err := m.db.Conn(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		var got sql.NullInt64
+		seconds := int(m.lockTimeout.Seconds())
+		if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, ?)`, lockName, seconds).Scan(&got); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("acquire migration lock: %w", err)
+		}
+		if !got.Valid || got.Int64 != 1 {
+			conn.Close()
+			return nil, fmt.Errorf("acquire migration lock: timed out after %s", m.lockTimeout)
+		}
+		return func() {
+			_, _ = conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK(?)`, lockName)
+			conn.Close()
+		}, nil
+	default:
+		return m.lockRow(ctx)
+	}
+}
+
+func (m *Migrator) lockRow(ctx context.Context) (func(), error) {
+	if _, err := m.db.Exec(`CREATE TABLE IF NOT EXISTS migration_locks (
+		name VARCHAR(255) PRIMARY KEY,
+		owner VARCHAR(255) NOT NULL,
+		acquired_at INTEGER NOT NULL
+	)`); err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano())
+	for {
+		_, _ = m.db.Exec(`DELETE FROM migration_locks WHERE name = ? AND acquired_at < ?`,
+			lockName, time.Now().Add(-staleLockAfter).Unix())
+		_, err := m.db.Exec(`INSERT INTO migration_locks (name, owner, acquired_at) VALUES (?, ?, ?)`,
+			lockName, owner, time.Now().Unix())
+		if err == nil {
+			return func() {
+				_, _ = m.db.Exec(`DELETE FROM migration_locks WHERE name = ? AND owner = ?`, lockName, owner)
+			}, nil
+		}
+		if !strings.Contains(strings.ToLower(err.Error()), "unique") {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("acquire migration lock: timed out after %s", m.lockTimeout)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
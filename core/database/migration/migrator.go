@@ -15,33 +15,73 @@ type Migration interface {
 	Down(schema *schema.Builder) error
 }
 
-// Migrator runs migrations.
+// WithinTransaction lets a migration opt out of the per-migration transaction,
+// e.g. for statements such as CREATE INDEX CONCURRENTLY.
+type WithinTransaction interface {
+	WithinTransaction() bool
+}
+
+// Migrator runs migrations. Each migration runs in its own transaction on
+// drivers with transactional DDL (PostgreSQL, SQLite), and every run holds a
+// migration lock so concurrent deploys do not migrate at the same time.
 type Migrator struct {
-	db         *sql.DB
-	driver     string
-	schema     *schema.Builder
-	repository *Repository
-	migrations []Migration
+	db          *sql.DB
+	driver      string
+	schema      *schema.Builder
+	repository  *Repository
+	migrations  []Migration
+	pretend     bool
+	lockTimeout time.Duration
+	schemaPath  string
 }
 
 // NewMigrator creates a migrator.
 func NewMigrator(db *sql.DB, driver string, migrations []Migration) *Migrator {
 	return &Migrator{
-		db:         db,
-		driver:     driver,
-		schema:     schema.New(db, driver),
-		repository: NewRepository(db, driver),
-		migrations: migrations,
+		db:          db,
+		driver:      driver,
+		schema:      schema.New(db, driver),
+		repository:  NewRepository(db, driver),
+		migrations:  migrations,
+		lockTimeout: time.Minute,
+	}
+}
+
+// SetPretend makes Migrate and Rollback print the SQL they would run instead
+// of executing it.
+func (m *Migrator) SetPretend(pretend bool) {
+	m.pretend = pretend
+}
+
+// SetLockTimeout sets how long a run waits for the migration lock (default one minute).
+func (m *Migrator) SetLockTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		m.lockTimeout = timeout
 	}
 }
 
 // Migrate runs outstanding migrations.
+// A pretend run does not create the migrations table.
 func (m *Migrator) Migrate() error {
+	if m.pretend {
+		return m.migrate()
+	}
 	if err := m.repository.CreateRepository(); err != nil {
 		return err
 	}
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return m.migrate()
+}
 
-	ran, err := m.repository.Ran()
+func (m *Migrator) migrate() error {
+	if _, err := m.loadSchemaDump(); err != nil {
+		return err
+	}
+	ran, err := m.ran()
 	if err != nil {
 		return err
 	}
@@ -50,9 +90,11 @@ func (m *Migrator) Migrate() error {
 		ranSet[name] = true
 	}
 
-	batch, err := m.repository.LastBatch()
-	if err != nil {
-		return err
+	batch := 0
+	if !m.pretend {
+		if batch, err = m.repository.LastBatch(); err != nil {
+			return err
+		}
 	}
 	batch++
 
@@ -61,13 +103,20 @@ func (m *Migrator) Migrate() error {
 		if ranSet[migration.Name()] {
 			continue
 		}
+		if m.pretend {
+			if err := m.pretendToRun(migration, migration.Up); err != nil {
+				return err
+			}
+			pending++
+			continue
+		}
 		fmt.Printf("Migrating: %s\n", migration.Name())
-		if err := migration.Up(m.schema); err != nil {
+		err := m.runMigration(migration, migration.Up, func(repo *Repository) error {
+			return repo.Log(migration.Name(), batch)
+		})
+		if err != nil {
 			return fmt.Errorf("%s: %w", migration.Name(), err)
 		}
-		if err := m.repository.Log(migration.Name(), batch); err != nil {
-			return err
-		}
 		fmt.Printf("Migrated:  %s\n", migration.Name())
 		pending++
 	}
@@ -80,8 +129,24 @@ func (m *Migrator) Migrate() error {
 
 // Rollback rolls back the last batch.
 func (m *Migrator) Rollback() error {
-	if err := m.repository.CreateRepository(); err != nil {
-		return err
+	if m.pretend {
+		exists, err := m.repositoryExists()
+		if err != nil {
+			return err
+		}
+		if !exists {
+			fmt.Println("Nothing to rollback.")
+			return nil
+		}
+	} else {
+		if err := m.repository.CreateRepository(); err != nil {
+			return err
+		}
+		unlock, err := m.lock()
+		if err != nil {
+			return err
+		}
+		defer unlock()
 	}
 	batch, err := m.repository.LastBatch()
 	if err != nil {
@@ -108,14 +173,84 @@ func (m *Migrator) Rollback() error {
 		if !ok {
 			return fmt.Errorf("migration [%s] not found", name)
 		}
+		if m.pretend {
+			if err := m.pretendToRun(migration, migration.Down); err != nil {
+				return err
+			}
+			continue
+		}
 		fmt.Printf("Rolling back: %s\n", name)
-		if err := migration.Down(m.schema); err != nil {
-			return err
+		err := m.runMigration(migration, migration.Down, func(repo *Repository) error {
+			return repo.Delete(name)
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		fmt.Printf("Rolled back:  %s\n", name)
+	}
+	return nil
+}
+
+// repositoryExists reports whether the migrations table has been created.
+func (m *Migrator) repositoryExists() (bool, error) {
+	return m.schema.HasTable("migrations")
+}
+
+// ran returns the migrations already run. A pretend run may find no
+// migrations table, since it never creates one.
+func (m *Migrator) ran() ([]string, error) {
+	if m.pretend {
+		if exists, err := m.repositoryExists(); err != nil || !exists {
+			return nil, err
 		}
-		if err := m.repository.Delete(name); err != nil {
+	}
+	return m.repository.Ran()
+}
+
+// runMigration runs step and its bookkeeping, inside a transaction when the
+// driver supports transactional DDL and the migration does not opt out.
+func (m *Migrator) runMigration(migration Migration, step func(*schema.Builder) error, record func(*Repository) error) error {
+	if !m.useTransaction(migration) {
+		if err := step(m.schema); err != nil {
 			return err
 		}
-		fmt.Printf("Rolled back:  %s\n", name)
+		return record(m.repository)
+	}
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := step(schema.New(tx, m.driver)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := record(&Repository{db: tx, driver: m.driver}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) useTransaction(migration Migration) bool {
+	if opt, ok := migration.(WithinTransaction); ok && !opt.WithinTransaction() {
+		return false
+	}
+	switch m.driver {
+	case "pgsql", "postgres", "postgresql", "sqlite", "sqlite3":
+		return true
+	default:
+		// MySQL commits implicitly around DDL, so a transaction would not help.
+		return false
+	}
+}
+
+func (m *Migrator) pretendToRun(migration Migration, step func(*schema.Builder) error) error {
+	builder := m.schema.Pretend()
+	if err := step(builder); err != nil {
+		return fmt.Errorf("%s: %w", migration.Name(), err)
+	}
+	for _, statement := range builder.Pretended() {
+		fmt.Printf("%s: %s\n", migration.Name(), statement)
 	}
 	return nil
 }
@@ -125,6 +260,11 @@ func (m *Migrator) Fresh() error {
 	if err := m.repository.CreateRepository(); err != nil {
 		return err
 	}
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	ran, err := m.repository.Ran()
 	if err != nil {
 		return err
@@ -139,7 +279,16 @@ func (m *Migrator) Fresh() error {
 		}
 	}
 	_ = m.schema.DropIfExists("migrations")
-	return m.Migrate()
+	if m.schemaPath != "" {
+		// Pruned migrations have no Down, so clear whatever the dump created.
+		if err := m.schema.DropAllTables("migration_locks"); err != nil {
+			return err
+		}
+	}
+	if err := m.repository.CreateRepository(); err != nil {
+		return err
+	}
+	return m.migrate()
 }
 
 // Status prints migration status.
@@ -172,12 +321,12 @@ func (m *Migrator) Status() error {
 
 // Repository stores migration history.
 type Repository struct {
-	db     *sql.DB
+	db     schema.Executor
 	driver string
 }
 
 // NewRepository creates a migration repository.
-func NewRepository(db *sql.DB, driver string) *Repository {
+func NewRepository(db schema.Executor, driver string) *Repository {
 	return &Repository{db: db, driver: driver}
 }
 
@@ -0,0 +1,137 @@
+package migration_test
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/database/migration"
+	"github.com/zatrano/framework/core/database/schema"
+)
+
+type testMigration struct {
+	name string
+	up   func(*schema.Builder) error
+}
+
+func (m *testMigration) Name() string                 { return m.name }
+func (m *testMigration) Up(s *schema.Builder) error   { return m.up(s) }
+func (m *testMigration) Down(s *schema.Builder) error { return s.DropIfExists(m.name) }
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+t.Name()+"?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestFailedMigrationRollsBack(t *testing.T) {
+	db := openDB(t)
+	migrations := []migration.Migration{
+		&testMigration{name: "posts", up: func(s *schema.Builder) error {
+			return s.Create("posts", func(table *schema.Blueprint) { table.ID() })
+		}},
+		&testMigration{name: "broken", up: func(s *schema.Builder) error {
+			if err := s.Create("broken", func(table *schema.Blueprint) { table.ID() }); err != nil {
+				return err
+			}
+			return errors.New("boom")
+		}},
+	}
+	err := migration.NewMigrator(db, "sqlite", migrations).Migrate()
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("err=%v", err)
+	}
+
+	b := schema.New(db, "sqlite")
+	if ok, _ := b.HasTable("posts"); !ok {
+		t.Fatal("posts migration should be committed")
+	}
+	if ok, _ := b.HasTable("broken"); ok {
+		t.Fatal("broken migration should be rolled back")
+	}
+	var logged int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM migrations`).Scan(&logged); err != nil || logged != 1 {
+		t.Fatalf("logged=%d err=%v", logged, err)
+	}
+	var locks int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM migration_locks`).Scan(&locks); err != nil || locks != 0 {
+		t.Fatalf("lock not released: locks=%d err=%v", locks, err)
+	}
+}
+
+func TestPretendDoesNotExecute(t *testing.T) {
+	db := openDB(t)
+	migrations := []migration.Migration{
+		&testMigration{name: "posts", up: func(s *schema.Builder) error {
+			return s.Create("posts", func(table *schema.Blueprint) {
+				table.ID()
+				table.String("title").Index()
+			})
+		}},
+	}
+	m := migration.NewMigrator(db, "sqlite", migrations)
+	m.SetPretend(true)
+	if err := m.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := schema.New(db, "sqlite").HasTable("posts"); ok {
+		t.Fatal("pretend should not create tables")
+	}
+	if ok, _ := schema.New(db, "sqlite").HasTable("migrations"); ok {
+		t.Fatal("pretend should not create the migrations table")
+	}
+	if err := m.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := schema.New(db, "sqlite").HasTable("migrations"); ok {
+		t.Fatal("pretend rollback should not create the migrations table")
+	}
+
+	builder := schema.New(db, "sqlite").Pretend()
+	if err := migrations[0].Up(builder); err != nil {
+		t.Fatal(err)
+	}
+	statements := builder.Pretended()
+	if len(statements) != 2 || !strings.HasPrefix(statements[0], "CREATE TABLE posts") ||
+		statements[1] != "CREATE INDEX posts_title_index ON posts (title)" {
+		t.Fatalf("statements=%v", statements)
+	}
+}
+
+func TestMigrateWaitsForLock(t *testing.T) {
+	db := openDB(t)
+	migrations := []migration.Migration{
+		&testMigration{name: "posts", up: func(s *schema.Builder) error {
+			return s.Create("posts", func(table *schema.Blueprint) { table.ID() })
+		}},
+	}
+	m := migration.NewMigrator(db, "sqlite", migrations)
+	if err := m.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO migration_locks (name, owner, acquired_at) VALUES ('zatrano_migrations', 'other', ?)`, time.Now().Unix()); err != nil {
+		t.Fatal(err)
+	}
+	m.SetLockTimeout(200 * time.Millisecond)
+	if err := m.Rollback(); err == nil || !strings.Contains(err.Error(), "migration lock") {
+		t.Fatalf("err=%v", err)
+	}
+	if _, err := db.Exec(`DELETE FROM migration_locks`); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := schema.New(db, "sqlite").HasTable("posts"); ok {
+		t.Fatal("posts should be rolled back")
+	}
+}
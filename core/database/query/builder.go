@@ -1,21 +1,50 @@
 package query
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 )
 
 // DBTX is satisfied by *sql.DB and *sql.Tx.
 type DBTX interface {
 	Exec(query string, args ...any) (sql.Result, error)
 	Query(query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Executed describes a statement run by a Builder.
+type Executed struct {
+	Context  context.Context
+	SQL      string
+	Bindings []any
+	Duration time.Duration
+	Err      error
+}
+
+// Expression is a raw SQL fragment used as a value, e.g. in Update.
+type Expression struct {
+	SQL      string
+	Bindings []any
+}
+
+// Raw wraps sqlStr so Update writes it verbatim: Update(map[string]any{"votes": query.Raw("votes + ?", 1)}).
+func Raw(sqlStr string, bindings ...any) Expression {
+	return Expression{SQL: sqlStr, Bindings: bindings}
 }
 
 // Builder builds SQL queries fluently.
 type Builder struct {
 	db             DBTX
+	reader         DBTX
+	sticky         bool
+	forceWrite     bool
+	ctx            context.Context
+	listeners      []func(Executed)
 	driver         string
 	table          string
 	columns        []string
@@ -25,6 +54,9 @@ type Builder struct {
 	groups         []string
 	havings        []whereClause
 	joins          []string
+	joinBindings   []any
+	fromBindings   []any
+	ctes           []cteClause
 	unions         []unionClause
 	limitN         int
 	offsetN        int
@@ -41,8 +73,10 @@ type whereClause struct {
 }
 
 type orderClause struct {
-	sql  string
-	args []any
+	sql       string
+	args      []any
+	column    string // empty for raw orders
+	direction string
 }
 
 type unionClause struct {
@@ -59,9 +93,33 @@ func New(db DBTX, driver, table string) *Builder {
 	}
 }
 
+// WithContext binds ctx to every statement the builder executes.
+// Cancelling ctx aborts the in-flight query.
+func (b *Builder) WithContext(ctx context.Context) *Builder {
+	b.ctx = ctx
+	return b
+}
+
+// Context returns the bound context or context.Background().
+func (b *Builder) Context() context.Context {
+	if b.ctx == nil {
+		return context.Background()
+	}
+	return b.ctx
+}
+
+// Listen registers fn to receive every statement the builder executes.
+func (b *Builder) Listen(fn func(Executed)) *Builder {
+	if fn != nil {
+		b.listeners = append(b.listeners, fn)
+	}
+	return b
+}
+
 // Table sets the table name.
 func (b *Builder) Table(table string) *Builder {
 	b.table = table
+	b.fromBindings = nil
 	return b
 }
 
@@ -72,6 +130,11 @@ func (b *Builder) Select(columns ...string) *Builder {
 	return b
 }
 
+// Columns returns the current select list; empty means SELECT *.
+func (b *Builder) Columns() []string {
+	return append([]string(nil), b.columns...)
+}
+
 // AddSelect appends columns to the current select list.
 func (b *Builder) AddSelect(columns ...string) *Builder {
 	b.columns = append(b.columns, columns...)
@@ -143,62 +206,62 @@ func (b *Builder) OrWhereNotIn(column string, values []any) *Builder {
 
 // WhereBetween adds a WHERE BETWEEN clause.
 func (b *Builder) WhereBetween(column string, min, max any) *Builder {
-	return b.addRawWhere("and", column+" BETWEEN ? AND ?", min, max)
+	return b.addRawWhere("and", b.wrap(column)+" BETWEEN ? AND ?", min, max)
 }
 
 // OrWhereBetween adds an OR WHERE BETWEEN clause.
 func (b *Builder) OrWhereBetween(column string, min, max any) *Builder {
-	return b.addRawWhere("or", column+" BETWEEN ? AND ?", min, max)
+	return b.addRawWhere("or", b.wrap(column)+" BETWEEN ? AND ?", min, max)
 }
 
 // WhereNotBetween adds a WHERE NOT BETWEEN clause.
 func (b *Builder) WhereNotBetween(column string, min, max any) *Builder {
-	return b.addRawWhere("and", column+" NOT BETWEEN ? AND ?", min, max)
+	return b.addRawWhere("and", b.wrap(column)+" NOT BETWEEN ? AND ?", min, max)
 }
 
 // OrWhereNotBetween adds an OR WHERE NOT BETWEEN clause.
 func (b *Builder) OrWhereNotBetween(column string, min, max any) *Builder {
-	return b.addRawWhere("or", column+" NOT BETWEEN ? AND ?", min, max)
+	return b.addRawWhere("or", b.wrap(column)+" NOT BETWEEN ? AND ?", min, max)
 }
 
 // WhereLike adds a WHERE LIKE clause.
 func (b *Builder) WhereLike(column string, pattern string) *Builder {
-	return b.addRawWhere("and", column+" LIKE ?", pattern)
+	return b.addRawWhere("and", b.wrap(column)+" LIKE ?", pattern)
 }
 
 // WhereNotLike adds a WHERE NOT LIKE clause.
 func (b *Builder) WhereNotLike(column string, pattern string) *Builder {
-	return b.addRawWhere("and", column+" NOT LIKE ?", pattern)
+	return b.addRawWhere("and", b.wrap(column)+" NOT LIKE ?", pattern)
 }
 
 // OrWhereLike adds an OR WHERE LIKE clause.
 func (b *Builder) OrWhereLike(column string, pattern string) *Builder {
-	return b.addRawWhere("or", column+" LIKE ?", pattern)
+	return b.addRawWhere("or", b.wrap(column)+" LIKE ?", pattern)
 }
 
 // OrWhereNotLike adds an OR WHERE NOT LIKE clause.
 func (b *Builder) OrWhereNotLike(column string, pattern string) *Builder {
-	return b.addRawWhere("or", column+" NOT LIKE ?", pattern)
+	return b.addRawWhere("or", b.wrap(column)+" NOT LIKE ?", pattern)
 }
 
 // WhereNull adds WHERE column IS NULL.
 func (b *Builder) WhereNull(column string) *Builder {
-	return b.addRawWhere("and", column+" IS NULL")
+	return b.addRawWhere("and", b.wrap(column)+" IS NULL")
 }
 
 // WhereNotNull adds WHERE column IS NOT NULL.
 func (b *Builder) WhereNotNull(column string) *Builder {
-	return b.addRawWhere("and", column+" IS NOT NULL")
+	return b.addRawWhere("and", b.wrap(column)+" IS NOT NULL")
 }
 
 // OrWhereNull adds OR WHERE column IS NULL.
 func (b *Builder) OrWhereNull(column string) *Builder {
-	return b.addRawWhere("or", column+" IS NULL")
+	return b.addRawWhere("or", b.wrap(column)+" IS NULL")
 }
 
 // OrWhereNotNull adds OR WHERE column IS NOT NULL.
 func (b *Builder) OrWhereNotNull(column string) *Builder {
-	return b.addRawWhere("or", column+" IS NOT NULL")
+	return b.addRawWhere("or", b.wrap(column)+" IS NOT NULL")
 }
 
 // WhereColumn compares two columns (WhereColumn("a", "b") or WhereColumn("a", ">", "b")).
@@ -253,7 +316,7 @@ func (b *Builder) addExists(boolean string, not bool, sub *Builder) *Builder {
 	clone.lockMode = ""
 	clone.lockSkip = false
 	clone.lockNoWait = false
-	sqlStr, args := clone.ToSQL()
+	sqlStr, args := clone.compile()
 	keyword := "EXISTS"
 	if not {
 		keyword = "NOT EXISTS"
@@ -378,7 +441,9 @@ func (b *Builder) OrderBy(column string, direction ...string) *Builder {
 		dir = direction[0]
 	}
 	b.orders = append(b.orders, orderClause{
-		sql: fmt.Sprintf("%s %s", column, strings.ToLower(dir)),
+		sql:       fmt.Sprintf("%s %s", b.wrap(column), strings.ToLower(dir)),
+		column:    column,
+		direction: strings.ToLower(dir),
 	})
 	return b
 }
@@ -594,10 +659,22 @@ func (b *Builder) NoWait() *Builder {
 
 // ToSQL returns the SELECT SQL and args.
 func (b *Builder) ToSQL() (string, []any) {
-	coreSQL, args := b.compileSelectCore()
+	sqlStr, args := b.compile()
+	return b.rebind(sqlStr), args
+}
 
+// compile builds the SELECT with ? placeholders so it can be embedded in an
+// outer query before rebinding.
+func (b *Builder) compile() (string, []any) {
 	var sb strings.Builder
+	args := make([]any, 0)
+	if withSQL, withArgs := b.compileCTEs(); withSQL != "" {
+		sb.WriteString(withSQL)
+		args = append(args, withArgs...)
+	}
+	coreSQL, coreArgs := b.compileSelectCore()
 	sb.WriteString(coreSQL)
+	args = append(args, coreArgs...)
 	for _, u := range b.unions {
 		part := u.query.clone()
 		part.orders = nil
@@ -635,7 +712,7 @@ func (b *Builder) ToSQL() (string, []any) {
 		sb.WriteString(lock)
 	}
 
-	return b.rebind(sb.String()), args
+	return sb.String(), args
 }
 
 // compileSelectCore builds SELECT..HAVING without ORDER/LIMIT/LOCK/UNION.
@@ -661,6 +738,8 @@ func (b *Builder) compileSelectCore() (string, []any) {
 
 	args := make([]any, 0)
 	args = append(args, b.selectBindings...)
+	args = append(args, b.fromBindings...)
+	args = append(args, b.joinBindings...)
 
 	whereSQL, whereArgs := b.compileWheres(b.wheres)
 	if whereSQL != "" {
@@ -687,7 +766,7 @@ func (b *Builder) compileSelectCore() (string, []any) {
 // Get executes the select query and returns maps.
 func (b *Builder) Get() ([]map[string]any, error) {
 	sqlStr, args := b.ToSQL()
-	rows, err := b.db.Query(sqlStr, args...)
+	rows, err := b.queryRows(sqlStr, args)
 	if err != nil {
 		return nil, err
 	}
@@ -873,7 +952,7 @@ func (b *Builder) Insert(values map[string]any) (int64, error) {
 		args = append(args, value)
 	}
 	sqlStr := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", b.table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
-	result, err := b.db.Exec(b.rebind(sqlStr), args...)
+	result, err := b.execStatement(b.rebind(sqlStr), args)
 	if err != nil {
 		return 0, err
 	}
@@ -944,7 +1023,7 @@ func (b *Builder) Upsert(values map[string]any, uniqueBy []string, update ...str
 		sb.WriteString(strings.Join(sets, ", "))
 	}
 
-	result, err := b.db.Exec(b.rebind(sb.String()), args...)
+	result, err := b.execStatement(b.rebind(sb.String()), args)
 	if err != nil {
 		return 0, err
 	}
@@ -956,12 +1035,7 @@ func (b *Builder) Update(values map[string]any) (int64, error) {
 	if len(values) == 0 {
 		return 0, fmt.Errorf("update values required")
 	}
-	sets := make([]string, 0, len(values))
-	args := make([]any, 0)
-	for column, value := range values {
-		sets = append(sets, column+" = ?")
-		args = append(args, value)
-	}
+	sets, args := b.compileSets(values)
 	var sb strings.Builder
 	sb.WriteString("UPDATE ")
 	sb.WriteString(b.table)
@@ -975,7 +1049,7 @@ func (b *Builder) Update(values map[string]any) (int64, error) {
 		args = append(args, whereArgs...)
 	}
 
-	result, err := b.db.Exec(b.rebind(sb.String()), args...)
+	result, err := b.execStatement(b.rebind(sb.String()), args)
 	if err != nil {
 		return 0, err
 	}
@@ -996,7 +1070,7 @@ func (b *Builder) Delete() (int64, error) {
 		args = append(args, whereArgs...)
 	}
 
-	result, err := b.db.Exec(b.rebind(sb.String()), args...)
+	result, err := b.execStatement(b.rebind(sb.String()), args)
 	if err != nil {
 		return 0, err
 	}
@@ -1040,7 +1114,7 @@ func (b *Builder) adjustColumn(column string, delta int64) (int64, error) {
 		args = append(args, whereArgs...)
 	}
 
-	result, err := b.db.Exec(b.rebind(sb.String()), args...)
+	result, err := b.execStatement(b.rebind(sb.String()), args)
 	if err != nil {
 		return 0, err
 	}
@@ -1139,7 +1213,7 @@ func (b *Builder) InsertBatch(rows []map[string]any) (int64, error) {
 		}
 		sb.WriteString("(" + strings.Join(placeholders, ", ") + ")")
 	}
-	result, err := b.db.Exec(b.rebind(sb.String()), args...)
+	result, err := b.execStatement(b.rebind(sb.String()), args)
 	if err != nil {
 		return 0, err
 	}
@@ -1148,7 +1222,7 @@ func (b *Builder) InsertBatch(rows []map[string]any) (int64, error) {
 
 // Truncate deletes all rows from the table.
 func (b *Builder) Truncate() error {
-	_, err := b.db.Exec(fmt.Sprintf("DELETE FROM %s", b.table))
+	_, err := b.execStatement(fmt.Sprintf("DELETE FROM %s", b.table), nil)
 	return err
 }
 
@@ -1189,7 +1263,7 @@ func (b *Builder) addWhereIn(boolean, column string, values []any, not bool) *Bu
 	if not {
 		op = "NOT IN"
 	}
-	return b.addRawWhere(boolean, fmt.Sprintf("%s %s (%s)", column, op, strings.Join(placeholders, ", ")), values...)
+	return b.addRawWhere(boolean, fmt.Sprintf("%s %s (%s)", b.wrap(column), op, strings.Join(placeholders, ", ")), values...)
 }
 
 func (b *Builder) addWhere(boolean, column string, args ...any) *Builder {
@@ -1197,10 +1271,9 @@ func (b *Builder) addWhere(boolean, column string, args ...any) *Builder {
 	case 0:
 		return b.addRawWhere(boolean, column)
 	case 1:
-		return b.addRawWhere(boolean, column+" = ?", args[0])
+		return b.addComparison(boolean, column, "=", args[0])
 	default:
-		operator := fmt.Sprint(args[0])
-		return b.addRawWhere(boolean, fmt.Sprintf("%s %s ?", column, operator), args[1])
+		return b.addComparison(boolean, column, fmt.Sprint(args[0]), args[1])
 	}
 }
 
@@ -1267,7 +1340,13 @@ func (b *Builder) compileWheres(clauses []whereClause) (string, []any) {
 }
 
 func (b *Builder) rebind(sqlStr string) string {
-	if b.driver != "pgsql" && b.driver != "postgres" && b.driver != "postgresql" {
+	return Rebind(b.driver, sqlStr)
+}
+
+// Rebind rewrites the ? placeholders of sqlStr to $1, $2, ... for PostgreSQL
+// drivers and returns it unchanged for the others.
+func Rebind(driver, sqlStr string) string {
+	if driver != "pgsql" && driver != "postgres" && driver != "postgresql" {
 		return sqlStr
 	}
 	var sb strings.Builder
@@ -1308,8 +1387,45 @@ func (b *Builder) compileLock() string {
 	return " " + clause
 }
 
+func (b *Builder) queryRows(sqlStr string, args []any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := b.readConnection().QueryContext(b.Context(), sqlStr, args...)
+	b.notify(sqlStr, args, time.Since(start), err)
+	return rows, err
+}
+
+func (b *Builder) execStatement(sqlStr string, args []any) (sql.Result, error) {
+	start := time.Now()
+	result, err := b.db.ExecContext(b.Context(), sqlStr, args...)
+	b.notify(sqlStr, args, time.Since(start), err)
+	if err == nil {
+		markModified(b.Context())
+		if b.reader != nil {
+			RecordWrite(b.db)
+		}
+	}
+	return result, err
+}
+
+func (b *Builder) notify(sqlStr string, args []any, elapsed time.Duration, err error) {
+	if len(b.listeners) == 0 {
+		return
+	}
+	event := Executed{
+		Context:  b.Context(),
+		SQL:      sqlStr,
+		Bindings: args,
+		Duration: elapsed,
+		Err:      err,
+	}
+	for _, fn := range b.listeners {
+		fn(event)
+	}
+}
+
 func (b *Builder) clone() *Builder {
 	cp := *b
+	cp.listeners = append([]func(Executed){}, b.listeners...)
 	cp.columns = append([]string{}, b.columns...)
 	cp.selectBindings = append([]any{}, b.selectBindings...)
 	cp.wheres = append([]whereClause{}, b.wheres...)
@@ -1317,6 +1433,9 @@ func (b *Builder) clone() *Builder {
 	cp.groups = append([]string{}, b.groups...)
 	cp.havings = append([]whereClause{}, b.havings...)
 	cp.joins = append([]string{}, b.joins...)
+	cp.joinBindings = append([]any{}, b.joinBindings...)
+	cp.fromBindings = append([]any{}, b.fromBindings...)
+	cp.ctes = append([]cteClause{}, b.ctes...)
 	cp.unions = make([]unionClause, len(b.unions))
 	for i, u := range b.unions {
 		cp.unions[i] = unionClause{all: u.all, query: u.query.clone()}
@@ -1331,24 +1450,32 @@ func scanRows(rows *sql.Rows) ([]map[string]any, error) {
 	}
 	results := make([]map[string]any, 0)
 	for rows.Next() {
-		values := make([]any, len(columns))
-		pointers := make([]any, len(columns))
-		for i := range values {
-			pointers[i] = &values[i]
-		}
-		if err := rows.Scan(pointers...); err != nil {
+		row, err := scanRow(rows, columns)
+		if err != nil {
 			return nil, err
 		}
-		row := make(map[string]any, len(columns))
-		for i, column := range columns {
-			val := values[i]
-			if b, ok := val.([]byte); ok {
-				row[column] = string(b)
-			} else {
-				row[column] = val
-			}
-		}
 		results = append(results, row)
 	}
 	return results, rows.Err()
 }
+
+func scanRow(rows *sql.Rows, columns []string) (map[string]any, error) {
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+	row := make(map[string]any, len(columns))
+	for i, column := range columns {
+		val := values[i]
+		if b, ok := val.([]byte); ok {
+			row[column] = string(b)
+		} else {
+			row[column] = val
+		}
+	}
+	return row, nil
+}
@@ -0,0 +1,118 @@
+package query
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type writeTrackerKey struct{}
+
+// TrackWrites returns a context that remembers whether a builder bound to it
+// has written. Sticky builders keep reading from the writer afterwards.
+func TrackWrites(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Value(writeTrackerKey{}).(*atomic.Bool); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, writeTrackerKey{}, new(atomic.Bool))
+}
+
+// RecordsModified reports whether a write ran on a TrackWrites context.
+func RecordsModified(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	if flag, ok := ctx.Value(writeTrackerKey{}).(*atomic.Bool); ok {
+		return flag.Load()
+	}
+	return false
+}
+
+func markModified(ctx context.Context) {
+	if flag, ok := ctx.Value(writeTrackerKey{}).(*atomic.Bool); ok {
+		flag.Store(true)
+	}
+}
+
+// DefaultStickyWindow is how long sticky reads without a TrackWrites context
+// stay on the primary after a write (see SetStickyWindow).
+const DefaultStickyWindow = 5 * time.Second
+
+var (
+	stickyWindow atomic.Int64
+	// lastWrites holds the UnixNano time of the latest write per primary
+	// connection, for builders that have a read connection.
+	lastWrites sync.Map
+)
+
+func init() {
+	stickyWindow.Store(int64(DefaultStickyWindow))
+}
+
+// SetStickyWindow sets how long after a write on a primary connection sticky
+// reads without a TrackWrites context go to that primary. Zero turns the
+// window off, leaving only TrackWrites contexts sticky.
+func SetStickyWindow(d time.Duration) {
+	stickyWindow.Store(int64(d))
+}
+
+// RecordWrite starts the sticky window for db, for writes that bypass the
+// builder such as a committed transaction.
+func RecordWrite(db DBTX) {
+	lastWrites.Store(db, time.Now().UnixNano())
+}
+
+func wroteRecently(db DBTX) bool {
+	window := stickyWindow.Load()
+	if window <= 0 {
+		return false
+	}
+	last, ok := lastWrites.Load(db)
+	return ok && time.Now().UnixNano()-last.(int64) < window
+}
+
+func tracksWrites(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	_, ok := ctx.Value(writeTrackerKey{}).(*atomic.Bool)
+	return ok
+}
+
+// UseReadConnection routes SELECT statements to reader while writes stay on
+// the builder's primary connection. With sticky set, reads return to the
+// primary once the bound context (see TrackWrites) has recorded a write.
+// Queries without a TrackWrites context, such as orm.Save and orm.Find, go to
+// the primary for the sticky window after any write on it.
+func (b *Builder) UseReadConnection(reader DBTX, sticky bool) *Builder {
+	b.reader = reader
+	b.sticky = sticky
+	return b
+}
+
+// UseWriteConnection forces SELECT statements onto the primary connection.
+func (b *Builder) UseWriteConnection() *Builder {
+	b.forceWrite = true
+	return b
+}
+
+func (b *Builder) readConnection() DBTX {
+	if b.reader == nil || b.forceWrite || b.lockMode != "" {
+		return b.db
+	}
+	if b.sticky {
+		ctx := b.Context()
+		if tracksWrites(ctx) {
+			if RecordsModified(ctx) {
+				return b.db
+			}
+		} else if wroteRecently(b.db) {
+			return b.db
+		}
+	}
+	return b.reader
+}
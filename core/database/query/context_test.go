@@ -0,0 +1,46 @@
+package query_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/database/query"
+)
+
+func TestWithContextCancelsQuery(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := query.New(db, "sqlite", "items").WithContext(ctx)
+	if b.Context() != ctx {
+		t.Fatal("context not bound")
+	}
+	if _, err := b.Clone().Insert(map[string]any{"name": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := b.Clone().Count(); err != nil || n != 1 {
+		t.Fatalf("count=%d err=%v", n, err)
+	}
+
+	cancel()
+	if _, err := b.Clone().Get(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("get after cancel err=%v", err)
+	}
+	if _, err := b.Clone().Where("id", 1).Update(map[string]any{"name": "b"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("update after cancel err=%v", err)
+	}
+	if query.New(db, "sqlite", "items").Context() == nil {
+		t.Fatal("default context should not be nil")
+	}
+}
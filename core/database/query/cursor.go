@@ -0,0 +1,125 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zatrano/framework/core/pagination"
+)
+
+// CursorPaginate returns up to perPage rows after (or before) cursor using
+// keyset pagination on the ORDER BY columns, plus the cursors of the adjacent
+// pages ("" when there is none). Orders by id when no order is set; the order
+// columns must identify rows uniquely and be selected.
+func (b *Builder) CursorPaginate(perPage int, cursor string) (items []map[string]any, next, prev string, err error) {
+	if perPage < 1 {
+		perPage = 15
+	}
+	var params *pagination.CursorParams
+	if cursor != "" {
+		if params, err = pagination.DecodeCursor(cursor); err != nil {
+			return nil, "", "", err
+		}
+	}
+
+	clone := b.clone()
+	if len(clone.orders) == 0 {
+		clone.OrderBy("id")
+	}
+	orders := append([]orderClause{}, clone.orders...)
+	for _, order := range orders {
+		if order.column == "" {
+			return nil, "", "", fmt.Errorf("cursor pagination requires column orders, got raw order %q", order.sql)
+		}
+	}
+	pointsToNext := params == nil || params.PointsToNext
+
+	if params != nil {
+		sqlStr, args, err := cursorWhere(orders, params)
+		if err != nil {
+			return nil, "", "", err
+		}
+		clone.WhereRaw(sqlStr, args...)
+	}
+	if !pointsToNext {
+		clone.orders = nil
+		for _, order := range orders {
+			clone.OrderBy(order.column, flipDirection(order.direction))
+		}
+	}
+	clone.limitN = perPage + 1
+	clone.offsetN = 0
+
+	rows, err := clone.Get()
+	if err != nil {
+		return nil, "", "", err
+	}
+	hasMore := len(rows) > perPage
+	if hasMore {
+		rows = rows[:perPage]
+	}
+	if !pointsToNext {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, "", "", nil
+	}
+
+	if (pointsToNext && hasMore) || (params != nil && !pointsToNext) {
+		next = pagination.EncodeCursor(cursorParams(orders, rows[len(rows)-1], true))
+	}
+	if (params != nil && pointsToNext) || (!pointsToNext && hasMore) {
+		prev = pagination.EncodeCursor(cursorParams(orders, rows[0], false))
+	}
+	return rows, next, prev, nil
+}
+
+// cursorWhere builds (c1 > ?) OR (c1 = ? AND c2 > ?) ... for the cursor position.
+func cursorWhere(orders []orderClause, params *pagination.CursorParams) (string, []any, error) {
+	parts := make([]string, 0, len(orders))
+	args := make([]any, 0)
+	for i, order := range orders {
+		conds := make([]string, 0, i+1)
+		for _, prior := range orders[:i] {
+			value, ok := params.Values[prior.column]
+			if !ok {
+				return "", nil, fmt.Errorf("invalid cursor: missing %s", prior.column)
+			}
+			conds = append(conds, prior.column+" = ?")
+			args = append(args, value)
+		}
+		value, ok := params.Values[order.column]
+		if !ok {
+			return "", nil, fmt.Errorf("invalid cursor: missing %s", order.column)
+		}
+		op := ">"
+		if (order.direction == "desc") == params.PointsToNext {
+			op = "<"
+		}
+		conds = append(conds, fmt.Sprintf("%s %s ?", order.column, op))
+		args = append(args, value)
+		parts = append(parts, "("+strings.Join(conds, " AND ")+")")
+	}
+	return "(" + strings.Join(parts, " OR ") + ")", args, nil
+}
+
+func cursorParams(orders []orderClause, row map[string]any, pointsToNext bool) pagination.CursorParams {
+	values := make(map[string]any, len(orders))
+	for _, order := range orders {
+		key := order.column
+		if i := strings.LastIndex(key, "."); i >= 0 {
+			key = key[i+1:]
+		}
+		values[order.column] = row[key]
+	}
+	return pagination.CursorParams{Values: values, PointsToNext: pointsToNext}
+}
+
+func flipDirection(direction string) string {
+	if direction == "desc" {
+		return "asc"
+	}
+	return "desc"
+}
@@ -0,0 +1,66 @@
+package query_test
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/database/query"
+)
+
+func TestCursorPaginateWalksForwardAndBack(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, score INTEGER, name TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 7; i++ {
+		// scores repeat so the id tie-breaker matters: 1,1,2,2,3,3,4
+		if _, err := db.Exec(`INSERT INTO items (id, score, name) VALUES (?, ?, ?)`, i, (i+1)/2, fmt.Sprintf("item%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	base := func() *query.Builder {
+		return query.New(db, "sqlite", "items").OrderByDesc("score").OrderBy("id")
+	}
+	ids := func(rows []map[string]any) string {
+		out := ""
+		for _, row := range rows {
+			out += fmt.Sprint(row["id"], ",")
+		}
+		return out
+	}
+
+	page1, next, prev, err := base().CursorPaginate(3, "")
+	if err != nil || ids(page1) != "7,5,6," || next == "" || prev != "" {
+		t.Fatalf("page1=%s next=%q prev=%q err=%v", ids(page1), next, prev, err)
+	}
+	page2, next2, prev2, err := base().CursorPaginate(3, next)
+	if err != nil || ids(page2) != "3,4,1," || next2 == "" || prev2 == "" {
+		t.Fatalf("page2=%s next=%q prev=%q err=%v", ids(page2), next2, prev2, err)
+	}
+	page3, next3, prev3, err := base().CursorPaginate(3, next2)
+	if err != nil || ids(page3) != "2," || next3 != "" || prev3 == "" {
+		t.Fatalf("page3=%s next=%q prev=%q err=%v", ids(page3), next3, prev3, err)
+	}
+	back, nextBack, prevBack, err := base().CursorPaginate(3, prev3)
+	if err != nil || ids(back) != "3,4,1," || nextBack == "" || prevBack == "" {
+		t.Fatalf("back=%s next=%q prev=%q err=%v", ids(back), nextBack, prevBack, err)
+	}
+	first, _, prevFirst, err := base().CursorPaginate(3, prevBack)
+	if err != nil || ids(first) != "7,5,6," || prevFirst != "" {
+		t.Fatalf("first=%s prev=%q err=%v", ids(first), prevFirst, err)
+	}
+
+	if _, _, _, err := base().CursorPaginate(3, "not a cursor!"); err == nil {
+		t.Fatal("expected invalid cursor error")
+	}
+	if _, _, _, err := query.New(db, "sqlite", "items").InRandomOrder().CursorPaginate(3, ""); err == nil {
+		t.Fatal("expected raw order error")
+	}
+}
@@ -0,0 +1,60 @@
+package query
+
+import (
+	"errors"
+	"iter"
+)
+
+// Cursor streams matching rows from a single result set. The connection stays
+// busy until iteration ends; breaking out of the loop closes the rows.
+func (b *Builder) Cursor() iter.Seq2[map[string]any, error] {
+	return func(yield func(map[string]any, error) bool) {
+		sqlStr, args := b.ToSQL()
+		rows, err := b.queryRows(sqlStr, args)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer rows.Close()
+		columns, err := rows.Columns()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for rows.Next() {
+			row, err := scanRow(rows, columns)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(row, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// Lazy iterates matching rows one at a time while fetching them in chunks of
+// size using keyset pagination on id (or column), like ChunkById.
+func (b *Builder) Lazy(size int, column ...string) iter.Seq2[map[string]any, error] {
+	return func(yield func(map[string]any, error) bool) {
+		stopped := false
+		err := b.ChunkById(size, func(rows []map[string]any) error {
+			for _, row := range rows {
+				if !yield(row, nil) {
+					stopped = true
+					return errStopIteration
+				}
+			}
+			return nil
+		}, column...)
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+var errStopIteration = errors.New("iteration stopped")
@@ -0,0 +1,62 @@
+package query_test
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/database/query"
+)
+
+func TestCursorAndLazyIterateRows(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if _, err := query.New(db, "sqlite", "items").Insert(map[string]any{"name": name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := ""
+	for row, err := range query.New(db, "sqlite", "items").OrderByDesc("id").Cursor() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names += row["name"].(string)
+		if len(names) == 3 {
+			break
+		}
+	}
+	if names != "edc" {
+		t.Fatalf("cursor names=%q", names)
+	}
+	// Breaking out closed the rows, so the single connection is free again.
+	db.SetMaxOpenConns(1)
+	if n, err := query.New(db, "sqlite", "items").Count(); err != nil || n != 5 {
+		t.Fatalf("count=%d err=%v", n, err)
+	}
+
+	names = ""
+	for row, err := range query.New(db, "sqlite", "items").Where("name", "!=", "c").Lazy(2) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names += row["name"].(string)
+	}
+	if names != "abde" {
+		t.Fatalf("lazy names=%q", names)
+	}
+
+	for _, err := range query.New(db, "sqlite", "missing").Cursor() {
+		if err == nil {
+			t.Fatal("expected error for missing table")
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonSegment is one step of a column->key->0 path.
+type jsonSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// splitJSONPath splits "meta->address->city" into the column and its path.
+func splitJSONPath(column string) (string, []jsonSegment) {
+	if !strings.Contains(column, "->") {
+		return column, nil
+	}
+	parts := strings.Split(column, "->")
+	segments := make([]jsonSegment, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		part = strings.Trim(strings.TrimPrefix(part, ">"), `"' `)
+		if n, err := strconv.Atoi(part); err == nil && n >= 0 {
+			segments = append(segments, jsonSegment{index: n, isIndex: true})
+			continue
+		}
+		segments = append(segments, jsonSegment{key: part})
+	}
+	return strings.TrimSpace(parts[0]), segments
+}
+
+// wrap compiles a column->path reference to the driver's JSON extraction
+// expression and returns other columns unchanged.
+func (b *Builder) wrap(column string) string {
+	col, path := splitJSONPath(column)
+	if len(path) == 0 {
+		return column
+	}
+	switch {
+	case b.isPostgres():
+		return col + postgresJSONPath(path, true)
+	case b.driver == "mysql":
+		return fmt.Sprintf("json_unquote(json_extract(%s, %s))", col, jsonPathLiteral(path))
+	default:
+		return fmt.Sprintf("json_extract(%s, %s)", col, jsonPathLiteral(path))
+	}
+}
+
+// jsonPathLiteral renders '$."address"."city"' for sqlite and mysql.
+func jsonPathLiteral(path []jsonSegment) string {
+	var sb strings.Builder
+	sb.WriteString("'$")
+	for _, seg := range path {
+		if seg.isIndex {
+			sb.WriteString(fmt.Sprintf("[%d]", seg.index))
+			continue
+		}
+		key := strings.NewReplacer(`"`, `\"`, "'", "''").Replace(seg.key)
+		sb.WriteString(`."` + key + `"`)
+	}
+	sb.WriteString("'")
+	return sb.String()
+}
+
+// postgresJSONPath renders ->'address'->>'city'; asText uses ->> for the last step.
+func postgresJSONPath(path []jsonSegment, asText bool) string {
+	var sb strings.Builder
+	for i, seg := range path {
+		op := "->"
+		if asText && i == len(path)-1 {
+			op = "->>"
+		}
+		sb.WriteString(op)
+		if seg.isIndex {
+			sb.WriteString(strconv.Itoa(seg.index))
+		} else {
+			sb.WriteString("'" + strings.ReplaceAll(seg.key, "'", "''") + "'")
+		}
+	}
+	return sb.String()
+}
+
+// jsonValue returns the JSON (not text) value at column->path.
+func (b *Builder) jsonValue(column string) string {
+	col, path := splitJSONPath(column)
+	switch {
+	case b.isPostgres():
+		return "(" + col + postgresJSONPath(path, false) + ")::jsonb"
+	case len(path) == 0:
+		return col
+	default:
+		return fmt.Sprintf("json_extract(%s, %s)", col, jsonPathLiteral(path))
+	}
+}
+
+// addComparison adds column operator ?, comparing booleans inside JSON as
+// JSON booleans rather than text.
+func (b *Builder) addComparison(boolean, column, operator string, value any) *Builder {
+	if flag, ok := value.(bool); ok && strings.Contains(column, "->") {
+		literal := strconv.FormatBool(flag)
+		switch {
+		case b.isPostgres():
+			return b.addRawWhere(boolean, fmt.Sprintf("%s %s '%s'::jsonb", b.jsonValue(column), operator, literal))
+		case b.driver == "mysql":
+			return b.addRawWhere(boolean, fmt.Sprintf("%s %s %s", b.jsonValue(column), operator, literal))
+		}
+	}
+	return b.addRawWhere(boolean, fmt.Sprintf("%s %s ?", b.wrap(column), operator), value)
+}
+
+// WhereJsonContains matches rows whose JSON array (or object) at column
+// contains value; a slice value must be contained entirely.
+func (b *Builder) WhereJsonContains(column string, value any) *Builder {
+	return b.addJSONContains("and", column, value, false)
+}
+
+// OrWhereJsonContains is WhereJsonContains combined with OR.
+func (b *Builder) OrWhereJsonContains(column string, value any) *Builder {
+	return b.addJSONContains("or", column, value, false)
+}
+
+// WhereJsonDoesntContain matches rows whose JSON at column does not contain value.
+func (b *Builder) WhereJsonDoesntContain(column string, value any) *Builder {
+	return b.addJSONContains("and", column, value, true)
+}
+
+func (b *Builder) addJSONContains(boolean, column string, value any, not bool) *Builder {
+	prefix := ""
+	if not {
+		prefix = "NOT "
+	}
+	if b.isSQLite() {
+		// SQLite has no json_contains; require every value to appear in json_each.
+		col, path := splitJSONPath(column)
+		values := []any{value}
+		if list, ok := value.([]any); ok {
+			values = list
+		}
+		parts := make([]string, 0, len(values))
+		for range values {
+			parts = append(parts, fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s, %s) WHERE json_each.value = ?)", col, jsonPathLiteral(path)))
+		}
+		return b.addRawWhere(boolean, prefix+"("+strings.Join(parts, " AND ")+")", values...)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		encoded = []byte("null")
+	}
+	if b.isPostgres() {
+		return b.addRawWhere(boolean, fmt.Sprintf("%s(%s @> ?::jsonb)", prefix, b.jsonValue(column)), string(encoded))
+	}
+	return b.addRawWhere(boolean, fmt.Sprintf("%sjson_contains(%s, ?)", prefix, b.jsonValue(column)), string(encoded))
+}
+
+// WhereJsonLength compares the length of the JSON array at column:
+// WhereJsonLength("meta->tags", 2) or WhereJsonLength("meta->tags", ">", 1).
+func (b *Builder) WhereJsonLength(column string, args ...any) *Builder {
+	if len(args) == 0 {
+		return b
+	}
+	operator, value := "=", args[0]
+	if len(args) > 1 {
+		operator, value = fmt.Sprint(args[0]), args[1]
+	}
+	col, path := splitJSONPath(column)
+	var length string
+	switch {
+	case b.isPostgres():
+		length = fmt.Sprintf("jsonb_array_length(%s)", b.jsonValue(column))
+	case b.driver == "mysql":
+		length = fmt.Sprintf("json_length(%s)", b.jsonValue(column))
+	default:
+		length = fmt.Sprintf("json_array_length(%s, %s)", col, jsonPathLiteral(path))
+	}
+	return b.addRawWhere("and", fmt.Sprintf("%s %s ?", length, operator), value)
+}
+
+// WhereJsonContainsKey matches rows where the column->path key exists.
+func (b *Builder) WhereJsonContainsKey(column string) *Builder {
+	return b.addRawWhere("and", b.jsonKeyExists(column))
+}
+
+// WhereJsonDoesntContainKey matches rows where the column->path key is missing.
+func (b *Builder) WhereJsonDoesntContainKey(column string) *Builder {
+	return b.addRawWhere("and", "NOT "+b.jsonKeyExists(column))
+}
+
+func (b *Builder) jsonKeyExists(column string) string {
+	col, path := splitJSONPath(column)
+	if len(path) == 0 {
+		return col + " IS NOT NULL"
+	}
+	switch {
+	case b.isPostgres():
+		parent := "(" + col + postgresJSONPath(path[:len(path)-1], false) + ")::jsonb"
+		last := path[len(path)-1]
+		if last.isIndex {
+			return fmt.Sprintf("(jsonb_typeof(%s) = 'array' AND jsonb_array_length(%s) > %d)", parent, parent, last.index)
+		}
+		// jsonb_exists is the function form of ?, which would clash with placeholders.
+		return fmt.Sprintf("coalesce(jsonb_exists(%s, '%s'), false)", parent, strings.ReplaceAll(last.key, "'", "''"))
+	case b.driver == "mysql":
+		return fmt.Sprintf("ifnull(json_contains_path(%s, 'one', %s), 0)", col, jsonPathLiteral(path))
+	default:
+		return fmt.Sprintf("json_type(%s, %s) IS NOT NULL", col, jsonPathLiteral(path))
+	}
+}
+
+// compileSets builds the SET list for Update, folding column->path keys into
+// one json_set/jsonb_set expression per column.
+func (b *Builder) compileSets(values map[string]any) ([]string, []any) {
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	sets := make([]string, 0, len(columns))
+	args := make([]any, 0, len(columns))
+	jsonExpr := make(map[string]string)
+	jsonArgs := make(map[string][]any)
+	jsonOrder := make([]string, 0)
+	for _, column := range columns {
+		col, path := splitJSONPath(column)
+		if len(path) == 0 {
+			if expr, ok := values[column].(Expression); ok {
+				sets = append(sets, column+" = "+expr.SQL)
+				args = append(args, expr.Bindings...)
+				continue
+			}
+			sets = append(sets, column+" = ?")
+			args = append(args, values[column])
+			continue
+		}
+		expr, ok := jsonExpr[col]
+		if !ok {
+			jsonOrder = append(jsonOrder, col)
+			switch {
+			case b.isPostgres():
+				expr = fmt.Sprintf("COALESCE(%s::jsonb, '{}'::jsonb)", col)
+			default:
+				expr = fmt.Sprintf("COALESCE(%s, '{}')", col)
+			}
+		}
+		encoded, err := json.Marshal(values[column])
+		if err != nil {
+			encoded = []byte("null")
+		}
+		switch {
+		case b.isPostgres():
+			keys := make([]string, 0, len(path))
+			for _, seg := range path {
+				if seg.isIndex {
+					keys = append(keys, strconv.Itoa(seg.index))
+				} else {
+					keys = append(keys, `"`+strings.NewReplacer(`"`, `\"`, "'", "''").Replace(seg.key)+`"`)
+				}
+			}
+			expr = fmt.Sprintf("jsonb_set(%s, '{%s}', ?::jsonb, true)", expr, strings.Join(keys, ","))
+		case b.driver == "mysql":
+			expr = fmt.Sprintf("json_set(%s, %s, CAST(? AS JSON))", expr, jsonPathLiteral(path))
+		default:
+			expr = fmt.Sprintf("json_set(%s, %s, json(?))", expr, jsonPathLiteral(path))
+		}
+		jsonExpr[col] = expr
+		jsonArgs[col] = append(jsonArgs[col], string(encoded))
+	}
+	for _, col := range jsonOrder {
+		sets = append(sets, col+" = "+jsonExpr[col])
+		args = append(args, jsonArgs[col]...)
+	}
+	return sets, args
+}
+
+func (b *Builder) isPostgres() bool {
+	return b.driver == "pgsql" || b.driver == "postgres" || b.driver == "postgresql"
+}
+
+func (b *Builder) isSQLite() bool {
+	return strings.Contains(b.driver, "sqlite")
+}
@@ -0,0 +1,108 @@
+package query_test
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/database/query"
+)
+
+func TestJSONQueriesOnSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	statements := []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, meta TEXT)`,
+		`INSERT INTO users (meta) VALUES ('{"address":{"city":"Paris"},"tags":["go","sql"],"active":true}')`,
+		`INSERT INTO users (meta) VALUES ('{"address":{"city":"Rome"},"tags":["go"],"active":false,"vip":1}')`,
+		`INSERT INTO users (meta) VALUES ('{"tags":[]}')`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := func(b *query.Builder) string {
+		rows, err := b.OrderBy("id").Pluck("id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprint(rows)
+	}
+	users := func() *query.Builder { return query.New(db, "sqlite", "users") }
+
+	cases := []struct {
+		want string
+		b    *query.Builder
+	}{
+		{"[1]", users().Where("meta->address->city", "Paris")},
+		{"[2]", users().Where("meta->active", false)},
+		{"[1 2]", users().WhereIn("meta->address->city", []any{"Paris", "Rome"})},
+		{"[1 2]", users().WhereJsonContains("meta->tags", "go")},
+		{"[1]", users().WhereJsonContains("meta->tags", []any{"go", "sql"})},
+		{"[2 3]", users().WhereJsonDoesntContain("meta->tags", "sql")},
+		{"[1 2]", users().WhereJsonLength("meta->tags", ">", 0)},
+		{"[3]", users().WhereJsonLength("meta->tags", 0)},
+		{"[2]", users().WhereJsonContainsKey("meta->vip")},
+		{"[1 2]", users().WhereJsonContainsKey("meta->tags->0")},
+		{"[3]", users().WhereJsonDoesntContainKey("meta->address->city")},
+	}
+	for _, tc := range cases {
+		sqlStr, _ := tc.b.ToSQL()
+		if got := ids(tc.b); got != tc.want {
+			t.Fatalf("%s: got %s want %s", sqlStr, got, tc.want)
+		}
+	}
+
+	if _, err := users().Where("id", 3).Update(map[string]any{
+		"meta->flags->beta": true,
+		"meta->address":     map[string]any{"city": "Oslo"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(users().Where("meta->flags->beta", true).Where("meta->address->city", "Oslo")); got != "[3]" {
+		t.Fatalf("after update got %s", got)
+	}
+	if got := ids(users().WhereJsonLength("meta->tags", 0)); got != "[3]" {
+		t.Fatalf("update should keep other keys, got %s", got)
+	}
+	if got := ids(users().OrderByDesc("meta->address->city")); got != "[2 1 3]" {
+		t.Fatalf("order by json got %s", got)
+	}
+}
+
+func TestJSONQueriesCompilePerDriver(t *testing.T) {
+	build := func(driver string) (string, []any) {
+		return query.New(nil, driver, "users").
+			Where("meta->address->city", "Paris").
+			Where("meta->active", true).
+			WhereJsonContains("meta->tags", []string{"go"}).
+			WhereJsonLength("meta->tags", ">", 1).
+			WhereJsonContainsKey("meta->address->zip").
+			ToSQL()
+	}
+	sqlStr, args := build("mysql")
+	want := "SELECT * FROM users WHERE json_unquote(json_extract(meta, '$.\"address\".\"city\"')) = ? " +
+		"AND json_extract(meta, '$.\"active\"') = true " +
+		"AND json_contains(json_extract(meta, '$.\"tags\"'), ?) " +
+		"AND json_length(json_extract(meta, '$.\"tags\"')) > ? " +
+		"AND ifnull(json_contains_path(meta, 'one', '$.\"address\".\"zip\"'), 0)"
+	if sqlStr != want || fmt.Sprint(args) != `[Paris ["go"] 1]` {
+		t.Fatalf("mysql:\n%s\n%v", sqlStr, args)
+	}
+
+	sqlStr, _ = build("pgsql")
+	want = "SELECT * FROM users WHERE meta->'address'->>'city' = $1 " +
+		"AND (meta->'active')::jsonb = 'true'::jsonb " +
+		"AND ((meta->'tags')::jsonb @> $2::jsonb) " +
+		"AND jsonb_array_length((meta->'tags')::jsonb) > $3 " +
+		"AND coalesce(jsonb_exists((meta->'address')::jsonb, 'zip'), false)"
+	if sqlStr != want {
+		t.Fatalf("pgsql:\n%s", sqlStr)
+	}
+}
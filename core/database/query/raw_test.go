@@ -83,3 +83,31 @@ func TestSelectOrderHavingRaw(t *testing.T) {
 		t.Fatalf("expected c first, got %#v", rows[0])
 	}
 }
+
+func TestUpdateRawExpression(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE raw_counters (id INTEGER PRIMARY KEY, title TEXT, votes INTEGER)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = db.Exec(`INSERT INTO raw_counters (id, title, votes) VALUES (1, 'a', 3)`)
+
+	n, err := query.New(db, "sqlite", "raw_counters").
+		Where("id", 1).
+		Update(map[string]any{"title": "b", "votes": query.Raw("votes + ?", 2)})
+	if err != nil || n != 1 {
+		t.Fatalf("update n=%d err=%v", n, err)
+	}
+	var title string
+	var votes int
+	if err := db.QueryRow(`SELECT title, votes FROM raw_counters WHERE id = 1`).Scan(&title, &votes); err != nil {
+		t.Fatal(err)
+	}
+	if title != "b" || votes != 5 {
+		t.Fatalf("title=%s votes=%d", title, votes)
+	}
+}
@@ -0,0 +1,99 @@
+package query_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/database/query"
+)
+
+func openNamed(t *testing.T, name string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO items (name) VALUES (?)`, name); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func firstName(t *testing.T, b *query.Builder) string {
+	t.Helper()
+	v, err := b.Value("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v.(string)
+}
+
+func TestReadWriteSplitting(t *testing.T) {
+	writer := openNamed(t, "writer")
+	defer writer.Close()
+	reader := openNamed(t, "reader")
+	defer reader.Close()
+
+	build := func(ctx context.Context, sticky bool) *query.Builder {
+		return query.New(writer, "sqlite", "items").UseReadConnection(reader, sticky).WithContext(ctx)
+	}
+
+	ctx := query.TrackWrites(context.Background())
+	if got := firstName(t, build(ctx, true)); got != "reader" {
+		t.Fatalf("select went to %s", got)
+	}
+	if got := firstName(t, build(ctx, true).UseWriteConnection()); got != "writer" {
+		t.Fatalf("forced write select went to %s", got)
+	}
+	if _, err := build(ctx, true).Insert(map[string]any{"name": "new"}); err != nil {
+		t.Fatal(err)
+	}
+	if !query.RecordsModified(ctx) {
+		t.Fatal("write not tracked")
+	}
+	if n, _ := query.New(writer, "sqlite", "items").Count(); n != 2 {
+		t.Fatalf("insert did not reach writer, count=%d", n)
+	}
+	if got := firstName(t, build(ctx, true)); got != "writer" {
+		t.Fatalf("sticky select went to %s", got)
+	}
+	if got := firstName(t, build(ctx, false)); got != "reader" {
+		t.Fatalf("non-sticky select went to %s", got)
+	}
+	other := query.TrackWrites(context.Background())
+	if got := firstName(t, build(other, true)); got != "reader" {
+		t.Fatalf("select on another tracked context went to %s", got)
+	}
+}
+
+func TestStickyWindowWithoutTrackedContext(t *testing.T) {
+	writer := openNamed(t, "writer")
+	defer writer.Close()
+	reader := openNamed(t, "reader")
+	defer reader.Close()
+	defer query.SetStickyWindow(query.DefaultStickyWindow)
+
+	build := func() *query.Builder {
+		return query.New(writer, "sqlite", "items").UseReadConnection(reader, true)
+	}
+	if got := firstName(t, build()); got != "reader" {
+		t.Fatalf("select before any write went to %s", got)
+	}
+	if _, err := build().Insert(map[string]any{"name": "new"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := firstName(t, build()); got != "writer" {
+		t.Fatalf("select within the sticky window went to %s", got)
+	}
+	query.SetStickyWindow(0)
+	if got := firstName(t, build()); got != "reader" {
+		t.Fatalf("select with the window off went to %s", got)
+	}
+}
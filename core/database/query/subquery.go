@@ -0,0 +1,174 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+type cteClause struct {
+	name      string
+	columns   []string
+	sql       string
+	args      []any
+	recursive bool
+}
+
+// WithExpression adds a common table expression: WITH name (columns) AS (sub).
+func (b *Builder) WithExpression(name string, sub *Builder, columns ...string) *Builder {
+	sqlStr, args := sub.compile()
+	return b.addCTE(cteClause{name: name, columns: columns, sql: sqlStr, args: args})
+}
+
+// WithExpressionRaw adds a common table expression from raw SQL.
+func (b *Builder) WithExpressionRaw(name, sqlStr string, bindings ...any) *Builder {
+	return b.addCTE(cteClause{name: name, sql: sqlStr, args: bindings})
+}
+
+// WithRecursive adds a recursive common table expression; sub is usually an
+// anchor query combined with UnionAll.
+func (b *Builder) WithRecursive(name string, sub *Builder, columns ...string) *Builder {
+	sqlStr, args := sub.compile()
+	return b.addCTE(cteClause{name: name, columns: columns, sql: sqlStr, args: args, recursive: true})
+}
+
+func (b *Builder) addCTE(cte cteClause) *Builder {
+	b.ctes = append(b.ctes, cte)
+	return b
+}
+
+func (b *Builder) compileCTEs() (string, []any) {
+	if len(b.ctes) == 0 {
+		return "", nil
+	}
+	recursive := false
+	parts := make([]string, 0, len(b.ctes))
+	args := make([]any, 0)
+	for _, cte := range b.ctes {
+		recursive = recursive || cte.recursive
+		name := cte.name
+		if len(cte.columns) > 0 {
+			name += " (" + strings.Join(cte.columns, ", ") + ")"
+		}
+		parts = append(parts, fmt.Sprintf("%s AS (%s)", name, cte.sql))
+		args = append(args, cte.args...)
+	}
+	keyword := "WITH "
+	if recursive {
+		keyword = "WITH RECURSIVE "
+	}
+	return keyword + strings.Join(parts, ", ") + " ", args
+}
+
+// FromSub selects from a derived table: FROM (sub) AS alias.
+func (b *Builder) FromSub(sub *Builder, alias string) *Builder {
+	sqlStr, args := sub.compile()
+	return b.FromRaw(fmt.Sprintf("(%s) AS %s", sqlStr, alias), args...)
+}
+
+// FromRaw sets a raw FROM expression with optional bindings.
+func (b *Builder) FromRaw(expression string, bindings ...any) *Builder {
+	b.table = expression
+	b.fromBindings = bindings
+	return b
+}
+
+// SelectSub adds (sub) AS alias to the select list.
+func (b *Builder) SelectSub(sub *Builder, alias string) *Builder {
+	sqlStr, args := sub.compile()
+	return b.SelectRaw(fmt.Sprintf("(%s) AS %s", sqlStr, alias), args...)
+}
+
+// SelectExists adds EXISTS (sub) AS alias to the select list.
+func (b *Builder) SelectExists(sub *Builder, alias string) *Builder {
+	sqlStr, args := sub.compile()
+	return b.SelectRaw(fmt.Sprintf("EXISTS (%s) AS %s", sqlStr, alias), args...)
+}
+
+// JoinSub inner joins a derived table: INNER JOIN (sub) AS alias ON first operator second.
+func (b *Builder) JoinSub(sub *Builder, alias, first, operator, second string) *Builder {
+	return b.joinSub("INNER", sub, alias, first, operator, second)
+}
+
+// LeftJoinSub left joins a derived table.
+func (b *Builder) LeftJoinSub(sub *Builder, alias, first, operator, second string) *Builder {
+	return b.joinSub("LEFT", sub, alias, first, operator, second)
+}
+
+func (b *Builder) joinSub(kind string, sub *Builder, alias, first, operator, second string) *Builder {
+	sqlStr, args := sub.compile()
+	b.joins = append(b.joins, fmt.Sprintf("%s JOIN (%s) AS %s ON %s %s %s", kind, sqlStr, alias, first, operator, second))
+	b.joinBindings = append(b.joinBindings, args...)
+	return b
+}
+
+// Window describes an OVER (...) clause for window functions.
+type Window struct {
+	partitions []string
+	orders     []string
+	frame      string
+}
+
+// Over starts a window definition.
+func Over() *Window {
+	return &Window{}
+}
+
+// PartitionBy adds PARTITION BY columns.
+func (w *Window) PartitionBy(columns ...string) *Window {
+	w.partitions = append(w.partitions, columns...)
+	return w
+}
+
+// OrderBy adds an ORDER BY column to the window.
+func (w *Window) OrderBy(column string, direction ...string) *Window {
+	dir := "asc"
+	if len(direction) > 0 && direction[0] != "" {
+		dir = strings.ToLower(direction[0])
+	}
+	w.orders = append(w.orders, column+" "+dir)
+	return w
+}
+
+// Frame sets the frame clause, e.g. "ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW".
+func (w *Window) Frame(frame string) *Window {
+	w.frame = frame
+	return w
+}
+
+// String compiles the window to OVER (...).
+func (w *Window) String() string {
+	parts := make([]string, 0, 3)
+	if w != nil {
+		if len(w.partitions) > 0 {
+			parts = append(parts, "PARTITION BY "+strings.Join(w.partitions, ", "))
+		}
+		if len(w.orders) > 0 {
+			parts = append(parts, "ORDER BY "+strings.Join(w.orders, ", "))
+		}
+		if w.frame != "" {
+			parts = append(parts, w.frame)
+		}
+	}
+	return "OVER (" + strings.Join(parts, " ") + ")"
+}
+
+// SelectWindow adds function OVER (window) AS alias to the select list,
+// e.g. SelectWindow("SUM(amount)", query.Over().PartitionBy("account_id"), "running").
+func (b *Builder) SelectWindow(function string, window *Window, alias string, bindings ...any) *Builder {
+	return b.SelectRaw(fmt.Sprintf("%s %s AS %s", function, window, alias), bindings...)
+}
+
+// RowNumber selects ROW_NUMBER() over window as alias.
+func (b *Builder) RowNumber(window *Window, alias string) *Builder {
+	return b.SelectWindow("ROW_NUMBER()", window, alias)
+}
+
+// Rank selects RANK() over window as alias.
+func (b *Builder) Rank(window *Window, alias string) *Builder {
+	return b.SelectWindow("RANK()", window, alias)
+}
+
+// DenseRank selects DENSE_RANK() over window as alias.
+func (b *Builder) DenseRank(window *Window, alias string) *Builder {
+	return b.SelectWindow("DENSE_RANK()", window, alias)
+}
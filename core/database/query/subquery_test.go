@@ -0,0 +1,121 @@
+package query_test
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/database/query"
+)
+
+func setupReportDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements := []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, team TEXT)`,
+		`CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER, amount INTEGER)`,
+		`INSERT INTO users (id, name, team) VALUES (1, 'ada', 'red'), (2, 'bob', 'red'), (3, 'cy', 'blue')`,
+		`INSERT INTO orders (user_id, amount) VALUES (1, 10), (1, 30), (2, 5), (3, 50), (3, 1)`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestCommonTableExpressions(t *testing.T) {
+	db := setupReportDB(t)
+	defer db.Close()
+
+	totals := query.New(db, "sqlite", "orders").
+		Select("user_id").SelectRaw("SUM(amount) AS total").
+		Where("amount", ">", 1).GroupBy("user_id")
+	rows, err := query.New(db, "sqlite", "totals").
+		WithExpression("totals", totals).
+		Where("total", ">=", 40).OrderBy("user_id").Get()
+	if err != nil || len(rows) != 2 || fmt.Sprint(rows[0]["total"], rows[1]["total"]) != "40 50" {
+		t.Fatalf("rows=%v err=%v", rows, err)
+	}
+
+	anchor := query.New(db, "sqlite", "").FromRaw("(SELECT 1 AS n)")
+	step := query.New(db, "sqlite", "counter").SelectRaw("n + ?", 1).Where("n", "<", 5)
+	anchor.UnionAll(step)
+	n, err := query.New(db, "sqlite", "counter").WithRecursive("counter", anchor, "n").Sum("n")
+	if err != nil || n != 15 {
+		t.Fatalf("recursive sum=%v err=%v", n, err)
+	}
+}
+
+func TestSubquerySelectsAndJoins(t *testing.T) {
+	db := setupReportDB(t)
+	defer db.Close()
+
+	orderCount := query.New(db, "sqlite", "orders").SelectRaw("COUNT(*)").WhereColumn("orders.user_id", "users.id").Where("amount", ">", 2)
+	rows, err := query.New(db, "sqlite", "users").Select("name").SelectSub(orderCount, "big_orders").OrderBy("id").Get()
+	if err != nil || fmt.Sprint(rows[0]["big_orders"], rows[1]["big_orders"], rows[2]["big_orders"]) != "2 1 1" {
+		t.Fatalf("rows=%v err=%v", rows, err)
+	}
+
+	latest := query.New(db, "sqlite", "orders").Select("user_id").SelectRaw("MAX(amount) AS top").Where("amount", "<", 40).GroupBy("user_id")
+	rows, err = query.New(db, "sqlite", "users").
+		Select("users.name", "t.top").
+		JoinSub(latest, "t", "t.user_id", "=", "users.id").
+		Where("users.team", "red").OrderBy("users.id").Get()
+	if err != nil || len(rows) != 2 || rows[0]["top"] != int64(30) || rows[1]["top"] != int64(5) {
+		t.Fatalf("join rows=%v err=%v", rows, err)
+	}
+
+	count, err := query.New(db, "sqlite", "").
+		FromSub(query.New(db, "sqlite", "orders").Where("amount", ">", 5), "o").
+		Where("o.user_id", 3).Count()
+	if err != nil || count != 1 {
+		t.Fatalf("from sub count=%d err=%v", count, err)
+	}
+}
+
+func TestWindowFunctions(t *testing.T) {
+	db := setupReportDB(t)
+	defer db.Close()
+
+	rows, err := query.New(db, "sqlite", "orders").
+		Select("id", "user_id", "amount").
+		RowNumber(query.Over().PartitionBy("user_id").OrderBy("amount", "desc"), "rn").
+		SelectWindow("SUM(amount)", query.Over().OrderBy("id").Frame("ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW"), "running").
+		OrderBy("id").Get()
+	if err != nil || len(rows) != 5 {
+		t.Fatalf("rows=%v err=%v", rows, err)
+	}
+	got := ""
+	for _, row := range rows {
+		got += fmt.Sprint(row["rn"], ":", row["running"], " ")
+	}
+	if got != "2:10 1:40 1:45 1:95 2:96 " {
+		t.Fatalf("window values=%q", got)
+	}
+}
+
+func TestSubqueryBindingsRebindInOrder(t *testing.T) {
+	sub := query.New(nil, "pgsql", "orders").Where("amount", ">", 10)
+	cte := query.New(nil, "pgsql", "users").Where("active", true)
+	sqlStr, args := query.New(nil, "pgsql", "users").
+		WithExpression("active_users", cte).
+		SelectSub(query.New(nil, "pgsql", "orders").SelectRaw("COUNT(*)").Where("status", "paid"), "paid").
+		JoinSub(sub, "o", "o.user_id", "=", "users.id").
+		Where("users.team", "red").
+		WhereExists(query.New(nil, "pgsql", "bans").Where("reason", "spam")).
+		ToSQL()
+	want := "WITH active_users AS (SELECT * FROM users WHERE active = $1) " +
+		"SELECT (SELECT COUNT(*) FROM orders WHERE status = $2) AS paid FROM users " +
+		"INNER JOIN (SELECT * FROM orders WHERE amount > $3) AS o ON o.user_id = users.id " +
+		"WHERE users.team = $4 AND EXISTS (SELECT 1 FROM bans WHERE reason = $5)"
+	if sqlStr != want || fmt.Sprint(args) != "[true paid 10 red spam]" {
+		t.Fatalf("sql=%s\nargs=%v", sqlStr, args)
+	}
+}
@@ -0,0 +1,55 @@
+package database_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/zatrano/framework/core/database"
+)
+
+func TestReadConnectionFallsBackToWriter(t *testing.T) {
+	dir := t.TempDir()
+	mgr := database.NewManager(database.Config{
+		Default: "main",
+		Connections: map[string]database.ConnectionConfig{
+			"main":     {Driver: "sqlite", Database: filepath.Join(dir, "main.sqlite")},
+			"replicas": {Driver: "sqlite", Database: filepath.Join(dir, "replicas.sqlite"), Read: []string{"r1", "r2"}, Sticky: true},
+		},
+	}, dir)
+	defer mgr.Close()
+
+	writer, err := mgr.Connection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := mgr.ReadConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db, ok := reader.(*sql.DB); !ok || db != writer {
+		t.Fatalf("expected writer fallback, got %T", reader)
+	}
+
+	pool, err := mgr.ReadConnection("replicas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pool.(*sql.DB); ok {
+		t.Fatal("expected a replica pool")
+	}
+	again, _ := mgr.ReadConnection("replicas")
+	if again != pool {
+		t.Fatal("replica pool should be cached")
+	}
+	if _, err := pool.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+	items, err := mgr.Table("items", "replicas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := items.Count(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -6,27 +6,51 @@ import (
 	"strings"
 )
 
+// Executor runs schema statements; *sql.DB and *sql.Tx both satisfy it.
+type Executor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
 // Builder builds and executes schema statements.
 type Builder struct {
-	db     *sql.DB
-	driver string
+	db        Executor
+	driver    string
+	pretend   bool
+	pretended []string
 }
 
 // New creates a schema builder.
-func New(db *sql.DB, driver string) *Builder {
+func New(db Executor, driver string) *Builder {
 	return &Builder{db: db, driver: driver}
 }
 
+// Pretend returns a builder that records statements instead of executing
+// them. Introspection queries still run against the connection.
+func (b *Builder) Pretend() *Builder {
+	return &Builder{db: b.db, driver: b.driver, pretend: true}
+}
+
+// Pretended returns the statements recorded by a Pretend builder.
+func (b *Builder) Pretended() []string {
+	return append([]string{}, b.pretended...)
+}
+
+// Driver returns the builder's driver name.
+func (b *Builder) Driver() string {
+	return b.driver
+}
+
 // Create creates a table.
 func (b *Builder) Create(table string, callback func(*Blueprint)) error {
 	bp := NewBlueprint(table, b.driver)
 	callback(bp)
-	sqlStr, err := bp.ToCreateSQL()
+	statements, err := bp.ToCreateStatements()
 	if err != nil {
 		return err
 	}
-	_, err = b.db.Exec(sqlStr)
-	return err
+	return b.exec(statements...)
 }
 
 // Table alters a table.
@@ -34,22 +58,36 @@ func (b *Builder) Table(table string, callback func(*Blueprint)) error {
 	bp := NewBlueprint(table, b.driver)
 	bp.altering = true
 	callback(bp)
-	statements, err := bp.ToAlterSQL()
-	if err != nil {
-		return err
+	if bp.isSQLite() && bp.needsRebuild() && !b.pretend {
+		// The rebuild drops the original table, so it runs atomically and
+		// without foreign keys cascading the drop into child tables.
+		return b.withoutForeignKeys(func(b *Builder) error {
+			return b.transaction(func(b *Builder) error {
+				return b.alter(bp)
+			})
+		})
 	}
-	for _, sqlStr := range statements {
-		if _, err := b.db.Exec(sqlStr); err != nil {
+	return b.alter(bp)
+}
+
+func (b *Builder) alter(bp *Blueprint) error {
+	if bp.isSQLite() && bp.needsRebuild() {
+		existing, err := loadSQLiteTable(b.db, bp.table)
+		if err != nil {
 			return err
 		}
+		bp.existing = existing
 	}
-	return nil
+	statements, err := bp.ToAlterSQL()
+	if err != nil {
+		return err
+	}
+	return b.exec(statements...)
 }
 
 // Drop drops a table.
 func (b *Builder) Drop(table string) error {
-	_, err := b.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", table))
-	return err
+	return b.exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", table))
 }
 
 // DropIfExists drops a table if it exists.
@@ -59,17 +97,23 @@ func (b *Builder) DropIfExists(table string) error {
 
 // Rename renames a table.
 func (b *Builder) Rename(from, to string) error {
-	switch b.driver {
-	case "sqlite", "sqlite3":
-		_, err := b.db.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", from, to))
-		return err
-	default:
-		_, err := b.db.Exec(fmt.Sprintf("RENAME TABLE %s TO %s", from, to))
-		if err != nil {
-			_, err = b.db.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", from, to))
+	if b.driver == "mysql" {
+		return b.exec(fmt.Sprintf("RENAME TABLE %s TO %s", from, to))
+	}
+	return b.exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", from, to))
+}
+
+func (b *Builder) exec(statements ...string) error {
+	for _, sqlStr := range statements {
+		if b.pretend {
+			b.pretended = append(b.pretended, sqlStr)
+			continue
+		}
+		if _, err := b.db.Exec(sqlStr); err != nil {
+			return err
 		}
-		return err
 	}
+	return nil
 }
 
 // HasTable reports whether a table exists.
@@ -101,6 +145,11 @@ type Blueprint struct {
 	table    string
 	driver   string
 	columns  []*Column
+	indexes  []*Index
+	foreigns []*ForeignKey
+	renames  []columnRename
+	drops    []dropCommand
+	existing *sqliteTable
 	altering bool
 }
 
@@ -113,9 +162,25 @@ type Column struct {
 	Primary       bool
 	AutoIncrement bool
 	IsUnique      bool
+	IsUnsigned    bool
 	DefaultValue  any
+	UseCurrentNow bool
+	Allowed       []string
 	Comment       string
+	AfterColumn   string
 	Change        string // add|modify|drop
+
+	blueprint *Blueprint
+}
+
+type columnRename struct {
+	from string
+	to   string
+}
+
+type dropCommand struct {
+	kind string // index|unique|primary|foreign
+	name string
 }
 
 // NewBlueprint creates a blueprint.
@@ -123,21 +188,27 @@ func NewBlueprint(table, driver string) *Blueprint {
 	return &Blueprint{table: table, driver: driver}
 }
 
+func (b *Blueprint) addColumn(col *Column) *Column {
+	col.blueprint = b
+	if col.Change == "" {
+		col.Change = "add"
+	}
+	b.columns = append(b.columns, col)
+	return col
+}
+
 // ID adds a big integer auto-incrementing primary key named id.
 func (b *Blueprint) ID(name ...string) *Column {
 	colName := "id"
 	if len(name) > 0 && name[0] != "" {
 		colName = name[0]
 	}
-	col := &Column{
+	return b.addColumn(&Column{
 		Name:          colName,
 		Type:          "id",
 		Primary:       true,
 		AutoIncrement: true,
-		Change:        "add",
-	}
-	b.columns = append(b.columns, col)
-	return col
+	})
 }
 
 // BigIncrements adds an auto-incrementing unsigned big integer.
@@ -151,44 +222,51 @@ func (b *Blueprint) String(name string, length ...int) *Column {
 	if len(length) > 0 {
 		l = length[0]
 	}
-	col := &Column{Name: name, Type: "string", Length: l, Change: "add"}
-	b.columns = append(b.columns, col)
-	return col
+	return b.addColumn(&Column{Name: name, Type: "string", Length: l})
+}
+
+// Char adds a fixed-length char column.
+func (b *Blueprint) Char(name string, length ...int) *Column {
+	l := 255
+	if len(length) > 0 {
+		l = length[0]
+	}
+	return b.addColumn(&Column{Name: name, Type: "char", Length: l})
 }
 
 // Text adds a text column.
 func (b *Blueprint) Text(name string) *Column {
-	col := &Column{Name: name, Type: "text", Change: "add"}
-	b.columns = append(b.columns, col)
-	return col
+	return b.addColumn(&Column{Name: name, Type: "text"})
 }
 
 // Integer adds an integer column.
 func (b *Blueprint) Integer(name string) *Column {
-	col := &Column{Name: name, Type: "integer", Change: "add"}
-	b.columns = append(b.columns, col)
-	return col
+	return b.addColumn(&Column{Name: name, Type: "integer"})
 }
 
 // BigInteger adds a big integer column.
 func (b *Blueprint) BigInteger(name string) *Column {
-	col := &Column{Name: name, Type: "biginteger", Change: "add"}
-	b.columns = append(b.columns, col)
-	return col
+	return b.addColumn(&Column{Name: name, Type: "biginteger"})
 }
 
 // Boolean adds a boolean column.
 func (b *Blueprint) Boolean(name string) *Column {
-	col := &Column{Name: name, Type: "boolean", Change: "add"}
-	b.columns = append(b.columns, col)
-	return col
+	return b.addColumn(&Column{Name: name, Type: "boolean"})
+}
+
+// Float adds a single-precision floating point column.
+func (b *Blueprint) Float(name string) *Column {
+	return b.addColumn(&Column{Name: name, Type: "float"})
+}
+
+// Date adds a date column.
+func (b *Blueprint) Date(name string) *Column {
+	return b.addColumn(&Column{Name: name, Type: "date"})
 }
 
 // Timestamp adds a timestamp column.
 func (b *Blueprint) Timestamp(name string) *Column {
-	col := &Column{Name: name, Type: "timestamp", IsNullable: true, Change: "add"}
-	b.columns = append(b.columns, col)
-	return col
+	return b.addColumn(&Column{Name: name, Type: "timestamp", IsNullable: true})
 }
 
 // Timestamps adds created_at and updated_at.
@@ -204,36 +282,51 @@ func (b *Blueprint) SoftDeletes() *Column {
 
 // Decimal adds a decimal column.
 func (b *Blueprint) Decimal(name string, precision, scale int) *Column {
-	col := &Column{
-		Name:   name,
-		Type:   fmt.Sprintf("decimal:%d:%d", precision, scale),
-		Change: "add",
-	}
-	b.columns = append(b.columns, col)
-	return col
+	return b.addColumn(&Column{Name: name, Type: fmt.Sprintf("decimal:%d:%d", precision, scale)})
 }
 
 // JSON adds a JSON column.
 func (b *Blueprint) JSON(name string) *Column {
-	col := &Column{Name: name, Type: "json", Change: "add"}
-	b.columns = append(b.columns, col)
-	return col
+	return b.addColumn(&Column{Name: name, Type: "json"})
+}
+
+// Binary adds a binary (blob) column.
+func (b *Blueprint) Binary(name string) *Column {
+	return b.addColumn(&Column{Name: name, Type: "binary"})
+}
+
+// UUID adds a UUID column (native uuid on postgres, char(36) elsewhere).
+func (b *Blueprint) UUID(name string) *Column {
+	return b.addColumn(&Column{Name: name, Type: "uuid"})
+}
+
+// ULID adds a char(26) ULID column.
+func (b *Blueprint) ULID(name string) *Column {
+	return b.addColumn(&Column{Name: name, Type: "ulid"})
+}
+
+// Enum adds a column restricted to values (ENUM on mysql, CHECK constraint elsewhere).
+func (b *Blueprint) Enum(name string, values []string) *Column {
+	return b.addColumn(&Column{Name: name, Type: "enum", Allowed: values})
 }
 
 // ForeignID adds an unsigned big integer foreign id column.
 func (b *Blueprint) ForeignID(name string) *Column {
-	col := &Column{Name: name, Type: "biginteger", Change: "add"}
-	b.columns = append(b.columns, col)
-	return col
+	return b.addColumn(&Column{Name: name, Type: "biginteger", IsUnsigned: true})
 }
 
 // DropColumn drops columns (alter mode).
 func (b *Blueprint) DropColumn(columns ...string) {
 	for _, column := range columns {
-		b.columns = append(b.columns, &Column{Name: column, Change: "drop"})
+		b.columns = append(b.columns, &Column{Name: column, Change: "drop", blueprint: b})
 	}
 }
 
+// RenameColumn renames a column (alter mode).
+func (b *Blueprint) RenameColumn(from, to string) {
+	b.renames = append(b.renames, columnRename{from: from, to: to})
+}
+
 // Unique marks the column as unique.
 func (c *Column) Unique() *Column {
 	c.IsUnique = true
@@ -252,12 +345,67 @@ func (c *Column) Default(value any) *Column {
 	return c
 }
 
-// ToCreateSQL compiles CREATE TABLE SQL.
+// UseCurrent defaults the column to CURRENT_TIMESTAMP.
+func (c *Column) UseCurrent() *Column {
+	c.UseCurrentNow = true
+	return c
+}
+
+// Unsigned marks a numeric column unsigned (mysql only).
+func (c *Column) Unsigned() *Column {
+	c.IsUnsigned = true
+	return c
+}
+
+// WithComment attaches a column comment (mysql and postgres).
+func (c *Column) WithComment(comment string) *Column {
+	c.Comment = comment
+	return c
+}
+
+// After places an added column after another column (mysql only).
+func (c *Column) After(column string) *Column {
+	c.AfterColumn = column
+	return c
+}
+
+// Modify turns the definition into a change of an existing column (alter mode).
+func (c *Column) Modify() *Column {
+	c.Change = "modify"
+	return c
+}
+
+// Index adds a plain index on the column.
+func (c *Column) Index(name ...string) *Column {
+	if c.blueprint != nil {
+		idx := c.blueprint.Index(c.Name)
+		if len(name) > 0 && name[0] != "" {
+			idx.Named(name[0])
+		}
+	}
+	return c
+}
+
+// Constrained adds a foreign key referencing id on table, guessed from the
+// column name when omitted (user_id -> users).
+func (c *Column) Constrained(table ...string) *ForeignKey {
+	target := guessTable(c.Name)
+	if len(table) > 0 && table[0] != "" {
+		target = table[0]
+	}
+	if c.blueprint == nil {
+		return &ForeignKey{Columns: []string{c.Name}, ReferencedTable: target, ReferencedColumns: []string{"id"}}
+	}
+	return c.blueprint.Foreign(c.Name).References("id").On(target)
+}
+
+// ToCreateSQL compiles the CREATE TABLE statement, including primary and foreign keys.
+// Indexes and postgres comments are separate statements; see ToCreateStatements.
 func (b *Blueprint) ToCreateSQL() (string, error) {
 	if len(b.columns) == 0 {
 		return "", fmt.Errorf("no columns defined for table %s", b.table)
 	}
-	defs := make([]string, 0, len(b.columns))
+	defs := make([]string, 0, len(b.columns)+len(b.foreigns)+1)
 	for _, col := range b.columns {
 		if col.Change == "drop" {
 			continue
@@ -268,23 +416,151 @@ func (b *Blueprint) ToCreateSQL() (string, error) {
 		}
 		defs = append(defs, def)
 	}
+	for _, idx := range b.indexes {
+		if idx.Type == "primary" {
+			defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(idx.Columns, ", ")))
+		}
+	}
+	for _, fk := range b.foreigns {
+		def, err := b.foreignSQL(fk)
+		if err != nil {
+			return "", err
+		}
+		defs = append(defs, def)
+	}
 	return fmt.Sprintf("CREATE TABLE %s (%s)", b.table, strings.Join(defs, ", ")), nil
 }
 
+// ToCreateStatements compiles CREATE TABLE followed by index and comment statements.
+func (b *Blueprint) ToCreateStatements() ([]string, error) {
+	create, err := b.ToCreateSQL()
+	if err != nil {
+		return nil, err
+	}
+	statements := []string{create}
+	for _, idx := range b.indexes {
+		if idx.Type != "primary" {
+			statements = append(statements, b.createIndexSQL(idx))
+		}
+	}
+	if b.isPostgres() {
+		for _, col := range b.columns {
+			if col.Comment != "" && col.Change != "drop" {
+				statements = append(statements, b.commentSQL(col))
+			}
+		}
+	}
+	return statements, nil
+}
+
 // ToAlterSQL compiles ALTER TABLE statements.
+// SQLite changes it cannot ALTER in place (modify, keys) compile to a table
+// rebuild, which needs the current definition loaded by Builder.Table.
 func (b *Blueprint) ToAlterSQL() ([]string, error) {
+	b.altering = true
+	if b.isSQLite() && b.needsRebuild() {
+		if b.existing == nil {
+			return nil, fmt.Errorf("sqlite table [%s] must be rebuilt; alter it through schema.Builder.Table", b.table)
+		}
+		return b.rebuildSQLite()
+	}
+
 	statements := make([]string, 0)
+	for _, drop := range b.drops {
+		if drop.kind == "foreign" {
+			statements = append(statements, b.dropSQL(drop))
+		}
+	}
+	for _, drop := range b.drops {
+		if drop.kind != "foreign" {
+			statements = append(statements, b.dropSQL(drop))
+		}
+	}
+	for _, rename := range b.renames {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", b.table, rename.from, rename.to))
+	}
 	for _, col := range b.columns {
 		switch col.Change {
 		case "drop":
-			statements = append(statements, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", b.table, col.Name))
+			continue
+		case "modify":
+			stmts, err := b.modifySQL(col)
+			if err != nil {
+				return nil, err
+			}
+			statements = append(statements, stmts...)
 		default:
-			def, err := b.columnSQL(col)
+			add := col
+			if b.isSQLite() && col.IsUnique {
+				// SQLite cannot add a UNIQUE column; add it plain and index it.
+				cp := *col
+				cp.IsUnique = false
+				add = &cp
+			}
+			def, err := b.columnSQL(add)
 			if err != nil {
 				return nil, err
 			}
 			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD %s", b.table, def))
+			if add != col {
+				statements = append(statements, b.createIndexSQL(&Index{Name: indexName(b.table, "unique", col.Name), Type: "unique", Columns: []string{col.Name}}))
+			}
+			if col.Comment != "" && b.isPostgres() {
+				statements = append(statements, b.commentSQL(col))
+			}
+		}
+	}
+	for _, col := range b.columns {
+		if col.Change == "drop" {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", b.table, col.Name))
+		}
+	}
+	for _, idx := range b.indexes {
+		if idx.Type == "primary" {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s PRIMARY KEY (%s)", b.table, idx.Name, strings.Join(idx.Columns, ", ")))
+			continue
 		}
+		statements = append(statements, b.createIndexSQL(idx))
+	}
+	for _, fk := range b.foreigns {
+		def, err := b.foreignSQL(fk)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD %s", b.table, def))
+	}
+	return statements, nil
+}
+
+func (b *Blueprint) modifySQL(col *Column) ([]string, error) {
+	if !b.isPostgres() {
+		def, err := b.columnSQL(col)
+		if err != nil {
+			return nil, err
+		}
+		return []string{fmt.Sprintf("ALTER TABLE %s MODIFY %s", b.table, def)}, nil
+	}
+	typeSQL, err := b.typeSQL(col)
+	if err != nil {
+		return nil, err
+	}
+	prefix := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s ", b.table, col.Name)
+	statements := []string{prefix + "TYPE " + typeSQL + " USING " + col.Name + "::" + typeSQL}
+	if col.IsNullable {
+		statements = append(statements, prefix+"DROP NOT NULL")
+	} else {
+		statements = append(statements, prefix+"SET NOT NULL")
+	}
+	if def := defaultSQL(col); def != "" {
+		statements = append(statements, prefix+"SET "+def)
+	} else {
+		statements = append(statements, prefix+"DROP DEFAULT")
+	}
+	if col.IsUnique {
+		statements = append(statements, b.createIndexSQL(&Index{Name: indexName(b.table, "unique", col.Name), Type: "unique", Columns: []string{col.Name}}))
+	}
+	if col.Comment != "" {
+		statements = append(statements, b.commentSQL(col))
 	}
 	return statements, nil
 }
@@ -297,6 +573,10 @@ func (b *Blueprint) columnSQL(col *Column) (string, error) {
 
 	parts := []string{col.Name, typeSQL}
 
+	if col.IsUnsigned && b.driver == "mysql" && col.Type != "id" {
+		parts = append(parts, "UNSIGNED")
+	}
+
 	if col.AutoIncrement {
 		switch b.driver {
 		case "mysql":
@@ -311,14 +591,25 @@ func (b *Blueprint) columnSQL(col *Column) (string, error) {
 	if !col.IsNullable && !col.Primary {
 		parts = append(parts, "NOT NULL")
 	}
-	if col.Primary {
+	if col.Primary && col.Change != "modify" {
 		parts = append(parts, "PRIMARY KEY")
 	}
 	if col.IsUnique {
 		parts = append(parts, "UNIQUE")
 	}
-	if col.DefaultValue != nil {
-		parts = append(parts, fmt.Sprintf("DEFAULT %s", formatDefault(col.DefaultValue)))
+	if def := defaultSQL(col); def != "" {
+		parts = append(parts, def)
+	}
+	if col.Type == "enum" && b.driver != "mysql" {
+		parts = append(parts, fmt.Sprintf("CHECK (%s IN (%s))", col.Name, quoteList(col.Allowed)))
+	}
+	if b.driver == "mysql" {
+		if col.Comment != "" {
+			parts = append(parts, "COMMENT "+formatDefault(col.Comment))
+		}
+		if col.AfterColumn != "" && b.altering {
+			parts = append(parts, "AFTER "+col.AfterColumn)
+		}
 	}
 
 	return strings.Join(parts, " "), nil
@@ -337,6 +628,8 @@ func (b *Blueprint) typeSQL(col *Column) (string, error) {
 		}
 	case "string":
 		return fmt.Sprintf("VARCHAR(%d)", col.Length), nil
+	case "char":
+		return fmt.Sprintf("CHAR(%d)", col.Length), nil
 	case "text":
 		return "TEXT", nil
 	case "integer":
@@ -355,6 +648,15 @@ func (b *Blueprint) typeSQL(col *Column) (string, error) {
 		default:
 			return "BOOLEAN", nil
 		}
+	case "float":
+		switch b.driver {
+		case "mysql":
+			return "FLOAT", nil
+		default:
+			return "REAL", nil
+		}
+	case "date":
+		return "DATE", nil
 	case "timestamp":
 		switch b.driver {
 		case "pgsql", "postgres", "postgresql":
@@ -369,6 +671,32 @@ func (b *Blueprint) typeSQL(col *Column) (string, error) {
 		default:
 			return "TEXT", nil
 		}
+	case "binary":
+		switch b.driver {
+		case "pgsql", "postgres", "postgresql":
+			return "BYTEA", nil
+		default:
+			return "BLOB", nil
+		}
+	case "uuid":
+		switch b.driver {
+		case "pgsql", "postgres", "postgresql":
+			return "UUID", nil
+		default:
+			return "CHAR(36)", nil
+		}
+	case "ulid":
+		return "CHAR(26)", nil
+	case "enum":
+		if len(col.Allowed) == 0 {
+			return "", fmt.Errorf("enum column %s requires values", col.Name)
+		}
+		switch b.driver {
+		case "mysql":
+			return fmt.Sprintf("ENUM(%s)", quoteList(col.Allowed)), nil
+		default:
+			return "VARCHAR(255)", nil
+		}
 	default:
 		if strings.HasPrefix(col.Type, "decimal:") {
 			parts := strings.Split(col.Type, ":")
@@ -378,6 +706,36 @@ func (b *Blueprint) typeSQL(col *Column) (string, error) {
 	}
 }
 
+func (b *Blueprint) commentSQL(col *Column) string {
+	return fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s", b.table, col.Name, formatDefault(col.Comment))
+}
+
+func (b *Blueprint) isSQLite() bool {
+	return b.driver == "sqlite" || b.driver == "sqlite3"
+}
+
+func (b *Blueprint) isPostgres() bool {
+	return b.driver == "pgsql" || b.driver == "postgres" || b.driver == "postgresql"
+}
+
+func defaultSQL(col *Column) string {
+	if col.UseCurrentNow {
+		return "DEFAULT CURRENT_TIMESTAMP"
+	}
+	if col.DefaultValue != nil {
+		return fmt.Sprintf("DEFAULT %s", formatDefault(col.DefaultValue))
+	}
+	return ""
+}
+
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = formatDefault(v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
 func formatDefault(value any) string {
 	switch v := value.(type) {
 	case string:
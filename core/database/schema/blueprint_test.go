@@ -0,0 +1,226 @@
+package schema_test
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/database/schema"
+)
+
+func TestCreateStatementsPerDriver(t *testing.T) {
+	define := func(table *schema.Blueprint) {
+		table.ID()
+		table.ForeignID("user_id").Constrained().CascadeOnDelete()
+		table.UUID("uuid").Unique()
+		table.Enum("status", []string{"draft", "live"}).Default("draft")
+		table.Float("score").Unsigned()
+		table.Char("code", 8).WithComment("short code")
+		table.Timestamp("published_at").UseCurrent()
+		table.Index("status", "published_at")
+	}
+
+	cases := map[string][]string{
+		"mysql": {
+			"user_id BIGINT UNSIGNED NOT NULL",
+			"uuid CHAR(36) NOT NULL UNIQUE",
+			"status ENUM('draft', 'live') NOT NULL DEFAULT 'draft'",
+			"score FLOAT UNSIGNED NOT NULL",
+			"code CHAR(8) NOT NULL COMMENT 'short code'",
+			"published_at DATETIME DEFAULT CURRENT_TIMESTAMP",
+			"CONSTRAINT posts_user_id_foreign FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE",
+		},
+		"pgsql": {
+			"id BIGSERIAL PRIMARY KEY",
+			"uuid UUID NOT NULL UNIQUE",
+			"status VARCHAR(255) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'live'))",
+			"score REAL NOT NULL",
+		},
+	}
+	for driver, wants := range cases {
+		bp := schema.NewBlueprint("posts", driver)
+		define(bp)
+		statements, err := bp.ToCreateStatements()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range wants {
+			if !strings.Contains(statements[0], want) {
+				t.Fatalf("%s: missing %q in %s", driver, want, statements[0])
+			}
+		}
+		if statements[1] != "CREATE INDEX posts_status_published_at_index ON posts (status, published_at)" {
+			t.Fatalf("%s: index statement %q", driver, statements[1])
+		}
+		if driver == "pgsql" && statements[len(statements)-1] != "COMMENT ON COLUMN posts.code IS 'short code'" {
+			t.Fatalf("pgsql comment statement %q", statements[len(statements)-1])
+		}
+	}
+}
+
+func TestAlterStatementsPerDriver(t *testing.T) {
+	bp := schema.NewBlueprint("posts", "mysql")
+	bp.String("title", 100).Modify()
+	bp.String("slug").After("title")
+	bp.RenameColumn("body", "content")
+	bp.DropForeign("posts_user_id_foreign")
+	bp.DropIndex("posts_status_index")
+	bp.Foreign("author_id").References("id").On("authors").NullOnDelete()
+	statements, err := bp.ToAlterSQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"ALTER TABLE posts DROP FOREIGN KEY posts_user_id_foreign",
+		"DROP INDEX posts_status_index ON posts",
+		"ALTER TABLE posts RENAME COLUMN body TO content",
+		"ALTER TABLE posts MODIFY title VARCHAR(100) NOT NULL",
+		"ALTER TABLE posts ADD slug VARCHAR(255) NOT NULL AFTER title",
+		"ALTER TABLE posts ADD CONSTRAINT posts_author_id_foreign FOREIGN KEY (author_id) REFERENCES authors (id) ON DELETE SET NULL",
+	}
+	if strings.Join(statements, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("mysql alter:\n%s", strings.Join(statements, "\n"))
+	}
+
+	pg := schema.NewBlueprint("posts", "pgsql")
+	pg.Integer("views").Nullable().Modify()
+	pg.DropPrimary()
+	statements, err = pg.ToAlterSQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statements[0] != "ALTER TABLE posts DROP CONSTRAINT posts_pkey" ||
+		statements[1] != "ALTER TABLE posts ALTER COLUMN views TYPE INTEGER USING views::INTEGER" ||
+		statements[2] != "ALTER TABLE posts ALTER COLUMN views DROP NOT NULL" {
+		t.Fatalf("pgsql alter:\n%s", strings.Join(statements, "\n"))
+	}
+
+	lite := schema.NewBlueprint("posts", "sqlite")
+	lite.String("title").Modify()
+	if _, err := lite.ToAlterSQL(); err == nil {
+		t.Fatal("expected sqlite rebuild to require the schema builder")
+	}
+}
+
+func TestSQLiteRebuildKeepsDataAndKeys(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	b := schema.New(db, "sqlite")
+
+	if err := b.Create("users", func(table *schema.Blueprint) {
+		table.ID()
+		table.String("email").Unique()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Create("posts", func(table *schema.Blueprint) {
+		table.ID()
+		table.ForeignID("user_id").Constrained()
+		table.String("title")
+		table.Integer("views").Default(0)
+		table.Index("title")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO users (email) VALUES ('a@example.com')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO posts (user_id, title, views) VALUES (1, 'hello', 3)`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Table("posts", func(table *schema.Blueprint) {
+		table.Text("title").Nullable().Modify()
+		table.RenameColumn("views", "hits")
+		table.DropForeign("posts_user_id_foreign")
+		table.ForeignID("editor_id").Nullable().Constrained("users").NullOnDelete()
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var title string
+	var hits int
+	if err := db.QueryRow(`SELECT title, hits FROM posts WHERE id = 1`).Scan(&title, &hits); err != nil || title != "hello" || hits != 3 {
+		t.Fatalf("title=%q hits=%d err=%v", title, hits, err)
+	}
+	var fkTable, fkFrom, onDelete string
+	if err := db.QueryRow(`SELECT "table", "from", on_delete FROM pragma_foreign_key_list('posts')`).Scan(&fkTable, &fkFrom, &onDelete); err != nil {
+		t.Fatal(err)
+	}
+	if fkTable != "users" || fkFrom != "editor_id" || onDelete != "SET NULL" {
+		t.Fatalf("foreign key %s.%s on delete %s", fkTable, fkFrom, onDelete)
+	}
+	var indexes int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='index' AND name='posts_title_index'`).Scan(&indexes); err != nil || indexes != 1 {
+		t.Fatalf("index recreated=%d err=%v", indexes, err)
+	}
+	if _, err := db.Exec(`INSERT INTO posts (user_id, title) VALUES (1, NULL)`); err != nil {
+		t.Fatalf("modified column should be nullable: %v", err)
+	}
+}
+
+func TestSQLiteRebuildIsAtomicAndKeepsChildRows(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	b := schema.New(db, "sqlite")
+
+	if err := b.Create("users", func(table *schema.Blueprint) {
+		table.ID()
+		table.String("email")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Create("posts", func(table *schema.Blueprint) {
+		table.ID()
+		table.ForeignID("user_id").Constrained().CascadeOnDelete()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`PRAGMA foreign_keys = ON`,
+		`INSERT INTO users (email) VALUES ('a@example.com'), ('a@example.com')`,
+		`INSERT INTO posts (user_id) VALUES (1)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = b.Table("users", func(table *schema.Blueprint) {
+		table.String("email", 100).Modify()
+		table.Unique("email")
+	})
+	if err == nil {
+		t.Fatal("expected the unique index to fail on duplicate emails")
+	}
+	var users int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&users); err != nil || users != 2 {
+		t.Fatalf("users=%d err=%v", users, err)
+	}
+
+	if _, err := db.Exec(`DELETE FROM users WHERE id = 2`); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Table("users", func(table *schema.Blueprint) {
+		table.String("email", 100).Modify()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var posts, enabled int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM posts`).Scan(&posts); err != nil || posts != 1 {
+		t.Fatalf("child rows cascaded away: posts=%d err=%v", posts, err)
+	}
+	if err := db.QueryRow(`PRAGMA foreign_keys`).Scan(&enabled); err != nil || enabled != 1 {
+		t.Fatalf("foreign_keys=%d err=%v", enabled, err)
+	}
+}
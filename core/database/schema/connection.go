@@ -0,0 +1,83 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// connExecutor runs statements on a single pooled connection.
+type connExecutor struct {
+	conn *sql.Conn
+}
+
+func (c connExecutor) Exec(query string, args ...any) (sql.Result, error) {
+	return c.conn.ExecContext(context.Background(), query, args...)
+}
+
+func (c connExecutor) Query(query string, args ...any) (*sql.Rows, error) {
+	return c.conn.QueryContext(context.Background(), query, args...)
+}
+
+func (c connExecutor) QueryRow(query string, args ...any) *sql.Row {
+	return c.conn.QueryRowContext(context.Background(), query, args...)
+}
+
+// withoutForeignKeys runs fn with foreign key checks disabled. SQLite's
+// foreign_keys pragma and MySQL's FOREIGN_KEY_CHECKS are per connection, so
+// fn gets a builder pinned to one connection, whose previous setting is
+// restored before it returns to the pool. On a transaction, where the
+// setting cannot change, and on other drivers fn runs on the builder as is.
+func (b *Builder) withoutForeignKeys(fn func(*Builder) error) error {
+	db, ok := b.db.(*sql.DB)
+	if !ok || b.pretend {
+		return fn(b)
+	}
+	var current, disable, restore string
+	switch {
+	case b.isSQLite():
+		current, disable, restore = "PRAGMA foreign_keys", "PRAGMA foreign_keys = OFF", "PRAGMA foreign_keys = %d"
+	case b.driver == "mysql":
+		current, disable, restore = "SELECT @@FOREIGN_KEY_CHECKS", "SET FOREIGN_KEY_CHECKS = 0", "SET FOREIGN_KEY_CHECKS = %d"
+	default:
+		return fn(b)
+	}
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	exec := connExecutor{conn: conn}
+	var enabled int
+	if err := exec.QueryRow(current).Scan(&enabled); err != nil {
+		return err
+	}
+	if _, err := exec.Exec(disable); err != nil {
+		return err
+	}
+	defer exec.Exec(fmt.Sprintf(restore, enabled))
+	return fn(&Builder{db: exec, driver: b.driver})
+}
+
+// transaction runs fn inside a transaction: a new one on a connection or
+// pool, or the caller's when the builder already runs on a *sql.Tx.
+func (b *Builder) transaction(fn func(*Builder) error) error {
+	var tx *sql.Tx
+	var err error
+	switch db := b.db.(type) {
+	case *sql.DB:
+		tx, err = db.Begin()
+	case connExecutor:
+		tx, err = db.conn.BeginTx(context.Background(), nil)
+	default:
+		return fn(b)
+	}
+	if err != nil {
+		return err
+	}
+	if err := fn(&Builder{db: tx, driver: b.driver}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
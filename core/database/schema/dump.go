@@ -0,0 +1,183 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dump returns the DDL statements that recreate the current schema, skipping
+// the excluded tables. Tables come first, then indexes and foreign keys.
+func (b *Builder) Dump(exclude ...string) ([]string, error) {
+	skip := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		skip[name] = true
+	}
+	switch {
+	case b.isSQLite():
+		return b.dumpSQLite(skip)
+	case b.driver == "mysql":
+		return b.dumpMySQL(skip)
+	case b.isPostgres():
+		return b.dumpPostgres(skip)
+	default:
+		return nil, fmt.Errorf("unsupported driver: %s", b.driver)
+	}
+}
+
+// Load runs statements produced by Dump on one connection with foreign key
+// checks off, so tables that reference each other load in any order.
+func (b *Builder) Load(statements []string) error {
+	return b.withoutForeignKeys(func(b *Builder) error {
+		for _, statement := range statements {
+			if err := b.exec(statement); err != nil {
+				return fmt.Errorf("load schema dump: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// DropAllTables drops every table except the excluded ones, ignoring foreign key order.
+func (b *Builder) DropAllTables(exclude ...string) error {
+	all, err := b.GetTables()
+	if err != nil {
+		return err
+	}
+	tables := make([]TableInfo, 0, len(all))
+	for _, t := range all {
+		keep := false
+		for _, name := range exclude {
+			keep = keep || t.Name == name
+		}
+		if !keep {
+			tables = append(tables, t)
+		}
+	}
+	if b.isSQLite() || b.driver == "mysql" {
+		return b.withoutForeignKeys(func(b *Builder) error {
+			for _, t := range tables {
+				if err := b.exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", t.Name)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	for _, t := range tables {
+		if err := b.exec(fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE", t.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Builder) dumpSQLite(skip map[string]bool) ([]string, error) {
+	rows, err := b.db.Query(`SELECT tbl_name, sql FROM sqlite_master
+		WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%'
+		ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 ELSE 2 END, rowid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	statements := make([]string, 0)
+	for rows.Next() {
+		var table, sqlStr string
+		if err := rows.Scan(&table, &sqlStr); err != nil {
+			return nil, err
+		}
+		if !skip[table] {
+			statements = append(statements, sqlStr)
+		}
+	}
+	return statements, rows.Err()
+}
+
+func (b *Builder) dumpMySQL(skip map[string]bool) ([]string, error) {
+	tables, err := b.GetTables()
+	if err != nil {
+		return nil, err
+	}
+	statements := []string{"SET FOREIGN_KEY_CHECKS = 0"}
+	for _, t := range tables {
+		if skip[t.Name] {
+			continue
+		}
+		var name, create string
+		if err := b.db.QueryRow(fmt.Sprintf("SHOW CREATE TABLE `%s`", t.Name)).Scan(&name, &create); err != nil {
+			return nil, err
+		}
+		statements = append(statements, create)
+	}
+	return append(statements, "SET FOREIGN_KEY_CHECKS = 1"), nil
+}
+
+func (b *Builder) dumpPostgres(skip map[string]bool) ([]string, error) {
+	tables, err := b.GetTables()
+	if err != nil {
+		return nil, err
+	}
+	creates := make([]string, 0, len(tables))
+	indexes := make([]string, 0)
+	foreigns := make([]string, 0)
+	for _, t := range tables {
+		if skip[t.Name] {
+			continue
+		}
+		columns, err := b.GetColumns(t.Name)
+		if err != nil {
+			return nil, err
+		}
+		defs := make([]string, 0, len(columns)+1)
+		for _, c := range columns {
+			defs = append(defs, postgresColumnDump(c))
+		}
+		tableIndexes, err := b.GetIndexes(t.Name)
+		if err != nil {
+			return nil, err
+		}
+		bp := NewBlueprint(t.Name, b.driver)
+		for _, idx := range tableIndexes {
+			if idx.Type == "primary" {
+				defs = append(defs, fmt.Sprintf("CONSTRAINT %s PRIMARY KEY (%s)", idx.Name, strings.Join(idx.Columns, ", ")))
+				continue
+			}
+			indexes = append(indexes, bp.createIndexSQL(&idx))
+		}
+		creates = append(creates, fmt.Sprintf("CREATE TABLE %s (%s)", t.Name, strings.Join(defs, ", ")))
+
+		keys, err := b.GetForeignKeys(t.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, fk := range keys {
+			def, err := bp.foreignSQL(&fk)
+			if err != nil {
+				return nil, err
+			}
+			foreigns = append(foreigns, fmt.Sprintf("ALTER TABLE %s ADD %s", t.Name, def))
+		}
+	}
+	return append(append(creates, indexes...), foreigns...), nil
+}
+
+func postgresColumnDump(c ColumnInfo) string {
+	typ := c.Type
+	if c.AutoIncrement {
+		switch c.TypeName {
+		case "int8":
+			typ = "bigserial"
+		case "int2":
+			typ = "smallserial"
+		default:
+			typ = "serial"
+		}
+	}
+	def := c.Name + " " + typ
+	if !c.Nullable {
+		def += " NOT NULL"
+	}
+	if c.Default != nil && !c.AutoIncrement {
+		def += " DEFAULT " + *c.Default
+	}
+	return def
+}
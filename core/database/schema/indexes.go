@@ -0,0 +1,207 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Index describes a primary key, unique index or plain index.
+type Index struct {
+	Name    string
+	Type    string // primary|unique|index
+	Columns []string
+}
+
+// ForeignKey describes a foreign key constraint.
+type ForeignKey struct {
+	Name              string
+	Columns           []string
+	ReferencedTable   string
+	ReferencedColumns []string
+	OnDeleteAction    string
+	OnUpdateAction    string
+}
+
+// Index adds a plain index over columns.
+func (b *Blueprint) Index(columns ...string) *Index {
+	return b.addIndex("index", columns)
+}
+
+// Unique adds a unique index over columns.
+func (b *Blueprint) Unique(columns ...string) *Index {
+	return b.addIndex("unique", columns)
+}
+
+// Primary sets a (composite) primary key over columns.
+func (b *Blueprint) Primary(columns ...string) *Index {
+	idx := b.addIndex("primary", columns)
+	idx.Name = b.table + "_pkey"
+	return idx
+}
+
+// Foreign adds a foreign key on columns; chain References and On.
+func (b *Blueprint) Foreign(columns ...string) *ForeignKey {
+	fk := &ForeignKey{
+		Name:    indexName(b.table, "foreign", columns...),
+		Columns: columns,
+	}
+	b.foreigns = append(b.foreigns, fk)
+	return fk
+}
+
+// DropIndex drops a plain index by name.
+func (b *Blueprint) DropIndex(name string) {
+	b.drops = append(b.drops, dropCommand{kind: "index", name: name})
+}
+
+// DropUnique drops a unique index by name.
+func (b *Blueprint) DropUnique(name string) {
+	b.drops = append(b.drops, dropCommand{kind: "unique", name: name})
+}
+
+// DropPrimary drops the primary key (default name <table>_pkey).
+func (b *Blueprint) DropPrimary(name ...string) {
+	n := b.table + "_pkey"
+	if len(name) > 0 && name[0] != "" {
+		n = name[0]
+	}
+	b.drops = append(b.drops, dropCommand{kind: "primary", name: n})
+}
+
+// DropForeign drops a foreign key by name (default <table>_<columns>_foreign).
+func (b *Blueprint) DropForeign(name string) {
+	b.drops = append(b.drops, dropCommand{kind: "foreign", name: name})
+}
+
+func (b *Blueprint) addIndex(kind string, columns []string) *Index {
+	idx := &Index{
+		Name:    indexName(b.table, kind, columns...),
+		Type:    kind,
+		Columns: columns,
+	}
+	b.indexes = append(b.indexes, idx)
+	return idx
+}
+
+// Named overrides the generated index name.
+func (i *Index) Named(name string) *Index {
+	i.Name = name
+	return i
+}
+
+// References sets the referenced columns.
+func (f *ForeignKey) References(columns ...string) *ForeignKey {
+	f.ReferencedColumns = columns
+	return f
+}
+
+// On sets the referenced table.
+func (f *ForeignKey) On(table string) *ForeignKey {
+	f.ReferencedTable = table
+	return f
+}
+
+// Named overrides the generated constraint name.
+func (f *ForeignKey) Named(name string) *ForeignKey {
+	f.Name = name
+	return f
+}
+
+// OnDelete sets the ON DELETE action (cascade, restrict, set null, no action).
+func (f *ForeignKey) OnDelete(action string) *ForeignKey {
+	f.OnDeleteAction = strings.ToUpper(action)
+	return f
+}
+
+// OnUpdate sets the ON UPDATE action.
+func (f *ForeignKey) OnUpdate(action string) *ForeignKey {
+	f.OnUpdateAction = strings.ToUpper(action)
+	return f
+}
+
+// CascadeOnDelete deletes child rows with the parent.
+func (f *ForeignKey) CascadeOnDelete() *ForeignKey {
+	return f.OnDelete("cascade")
+}
+
+// RestrictOnDelete prevents deleting referenced parents.
+func (f *ForeignKey) RestrictOnDelete() *ForeignKey {
+	return f.OnDelete("restrict")
+}
+
+// NullOnDelete sets the column to NULL when the parent is deleted.
+func (f *ForeignKey) NullOnDelete() *ForeignKey {
+	return f.OnDelete("set null")
+}
+
+// CascadeOnUpdate propagates parent key updates.
+func (f *ForeignKey) CascadeOnUpdate() *ForeignKey {
+	return f.OnUpdate("cascade")
+}
+
+func (b *Blueprint) createIndexSQL(idx *Index) string {
+	keyword := "INDEX"
+	if idx.Type == "unique" {
+		keyword = "UNIQUE INDEX"
+	}
+	return fmt.Sprintf("CREATE %s %s ON %s (%s)", keyword, idx.Name, b.table, strings.Join(idx.Columns, ", "))
+}
+
+func (b *Blueprint) foreignSQL(fk *ForeignKey) (string, error) {
+	if fk.ReferencedTable == "" {
+		return "", fmt.Errorf("foreign key %s on %s requires a referenced table", fk.Name, b.table)
+	}
+	refs := fk.ReferencedColumns
+	if len(refs) == 0 {
+		refs = []string{"id"}
+	}
+	def := fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+		fk.Name, strings.Join(fk.Columns, ", "), fk.ReferencedTable, strings.Join(refs, ", "))
+	if fk.OnDeleteAction != "" {
+		def += " ON DELETE " + fk.OnDeleteAction
+	}
+	if fk.OnUpdateAction != "" {
+		def += " ON UPDATE " + fk.OnUpdateAction
+	}
+	return def, nil
+}
+
+func (b *Blueprint) dropSQL(drop dropCommand) string {
+	switch {
+	case b.driver == "mysql":
+		switch drop.kind {
+		case "primary":
+			return fmt.Sprintf("ALTER TABLE %s DROP PRIMARY KEY", b.table)
+		case "foreign":
+			return fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s", b.table, drop.name)
+		default:
+			return fmt.Sprintf("DROP INDEX %s ON %s", drop.name, b.table)
+		}
+	case b.isPostgres():
+		if drop.kind == "primary" || drop.kind == "foreign" {
+			return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", b.table, drop.name)
+		}
+		return fmt.Sprintf("DROP INDEX %s", drop.name)
+	default:
+		return fmt.Sprintf("DROP INDEX %s", drop.name)
+	}
+}
+
+// indexName builds <table>_<columns>_<kind>, lowercased with separators normalised.
+func indexName(table, kind string, columns ...string) string {
+	name := strings.ToLower(table + "_" + strings.Join(columns, "_") + "_" + kind)
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+// guessTable derives a table name from a foreign id column (user_id -> users).
+func guessTable(column string) string {
+	base := strings.TrimSuffix(column, "_id")
+	switch {
+	case strings.HasSuffix(base, "y") && len(base) > 1 && !strings.ContainsAny(base[len(base)-2:len(base)-1], "aeiou"):
+		return base[:len(base)-1] + "ies"
+	case strings.HasSuffix(base, "s"), strings.HasSuffix(base, "x"), strings.HasSuffix(base, "ch"), strings.HasSuffix(base, "sh"):
+		return base + "es"
+	default:
+		return base + "s"
+	}
+}
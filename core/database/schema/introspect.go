@@ -0,0 +1,258 @@
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// TableInfo describes a table in the current database or schema.
+type TableInfo struct {
+	Name   string
+	Schema string
+}
+
+// ColumnInfo describes an existing column. Type is the full lowercase column
+// type (varchar(255)); TypeName is its base name (varchar).
+type ColumnInfo struct {
+	Name          string
+	Type          string
+	TypeName      string
+	Nullable      bool
+	Default       *string
+	AutoIncrement bool
+	Comment       string
+}
+
+// GetTables lists the base tables of the connection's database (mysql),
+// current schema (pgsql) or main database (sqlite).
+func (b *Builder) GetTables() ([]TableInfo, error) {
+	var query string
+	switch {
+	case b.isSQLite():
+		query = `SELECT name, 'main' FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%' ORDER BY name`
+	case b.driver == "mysql":
+		query = `SELECT table_name, table_schema FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' ORDER BY table_name`
+	case b.isPostgres():
+		query = `SELECT table_name, table_schema FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' ORDER BY table_name`
+	default:
+		return nil, fmt.Errorf("unsupported driver: %s", b.driver)
+	}
+	rows, err := b.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tables := make([]TableInfo, 0)
+	for rows.Next() {
+		var t TableInfo
+		if err := rows.Scan(&t.Name, &t.Schema); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+// HasColumn reports whether table has column (case-insensitive).
+func (b *Builder) HasColumn(table, column string) (bool, error) {
+	columns, err := b.GetColumns(table)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range columns {
+		if strings.EqualFold(c.Name, column) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetColumns returns the columns of table in ordinal order. A missing table
+// yields an empty slice.
+func (b *Builder) GetColumns(table string) ([]ColumnInfo, error) {
+	switch {
+	case b.isSQLite():
+		return b.sqliteColumns(table)
+	case b.driver == "mysql":
+		return b.scanColumns(`SELECT column_name, column_type, data_type, is_nullable = 'YES', column_default,
+			extra LIKE '%auto_increment%', column_comment
+			FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ?
+			ORDER BY ordinal_position`, table)
+	case b.isPostgres():
+		return b.scanColumns(`SELECT a.attname, format_type(a.atttypid, a.atttypmod), t.typname, NOT a.attnotnull,
+			pg_get_expr(d.adbin, d.adrelid),
+			a.attidentity <> '' OR COALESCE(pg_get_expr(d.adbin, d.adrelid), '') LIKE 'nextval(%',
+			COALESCE(col_description(c.oid, a.attnum), '')
+			FROM pg_attribute a
+			JOIN pg_class c ON c.oid = a.attrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			JOIN pg_type t ON t.oid = a.atttypid
+			LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+			WHERE c.relname = $1 AND n.nspname = current_schema() AND a.attnum > 0 AND NOT a.attisdropped
+			ORDER BY a.attnum`, table)
+	default:
+		return nil, fmt.Errorf("unsupported driver: %s", b.driver)
+	}
+}
+
+// GetIndexes returns the primary key, unique and plain indexes of table.
+func (b *Builder) GetIndexes(table string) ([]Index, error) {
+	switch {
+	case b.isSQLite():
+		return b.sqliteIndexes(table)
+	case b.driver == "mysql":
+		return b.scanIndexes(`SELECT index_name, index_name = 'PRIMARY', non_unique = 0, column_name
+			FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ?
+			ORDER BY index_name, seq_in_index`, table)
+	case b.isPostgres():
+		return b.scanIndexes(`SELECT i.relname, ix.indisprimary, ix.indisunique, a.attname
+			FROM pg_class t
+			JOIN pg_namespace n ON n.oid = t.relnamespace
+			JOIN pg_index ix ON ix.indrelid = t.oid
+			JOIN pg_class i ON i.oid = ix.indexrelid
+			CROSS JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord)
+			JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+			WHERE t.relname = $1 AND n.nspname = current_schema()
+			ORDER BY i.relname, k.ord`, table)
+	default:
+		return nil, fmt.Errorf("unsupported driver: %s", b.driver)
+	}
+}
+
+// GetForeignKeys returns the foreign keys of table. Actions are uppercase and
+// empty for the NO ACTION default.
+func (b *Builder) GetForeignKeys(table string) ([]ForeignKey, error) {
+	switch {
+	case b.isSQLite():
+		if ok, err := b.HasTable(table); err != nil || !ok {
+			return []ForeignKey{}, err
+		}
+		existing, err := loadSQLiteTable(b.db, table)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]ForeignKey, 0, len(existing.foreigns))
+		for _, fk := range existing.foreigns {
+			out = append(out, *fk)
+		}
+		return out, nil
+	case b.driver == "mysql":
+		return b.scanForeignKeys(`SELECT kcu.constraint_name, kcu.column_name, kcu.referenced_table_name,
+			kcu.referenced_column_name, rc.update_rule, rc.delete_rule
+			FROM information_schema.key_column_usage kcu
+			JOIN information_schema.referential_constraints rc
+				ON rc.constraint_schema = kcu.constraint_schema AND rc.constraint_name = kcu.constraint_name
+			WHERE kcu.table_schema = DATABASE() AND kcu.table_name = ? AND kcu.referenced_table_name IS NOT NULL
+			ORDER BY kcu.constraint_name, kcu.ordinal_position`, table)
+	case b.isPostgres():
+		return b.scanForeignKeys(`SELECT c.conname, a.attname, cf.relname, af.attname,
+			CASE c.confupdtype WHEN 'c' THEN 'CASCADE' WHEN 'r' THEN 'RESTRICT' WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT' ELSE 'NO ACTION' END,
+			CASE c.confdeltype WHEN 'c' THEN 'CASCADE' WHEN 'r' THEN 'RESTRICT' WHEN 'n' THEN 'SET NULL' WHEN 'd' THEN 'SET DEFAULT' ELSE 'NO ACTION' END
+			FROM pg_constraint c
+			JOIN pg_class t ON t.oid = c.conrelid
+			JOIN pg_namespace n ON n.oid = t.relnamespace
+			JOIN pg_class cf ON cf.oid = c.confrelid
+			CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, fattnum, ord)
+			JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+			JOIN pg_attribute af ON af.attrelid = c.confrelid AND af.attnum = k.fattnum
+			WHERE c.contype = 'f' AND t.relname = $1 AND n.nspname = current_schema()
+			ORDER BY c.conname, k.ord`, table)
+	default:
+		return nil, fmt.Errorf("unsupported driver: %s", b.driver)
+	}
+}
+
+func (b *Builder) isSQLite() bool {
+	return b.driver == "sqlite" || b.driver == "sqlite3"
+}
+
+func (b *Builder) isPostgres() bool {
+	return b.driver == "pgsql" || b.driver == "postgres" || b.driver == "postgresql"
+}
+
+func (b *Builder) scanColumns(query, table string) ([]ColumnInfo, error) {
+	rows, err := b.db.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := make([]ColumnInfo, 0)
+	for rows.Next() {
+		var c ColumnInfo
+		var dflt sql.NullString
+		if err := rows.Scan(&c.Name, &c.Type, &c.TypeName, &c.Nullable, &dflt, &c.AutoIncrement, &c.Comment); err != nil {
+			return nil, err
+		}
+		c.Type = strings.ToLower(c.Type)
+		c.TypeName = strings.ToLower(c.TypeName)
+		if dflt.Valid {
+			c.Default = &dflt.String
+		}
+		columns = append(columns, c)
+	}
+	return columns, rows.Err()
+}
+
+func (b *Builder) scanIndexes(query, table string) ([]Index, error) {
+	rows, err := b.db.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	indexes := make([]Index, 0)
+	for rows.Next() {
+		var name, column string
+		var primary, unique bool
+		if err := rows.Scan(&name, &primary, &unique, &column); err != nil {
+			return nil, err
+		}
+		if n := len(indexes); n > 0 && indexes[n-1].Name == name {
+			indexes[n-1].Columns = append(indexes[n-1].Columns, column)
+			continue
+		}
+		kind := "index"
+		switch {
+		case primary:
+			kind = "primary"
+		case unique:
+			kind = "unique"
+		}
+		indexes = append(indexes, Index{Name: name, Type: kind, Columns: []string{column}})
+	}
+	return indexes, rows.Err()
+}
+
+func (b *Builder) scanForeignKeys(query, table string) ([]ForeignKey, error) {
+	rows, err := b.db.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := make([]ForeignKey, 0)
+	for rows.Next() {
+		var name, column, refTable, refColumn, onUpdate, onDelete string
+		if err := rows.Scan(&name, &column, &refTable, &refColumn, &onUpdate, &onDelete); err != nil {
+			return nil, err
+		}
+		if n := len(keys); n > 0 && keys[n-1].Name == name {
+			keys[n-1].Columns = append(keys[n-1].Columns, column)
+			keys[n-1].ReferencedColumns = append(keys[n-1].ReferencedColumns, refColumn)
+			continue
+		}
+		fk := ForeignKey{
+			Name:              name,
+			Columns:           []string{column},
+			ReferencedTable:   refTable,
+			ReferencedColumns: []string{refColumn},
+		}
+		if action := strings.ToUpper(onDelete); action != "NO ACTION" {
+			fk.OnDeleteAction = action
+		}
+		if action := strings.ToUpper(onUpdate); action != "NO ACTION" {
+			fk.OnUpdateAction = action
+		}
+		keys = append(keys, fk)
+	}
+	return keys, rows.Err()
+}
@@ -0,0 +1,161 @@
+package schema_test
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/database/schema"
+)
+
+func TestSQLiteIntrospection(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	b := schema.New(db, "sqlite")
+
+	if err := b.Create("users", func(table *schema.Blueprint) {
+		table.ID()
+		table.String("email").Unique()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Create("posts", func(table *schema.Blueprint) {
+		table.ID()
+		table.ForeignID("user_id").Constrained().CascadeOnDelete()
+		table.String("title", 120)
+		table.Text("body").Nullable()
+		table.Integer("views").Default(0)
+		table.Index("title", "views")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := b.GetTables()
+	if err != nil || len(tables) != 2 || tables[0].Name != "posts" || tables[1].Name != "users" {
+		t.Fatalf("tables=%v err=%v", tables, err)
+	}
+
+	columns, err := b.GetColumns("posts")
+	if err != nil || len(columns) != 5 {
+		t.Fatalf("columns=%v err=%v", columns, err)
+	}
+	if !columns[0].AutoIncrement || columns[0].Nullable || columns[0].TypeName != "integer" {
+		t.Fatalf("id column=%+v", columns[0])
+	}
+	if columns[2].Type != "varchar(120)" || columns[2].TypeName != "varchar" {
+		t.Fatalf("title column=%+v", columns[2])
+	}
+	if !columns[3].Nullable || columns[4].Default == nil || *columns[4].Default != "0" {
+		t.Fatalf("body=%+v views=%+v", columns[3], columns[4])
+	}
+
+	for column, want := range map[string]bool{"title": true, "TITLE": true, "missing": false} {
+		if ok, err := b.HasColumn("posts", column); err != nil || ok != want {
+			t.Fatalf("HasColumn(%s)=%v err=%v", column, ok, err)
+		}
+	}
+	if columns, err := b.GetColumns("nope"); err != nil || len(columns) != 0 {
+		t.Fatalf("missing table columns=%v err=%v", columns, err)
+	}
+
+	indexes, err := b.GetIndexes("posts")
+	if err != nil || len(indexes) != 2 {
+		t.Fatalf("indexes=%v err=%v", indexes, err)
+	}
+	if indexes[0].Type != "primary" || indexes[0].Columns[0] != "id" {
+		t.Fatalf("primary=%+v", indexes[0])
+	}
+	if indexes[1].Name != "posts_title_views_index" || indexes[1].Type != "index" || len(indexes[1].Columns) != 2 {
+		t.Fatalf("index=%+v", indexes[1])
+	}
+	userIndexes, err := b.GetIndexes("users")
+	if err != nil || len(userIndexes) != 2 || userIndexes[1].Type != "unique" || userIndexes[1].Columns[0] != "email" {
+		t.Fatalf("user indexes=%v err=%v", userIndexes, err)
+	}
+
+	keys, err := b.GetForeignKeys("posts")
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("keys=%v err=%v", keys, err)
+	}
+	fk := keys[0]
+	if fk.Name != "posts_user_id_foreign" || fk.ReferencedTable != "users" || fk.ReferencedColumns[0] != "id" ||
+		fk.OnDeleteAction != "CASCADE" || fk.OnUpdateAction != "" {
+		t.Fatalf("foreign key=%+v", fk)
+	}
+}
+
+func TestDropAllTablesRestoresForeignKeySetting(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	b := schema.New(db, "sqlite")
+
+	for _, enabled := range []int{0, 1} {
+		if _, err := db.Exec("PRAGMA foreign_keys = " + map[int]string{0: "OFF", 1: "ON"}[enabled]); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Create("users", func(table *schema.Blueprint) { table.ID() }); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Create("posts", func(table *schema.Blueprint) {
+			table.ID()
+			table.ForeignID("user_id").Constrained().CascadeOnDelete()
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec("INSERT INTO users (id) VALUES (1)"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec("INSERT INTO posts (id, user_id) VALUES (1, 1)"); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.DropAllTables(); err != nil {
+			t.Fatal(err)
+		}
+		if tables, _ := b.GetTables(); len(tables) != 0 {
+			t.Fatalf("tables=%v", tables)
+		}
+		var got int
+		if err := db.QueryRow("PRAGMA foreign_keys").Scan(&got); err != nil || got != enabled {
+			t.Fatalf("foreign_keys=%d want %d err=%v", got, enabled, err)
+		}
+	}
+}
+
+func TestLoadRunsStatementsWithoutForeignKeys(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = schema.New(db, "sqlite").Load([]string{
+		"CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES users (id))",
+		"INSERT INTO posts (id, user_id) VALUES (1, 1)",
+		"CREATE TABLE users (id INTEGER PRIMARY KEY)",
+		"INSERT INTO users (id) VALUES (1)",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var enabled int
+	if err := db.QueryRow("PRAGMA foreign_keys").Scan(&enabled); err != nil || enabled != 1 {
+		t.Fatalf("foreign_keys=%d err=%v", enabled, err)
+	}
+	if err := schema.New(db, "sqlite").Load([]string{"INSERT INTO nope VALUES (1)"}); err == nil || !strings.Contains(err.Error(), "load schema dump") {
+		t.Fatalf("err=%v", err)
+	}
+}
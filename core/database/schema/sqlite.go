@@ -0,0 +1,421 @@
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// sqliteTable is the current shape of a SQLite table, read via PRAGMA so a
+// blueprint can rebuild it.
+type sqliteTable struct {
+	columns       []sqliteColumn
+	foreigns      []*ForeignKey
+	indexes       []*Index
+	uniques       [][]string
+	autoIncrement bool
+}
+
+type sqliteColumn struct {
+	name    string
+	typ     string
+	notNull bool
+	dflt    sql.NullString
+	pk      int
+}
+
+// needsRebuild reports whether the blueprint uses changes SQLite cannot ALTER in place.
+func (b *Blueprint) needsRebuild() bool {
+	if len(b.foreigns) > 0 {
+		return true
+	}
+	for _, col := range b.columns {
+		if col.Change == "modify" {
+			return true
+		}
+	}
+	for _, idx := range b.indexes {
+		if idx.Type == "primary" {
+			return true
+		}
+	}
+	for _, drop := range b.drops {
+		if drop.kind == "primary" || drop.kind == "foreign" {
+			return true
+		}
+	}
+	return false
+}
+
+// rebuildSQLite compiles the create-copy-drop-rename sequence SQLite recommends
+// for schema changes ALTER TABLE does not support.
+func (b *Blueprint) rebuildSQLite() ([]string, error) {
+	ex := b.existing
+	renamed := make(map[string]string, len(b.renames))
+	for _, r := range b.renames {
+		renamed[r.from] = r.to
+	}
+	newName := func(column string) string {
+		if to, ok := renamed[column]; ok {
+			return to
+		}
+		return column
+	}
+	dropped := make(map[string]bool)
+	modified := make(map[string]*Column)
+	for _, col := range b.columns {
+		switch col.Change {
+		case "drop":
+			dropped[col.Name] = true
+		case "modify":
+			modified[col.Name] = col
+		}
+	}
+	droppedNames := make(map[string]bool, len(b.drops))
+	dropPrimary := false
+	for _, drop := range b.drops {
+		droppedNames[drop.name] = true
+		if drop.kind == "primary" {
+			dropPrimary = true
+		}
+	}
+	mapColumns := func(columns []string) ([]string, bool) {
+		out := make([]string, 0, len(columns))
+		for _, c := range columns {
+			if dropped[c] {
+				return nil, false
+			}
+			out = append(out, newName(c))
+		}
+		return out, true
+	}
+
+	var primary []string
+	if !dropPrimary {
+		pks := make([]string, 0)
+		for pos := 1; ; pos++ {
+			found := false
+			for _, c := range ex.columns {
+				if c.pk == pos {
+					pks = append(pks, c.name)
+					found = true
+				}
+			}
+			if !found {
+				break
+			}
+		}
+		primary, _ = mapColumns(pks)
+	}
+	for _, idx := range b.indexes {
+		if idx.Type == "primary" {
+			primary = idx.Columns
+		}
+	}
+
+	defs := make([]string, 0, len(ex.columns)+len(b.columns))
+	copyTo := make([]string, 0, len(ex.columns))
+	copyFrom := make([]string, 0, len(ex.columns))
+	inlinePrimary := false
+	for _, c := range ex.columns {
+		if dropped[c.name] {
+			continue
+		}
+		name := newName(c.name)
+		var def string
+		if mod, ok := modified[name]; ok {
+			cp := *mod
+			cp.Name = name
+			d, err := b.columnSQL(&cp)
+			if err != nil {
+				return nil, err
+			}
+			def = d
+		} else {
+			def = strings.TrimSpace(name + " " + c.typ)
+			if c.notNull {
+				def += " NOT NULL"
+			}
+			if c.dflt.Valid {
+				def += " DEFAULT " + c.dflt.String
+			}
+		}
+		if len(primary) == 1 && primary[0] == name && strings.EqualFold(c.typ, "INTEGER") {
+			// Keep the rowid alias (and AUTOINCREMENT) for single integer keys.
+			def += " PRIMARY KEY"
+			if ex.autoIncrement {
+				def += " AUTOINCREMENT"
+			}
+			inlinePrimary = true
+		}
+		defs = append(defs, def)
+		copyTo = append(copyTo, name)
+		copyFrom = append(copyFrom, c.name)
+	}
+	for _, col := range b.columns {
+		if col.Change != "add" {
+			continue
+		}
+		def, err := b.columnSQL(col)
+		if err != nil {
+			return nil, err
+		}
+		if col.Primary {
+			inlinePrimary = true
+		}
+		defs = append(defs, def)
+	}
+	if len(primary) > 0 && !inlinePrimary {
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primary, ", ")))
+	}
+	for _, unique := range ex.uniques {
+		if droppedNames[indexName(b.table, "unique", unique...)] {
+			continue
+		}
+		if cols, ok := mapColumns(unique); ok {
+			defs = append(defs, fmt.Sprintf("UNIQUE (%s)", strings.Join(cols, ", ")))
+		}
+	}
+	foreigns := make([]*ForeignKey, 0, len(ex.foreigns)+len(b.foreigns))
+	for _, fk := range ex.foreigns {
+		if droppedNames[fk.Name] {
+			continue
+		}
+		cols, ok := mapColumns(fk.Columns)
+		if !ok {
+			continue
+		}
+		cp := *fk
+		cp.Columns = cols
+		foreigns = append(foreigns, &cp)
+	}
+	foreigns = append(foreigns, b.foreigns...)
+	for _, fk := range foreigns {
+		def, err := b.foreignSQL(fk)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+
+	temp := "__temp__" + b.table
+	statements := []string{
+		fmt.Sprintf("CREATE TABLE %s (%s)", temp, strings.Join(defs, ", ")),
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", temp, strings.Join(copyTo, ", "), strings.Join(copyFrom, ", "), b.table),
+		fmt.Sprintf("DROP TABLE %s", b.table),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", temp, b.table),
+	}
+	for _, idx := range ex.indexes {
+		if droppedNames[idx.Name] {
+			continue
+		}
+		cols, ok := mapColumns(idx.Columns)
+		if !ok {
+			continue
+		}
+		statements = append(statements, b.createIndexSQL(&Index{Name: idx.Name, Type: idx.Type, Columns: cols}))
+	}
+	for _, idx := range b.indexes {
+		if idx.Type != "primary" {
+			statements = append(statements, b.createIndexSQL(idx))
+		}
+	}
+	return statements, nil
+}
+
+// loadSQLiteTable reads columns, keys and indexes of table through PRAGMA.
+func loadSQLiteTable(db Executor, table string) (*sqliteTable, error) {
+	out := &sqliteTable{}
+
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var cid int
+		var c sqliteColumn
+		var notNull int
+		if err := rows.Scan(&cid, &c.name, &c.typ, &notNull, &c.dflt, &c.pk); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		c.notNull = notNull == 1
+		out.columns = append(out.columns, c)
+	}
+	rows.Close()
+	if len(out.columns) == 0 {
+		return nil, fmt.Errorf("table [%s] does not exist", table)
+	}
+
+	rows, err = db.Query(fmt.Sprintf("PRAGMA foreign_key_list(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*ForeignKey)
+	order := make([]int, 0)
+	for rows.Next() {
+		var id, seq int
+		var refTable, from, onUpdate, onDelete, match string
+		var to sql.NullString
+		if err := rows.Scan(&id, &seq, &refTable, &from, &to, &onUpdate, &onDelete, &match); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		fk, ok := byID[id]
+		if !ok {
+			fk = &ForeignKey{ReferencedTable: refTable}
+			if onDelete != "NO ACTION" {
+				fk.OnDeleteAction = onDelete
+			}
+			if onUpdate != "NO ACTION" {
+				fk.OnUpdateAction = onUpdate
+			}
+			byID[id] = fk
+			order = append(order, id)
+		}
+		fk.Columns = append(fk.Columns, from)
+		if to.Valid {
+			fk.ReferencedColumns = append(fk.ReferencedColumns, to.String)
+		}
+	}
+	rows.Close()
+	for i := len(order) - 1; i >= 0; i-- {
+		fk := byID[order[i]]
+		fk.Name = indexName(table, "foreign", fk.Columns...)
+		out.foreigns = append(out.foreigns, fk)
+	}
+
+	rows, err = db.Query(fmt.Sprintf("PRAGMA index_list(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	type indexEntry struct {
+		name   string
+		unique bool
+		origin string
+	}
+	entries := make([]indexEntry, 0)
+	for rows.Next() {
+		var seq, unique, partial int
+		var e indexEntry
+		if err := rows.Scan(&seq, &e.name, &unique, &e.origin, &partial); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		e.unique = unique == 1
+		entries = append(entries, e)
+	}
+	rows.Close()
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.origin == "pk" {
+			continue
+		}
+		columns, err := sqliteIndexColumns(db, e.name)
+		if err != nil {
+			return nil, err
+		}
+		if e.origin == "u" {
+			out.uniques = append(out.uniques, columns)
+			continue
+		}
+		kind := "index"
+		if e.unique {
+			kind = "unique"
+		}
+		out.indexes = append(out.indexes, &Index{Name: e.name, Type: kind, Columns: columns})
+	}
+
+	var createSQL sql.NullString
+	if err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type='table' AND name=?`, table).Scan(&createSQL); err != nil {
+		return nil, err
+	}
+	out.autoIncrement = strings.Contains(strings.ToUpper(createSQL.String), "AUTOINCREMENT")
+	return out, nil
+}
+
+func sqliteIndexColumns(db Executor, index string) ([]string, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA index_info(%s)", index))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := make([]string, 0)
+	for rows.Next() {
+		var seqno, cid int
+		var name sql.NullString
+		if err := rows.Scan(&seqno, &cid, &name); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name.String)
+	}
+	return columns, rows.Err()
+}
+
+func (b *Builder) sqliteColumns(table string) ([]ColumnInfo, error) {
+	if ok, err := b.HasTable(table); err != nil || !ok {
+		return []ColumnInfo{}, err
+	}
+	existing, err := loadSQLiteTable(b.db, table)
+	if err != nil {
+		return nil, err
+	}
+	primaries := 0
+	for _, c := range existing.columns {
+		if c.pk > 0 {
+			primaries++
+		}
+	}
+	columns := make([]ColumnInfo, 0, len(existing.columns))
+	for _, c := range existing.columns {
+		typ := strings.ToLower(c.typ)
+		info := ColumnInfo{
+			Name:     c.name,
+			Type:     typ,
+			TypeName: strings.TrimSpace(strings.SplitN(typ, "(", 2)[0]),
+			Nullable: !c.notNull && c.pk == 0,
+			// A lone INTEGER PRIMARY KEY aliases the rowid and is assigned automatically.
+			AutoIncrement: c.pk > 0 && primaries == 1 && typ == "integer",
+		}
+		if c.dflt.Valid {
+			dflt := c.dflt.String
+			info.Default = &dflt
+		}
+		columns = append(columns, info)
+	}
+	return columns, nil
+}
+
+func (b *Builder) sqliteIndexes(table string) ([]Index, error) {
+	if ok, err := b.HasTable(table); err != nil || !ok {
+		return []Index{}, err
+	}
+	existing, err := loadSQLiteTable(b.db, table)
+	if err != nil {
+		return nil, err
+	}
+	indexes := make([]Index, 0, len(existing.indexes)+len(existing.uniques)+1)
+	primary := Index{Name: "primary", Type: "primary"}
+	for pos := 1; ; pos++ {
+		found := false
+		for _, c := range existing.columns {
+			if c.pk == pos {
+				primary.Columns = append(primary.Columns, c.name)
+				found = true
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	if len(primary.Columns) > 0 {
+		indexes = append(indexes, primary)
+	}
+	for _, unique := range existing.uniques {
+		indexes = append(indexes, Index{Name: indexName(table, "unique", unique...), Type: "unique", Columns: unique})
+	}
+	for _, idx := range existing.indexes {
+		indexes = append(indexes, *idx)
+	}
+	return indexes, nil
+}
@@ -16,5 +16,13 @@ func (m *Manager) Table(table string, connection ...string) (*query.Builder, err
 	if err != nil {
 		return nil, err
 	}
-	return query.New(db, driver, table), nil
+	builder := query.New(db, driver, table).Listen(m.QueryListener(connection...))
+	if cfg := m.config.Connections[m.connectionName(connection...)]; len(cfg.Read) > 0 {
+		reader, err := m.ReadConnection(connection...)
+		if err != nil {
+			return nil, err
+		}
+		builder.UseReadConnection(reader, cfg.Sticky)
+	}
+	return builder, nil
 }
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -52,6 +53,16 @@ func (r *Request) Raw() *stdhttp.Request {
 	return r.raw
 }
 
+// Context returns the request context; it is cancelled when the client disconnects.
+func (r *Request) Context() context.Context {
+	return r.raw.Context()
+}
+
+// SetContext replaces the request context.
+func (r *Request) SetContext(ctx context.Context) {
+	r.raw = r.raw.WithContext(ctx)
+}
+
 // Method returns the HTTP method.
 func (r *Request) Method() string {
 	return r.raw.Method
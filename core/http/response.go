@@ -207,8 +207,22 @@ func HTML(body string) *Response {
 	}
 }
 
-// JSON creates a JSON response.
+// jsonTransformer rewrites JSON payloads before encoding; see SetJSONTransformer.
+var jsonTransformer func(any) any
+
+// SetJSONTransformer installs a function that rewrites every JSON payload
+// before it is encoded. The application sets it to orm.Serialize at boot so
+// Hidden, Visible and Appends apply to models; nil encodes payloads as is.
+func SetJSONTransformer(fn func(any) any) {
+	jsonTransformer = fn
+}
+
+// JSON creates a JSON response, passing data through the JSON transformer
+// when one is set.
 func JSON(data any) *Response {
+	if jsonTransformer != nil {
+		data = jsonTransformer(data)
+	}
 	payload, err := json.Marshal(data)
 	if err != nil {
 		return &Response{
@@ -213,3 +213,21 @@ func TestResponseCompletionHelpers(t *testing.T) {
 		t.Fatalf("cookie options=%+v", c)
 	}
 }
+
+func TestJSONTransformer(t *testing.T) {
+	http.SetJSONTransformer(func(value any) any {
+		if s, ok := value.(string); ok {
+			return strings.ToUpper(s)
+		}
+		return value
+	})
+	defer http.SetJSONTransformer(nil)
+
+	if body := string(http.JSON("ada").Content()); body != `"ADA"` {
+		t.Fatalf("body=%s", body)
+	}
+	http.SetJSONTransformer(nil)
+	if body := string(http.JSON("ada").Content()); body != `"ada"` {
+		t.Fatalf("body=%s", body)
+	}
+}
@@ -1,9 +1,13 @@
 package inspector
 
 import (
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/zatrano/framework/core/database"
 	"github.com/zatrano/framework/core/http"
 	"github.com/zatrano/framework/core/routing"
 )
@@ -21,14 +25,29 @@ type Entry struct {
 	RequestID  string            `json:"request_id,omitempty"`
 	UserAgent  string            `json:"user_agent,omitempty"`
 	Query      map[string]string `json:"query,omitempty"`
+	Queries    int               `json:"queries,omitempty"`
+	NPlusOne   []QueryShape      `json:"n_plus_one,omitempty"`
 }
 
+// QueryShape is a statement that ran repeatedly during one request, with
+// bindings left out: the signature of an N+1 loop.
+type QueryShape struct {
+	SQL        string  `json:"sql"`
+	Count      int     `json:"count"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// DefaultNPlusOneThreshold is how many times one query shape must run in a
+// request before it is reported.
+const DefaultNPlusOneThreshold = 3
+
 // Manager records recent HTTP requests for debugging.
 type Manager struct {
 	mu      sync.Mutex
 	entries []Entry
 	limit   int
 	enabled bool
+	repeats int
 }
 
 // New creates an inspector with a ring buffer.
@@ -36,7 +55,15 @@ func New(limit int) *Manager {
 	if limit <= 0 {
 		limit = 200
 	}
-	return &Manager{entries: make([]Entry, 0, limit), limit: limit, enabled: true}
+	return &Manager{entries: make([]Entry, 0, limit), limit: limit, enabled: true, repeats: DefaultNPlusOneThreshold}
+}
+
+// SetNPlusOneThreshold sets how many runs of one query shape per request are
+// reported as N+1; zero disables detection.
+func (m *Manager) SetNPlusOneThreshold(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.repeats = n
 }
 
 // Enable toggles recording.
@@ -91,6 +118,7 @@ func (m *Manager) Middleware() routing.MiddlewareFunc {
 	return func(next routing.HandlerFunc) routing.HandlerFunc {
 		return func(req *http.Request) *http.Response {
 			start := time.Now()
+			req.SetContext(database.WithQueryLog(req.Context()))
 			resp := next(req)
 			status := 200
 			if resp != nil {
@@ -123,8 +151,60 @@ func (m *Manager) Middleware() routing.MiddlewareFunc {
 					}
 				}
 			}
+			queries := database.QueryLogFromContext(req.Context())
+			entry.Queries = len(queries)
+			m.mu.Lock()
+			repeats := m.repeats
+			m.mu.Unlock()
+			entry.NPlusOne = RepeatedQueries(queries, repeats)
 			m.Record(entry)
 			return resp
 		}
 	}
 }
+
+var (
+	placeholderLists = regexp.MustCompile(`\(\s*\?(\s*,\s*\?)*\s*\)`)
+	numbered         = regexp.MustCompile(`\$\d+`)
+)
+
+// RepeatedQueries groups queries by shape and returns the shapes that ran at
+// least threshold times, most frequent first. Shapes ignore bindings,
+// whitespace and the length of IN (?, ?) lists. Only queries run with the
+// request context are seen, e.g. through orm.Querier.WithContext or the
+// Context variants of the relation helpers (orm.HasManyContext).
+func RepeatedQueries(queries []database.QueryExecuted, threshold int) []QueryShape {
+	if threshold <= 0 {
+		return nil
+	}
+	byShape := map[string]*QueryShape{}
+	order := make([]string, 0)
+	for _, q := range queries {
+		shape := queryShape(q.SQL)
+		s, ok := byShape[shape]
+		if !ok {
+			s = &QueryShape{SQL: shape}
+			byShape[shape] = s
+			order = append(order, shape)
+		}
+		s.Count++
+		s.DurationMs += float64(q.Duration.Microseconds()) / 1000.0
+	}
+	out := make([]QueryShape, 0)
+	for _, shape := range order {
+		if s := byShape[shape]; s.Count >= threshold {
+			out = append(out, *s)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Count > out[j].Count })
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func queryShape(sqlStr string) string {
+	shape := strings.Join(strings.Fields(sqlStr), " ")
+	shape = numbered.ReplaceAllString(shape, "?")
+	return placeholderLists.ReplaceAllString(shape, "(?)")
+}
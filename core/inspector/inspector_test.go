@@ -2,10 +2,14 @@ package inspector_test
 
 import (
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/zatrano/framework/core/database"
 	"github.com/zatrano/framework/core/http"
 	"github.com/zatrano/framework/core/inspector"
+	"github.com/zatrano/framework/core/orm"
 )
 
 func TestInspectorRecordsRequests(t *testing.T) {
@@ -22,3 +26,115 @@ func TestInspectorRecordsRequests(t *testing.T) {
 		t.Fatalf("unexpected %#v", entries[0])
 	}
 }
+
+func TestInspectorReportsRepeatedQueries(t *testing.T) {
+	dir := t.TempDir()
+	db := database.NewManager(database.Config{
+		Default: "sqlite",
+		Connections: map[string]database.ConnectionConfig{
+			"sqlite": {Driver: "sqlite", Database: filepath.Join(dir, "inspector.sqlite")},
+		},
+	}, dir)
+	defer db.Close()
+	conn, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Exec(`CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := inspector.New(10)
+	handler := mgr.Middleware()(func(req *http.Request) *http.Response {
+		for userID := 1; userID <= 4; userID++ {
+			posts, _ := db.Table("posts")
+			_, _ = posts.WithContext(req.Context()).Where("user_id", userID).Get()
+		}
+		byID, _ := db.Table("posts")
+		_, _ = byID.WithContext(req.Context()).WhereIn("id", []any{1, 2}).Get()
+		return http.JSON(map[string]any{"ok": true})
+	})
+	_ = handler(http.NewRequest(httptest.NewRequest("GET", "/posts", nil)))
+
+	entry := mgr.Recent(1)[0]
+	if entry.Queries != 5 || len(entry.NPlusOne) != 1 {
+		t.Fatalf("entry=%+v", entry)
+	}
+	if shape := entry.NPlusOne[0]; shape.Count != 4 || !strings.Contains(shape.SQL, "WHERE user_id = ?") {
+		t.Fatalf("shape=%+v", shape)
+	}
+
+	shapes := inspector.RepeatedQueries([]database.QueryExecuted{
+		{SQL: "SELECT * FROM tags WHERE id IN (?, ?)"},
+		{SQL: "SELECT *  FROM tags WHERE id IN (?)"},
+	}, 2)
+	if len(shapes) != 1 || shapes[0].SQL != "SELECT * FROM tags WHERE id IN (?)" {
+		t.Fatalf("shapes=%+v", shapes)
+	}
+	if inspector.RepeatedQueries(nil, 0) != nil {
+		t.Fatal("threshold 0 should disable detection")
+	}
+}
+
+type inspectedAuthor struct {
+	orm.Model
+}
+
+func (inspectedAuthor) TableName() string { return "authors" }
+
+type inspectedBook struct {
+	orm.Model
+	AuthorID int64 `db:"author_id"`
+}
+
+func (inspectedBook) TableName() string { return "books" }
+
+func TestInspectorSeesLazyRelationLoops(t *testing.T) {
+	dir := t.TempDir()
+	db := database.NewManager(database.Config{
+		Default: "sqlite",
+		Connections: map[string]database.ConnectionConfig{
+			"sqlite": {Driver: "sqlite", Database: filepath.Join(dir, "relations.sqlite")},
+		},
+	}, dir)
+	defer db.Close()
+	conn, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE authors (id INTEGER PRIMARY KEY, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE books (id INTEGER PRIMARY KEY, author_id INTEGER, created_at DATETIME, updated_at DATETIME)`,
+		`INSERT INTO authors (id) VALUES (1), (2), (3)`,
+	} {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orm.Configure(conn, "sqlite")
+	orm.SetQueryListener(db.QueryListener())
+	defer orm.SetQueryListener(nil)
+
+	mgr := inspector.New(10)
+	handler := mgr.Middleware()(func(req *http.Request) *http.Response {
+		authors, err := orm.Query[inspectedAuthor]().WithContext(req.Context()).Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range authors {
+			if _, err := orm.HasManyContext[inspectedAuthor, inspectedBook](req.Context(), &authors[i], "author_id"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return http.JSON(map[string]any{"ok": true})
+	})
+	_ = handler(http.NewRequest(httptest.NewRequest("GET", "/authors", nil)))
+
+	entry := mgr.Recent(1)[0]
+	if entry.Queries != 4 || len(entry.NPlusOne) != 1 || entry.NPlusOne[0].Count != 3 {
+		t.Fatalf("entry=%+v", entry)
+	}
+	if !strings.Contains(entry.NPlusOne[0].SQL, "FROM books") {
+		t.Fatalf("shape=%+v", entry.NPlusOne[0])
+	}
+}
@@ -46,6 +46,18 @@ func (m *Manager) Get(name string, ttl ...time.Duration) *Lock {
 	}
 }
 
+// Restore returns a handle for a lock owned by owner, so a lock acquired by
+// one handle can be released through another (for example by the worker
+// finishing a job that was locked when it was pushed).
+func (m *Manager) Restore(name, owner string, ttl ...time.Duration) *Lock {
+	l := m.Get(name, ttl...)
+	l.owner = owner
+	return l
+}
+
+// Owner returns the token identifying the handle's owner.
+func (l *Lock) Owner() string { return l.owner }
+
 // Acquire tries to obtain the lock.
 func (l *Lock) Acquire() bool {
 	l.manager.mu.Lock()
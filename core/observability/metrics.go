@@ -13,12 +13,15 @@ import (
 	"github.com/zatrano/framework/core/timing"
 )
 
-// Metrics collects simple request timing statistics.
+// Metrics collects simple request and query timing statistics.
 type Metrics struct {
 	mu            sync.RWMutex
 	requests      atomic.Int64
 	errors        atomic.Int64
 	totalDuration atomic.Int64 // nanoseconds
+	queries       atomic.Int64
+	slowQueries   atomic.Int64
+	queryDuration atomic.Int64 // nanoseconds
 	slowestPath   string
 	slowestDur    time.Duration
 	byStatus      map[int]int64
@@ -47,6 +50,15 @@ func (m *Metrics) Observe(method, path string, status int, duration time.Duratio
 	}
 }
 
+// ObserveQuery records a database query sample.
+func (m *Metrics) ObserveQuery(duration time.Duration, slow bool) {
+	m.queries.Add(1)
+	m.queryDuration.Add(duration.Nanoseconds())
+	if slow {
+		m.slowQueries.Add(1)
+	}
+}
+
 // Snapshot returns a copy of current metrics.
 func (m *Metrics) Snapshot() map[string]any {
 	requests := m.requests.Load()
@@ -55,6 +67,11 @@ func (m *Metrics) Snapshot() map[string]any {
 	if requests > 0 {
 		avg = time.Duration(total / requests)
 	}
+	queries := m.queries.Load()
+	queryAvg := time.Duration(0)
+	if queries > 0 {
+		queryAvg = time.Duration(m.queryDuration.Load() / queries)
+	}
 
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -71,6 +88,9 @@ func (m *Metrics) Snapshot() map[string]any {
 		"slowest":       m.slowestPath,
 		"slowest_ms":    float64(m.slowestDur.Microseconds()) / 1000.0,
 		"status_counts": statuses,
+		"queries":       queries,
+		"slow_queries":  m.slowQueries.Load(),
+		"query_avg_ms":  float64(queryAvg.Microseconds()) / 1000.0,
 	}
 }
 
@@ -79,6 +99,9 @@ func (m *Metrics) Reset() {
 	m.requests.Store(0)
 	m.errors.Store(0)
 	m.totalDuration.Store(0)
+	m.queries.Store(0)
+	m.slowQueries.Store(0)
+	m.queryDuration.Store(0)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.byStatus = make(map[int]int64)
@@ -102,6 +125,12 @@ func (m *Metrics) Prometheus() string {
 	b.WriteString("# HELP zatrano_http_slowest_ms Slowest observed request duration in milliseconds.\n")
 	b.WriteString("# TYPE zatrano_http_slowest_ms gauge\n")
 	b.WriteString(fmt.Sprintf("zatrano_http_slowest_ms %.3f\n", snap["slowest_ms"]))
+	b.WriteString("# HELP zatrano_db_queries_total Total database queries.\n")
+	b.WriteString("# TYPE zatrano_db_queries_total counter\n")
+	b.WriteString(fmt.Sprintf("zatrano_db_queries_total %d\n", snap["queries"]))
+	b.WriteString("# HELP zatrano_db_slow_queries_total Database queries over the slow threshold.\n")
+	b.WriteString("# TYPE zatrano_db_slow_queries_total counter\n")
+	b.WriteString(fmt.Sprintf("zatrano_db_slow_queries_total %d\n", snap["slow_queries"]))
 	b.WriteString("# HELP zatrano_http_responses_total HTTP responses by status code.\n")
 	b.WriteString("# TYPE zatrano_http_responses_total counter\n")
 	if statuses, ok := snap["status_counts"].(map[string]int64); ok {
@@ -0,0 +1,90 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// WithCount adds a correlated COUNT(*) of a registered relation to the select
+// list as <relation>_count, so it hydrates into a field such as
+//
+//	OrdersCount int64 `db:"orders_count,readonly"`
+//
+// Constraints are func(*orm.Querier[Related]) callbacks. "Orders as paid" picks
+// the column alias. This is unrelated to the package-level WithCount, which
+// counts for an already loaded slice.
+func (q *Querier[T]) WithCount(relation string, constrain ...any) *Querier[T] {
+	return q.withAggregate(relation, "count", "*", constrain)
+}
+
+// WithSum adds SUM(column) of a relation as <relation>_sum_<column>.
+func (q *Querier[T]) WithSum(relation, column string, constrain ...any) *Querier[T] {
+	return q.withAggregate(relation, "sum", column, constrain)
+}
+
+// WithAvg adds AVG(column) of a relation as <relation>_avg_<column>.
+func (q *Querier[T]) WithAvg(relation, column string, constrain ...any) *Querier[T] {
+	return q.withAggregate(relation, "avg", column, constrain)
+}
+
+// WithMin adds MIN(column) of a relation as <relation>_min_<column>.
+func (q *Querier[T]) WithMin(relation, column string, constrain ...any) *Querier[T] {
+	return q.withAggregate(relation, "min", column, constrain)
+}
+
+// WithMax adds MAX(column) of a relation as <relation>_max_<column>.
+func (q *Querier[T]) WithMax(relation, column string, constrain ...any) *Querier[T] {
+	return q.withAggregate(relation, "max", column, constrain)
+}
+
+// WithExists adds whether any related row exists as <relation>_exists.
+func (q *Querier[T]) WithExists(relation string, constrain ...any) *Querier[T] {
+	return q.withAggregate(relation, "exists", "", constrain)
+}
+
+func (q *Querier[T]) withAggregate(relation, function, column string, constraints []any) *Querier[T] {
+	name, alias, _ := strings.Cut(relation, " as ")
+	name, alias = strings.TrimSpace(name), strings.TrimSpace(alias)
+	field, rel, ok := relationsOf[T]().lookup(name)
+	if !ok {
+		return q.fail(fmt.Errorf("relation [%s] is not defined on model [%s]", name, q.table))
+	}
+	sub, related, err := rel.subquery(q.table, field, constraints)
+	if err != nil {
+		return q.fail(err)
+	}
+	if alias == "" {
+		alias = aggregateAlias(field, function, column)
+	}
+	if len(q.builder.Columns()) == 0 {
+		q.builder.Select(q.table + ".*")
+	}
+
+	switch function {
+	case "exists":
+		q.builder.SelectExists(sub.Select("1"), alias)
+	case "count":
+		q.builder.SelectSub(sub.Select("COUNT(*)"), alias)
+	default:
+		if !strings.ContainsAny(column, ".( ") {
+			column = related + "." + column
+		}
+		q.builder.SelectSub(sub.Select(strings.ToUpper(function)+"("+column+")"), alias)
+	}
+	return q
+}
+
+// aggregateAlias names an aggregate column: orders_count, orders_sum_total.
+func aggregateAlias(field, function, column string) string {
+	parts := []string{toSnake(field), function}
+	if column != "" && column != "*" {
+		parts = append(parts, strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return '_'
+		}, column))
+	}
+	return strings.Join(parts, "_")
+}
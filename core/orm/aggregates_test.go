@@ -0,0 +1,142 @@
+package orm_test
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/database/query"
+	"github.com/zatrano/framework/core/orm"
+)
+
+type aggCustomer struct {
+	orm.Model
+	Name          string  `db:"name"`
+	ReferrerID    *int64  `db:"referrer_id"`
+	OrdersCount   int64   `db:"orders_count,readonly"`
+	OrdersSum     float64 `db:"orders_sum_total,readonly"`
+	OrdersAvg     float64 `db:"orders_avg_total,readonly"`
+	OrdersMax     float64 `db:"orders_max_total,readonly"`
+	PaidCount     int64   `db:"paid_count,readonly"`
+	HasOrders     bool    `db:"orders_exists,readonly"`
+	TagsCount     int64   `db:"tags_count,readonly"`
+	ReferralCount int64   `db:"referrals_count,readonly"`
+}
+
+func (aggCustomer) TableName() string { return "agg_customers" }
+
+func (aggCustomer) Relations() orm.Relations {
+	return orm.Relations{
+		"Orders":    orm.HasManyRelation[aggCustomer, aggOrder]("customer_id"),
+		"Tags":      orm.BelongsToManyRelation[aggCustomer, aggTag]("agg_customer_tag", "customer_id", "tag_id"),
+		"Referrals": orm.HasManyRelation[aggCustomer, aggCustomer]("referrer_id"),
+	}
+}
+
+type aggOrder struct {
+	orm.Model
+	orm.SoftDeletes
+	CustomerID int64   `db:"customer_id"`
+	Total      float64 `db:"total"`
+	Status     string  `db:"status"`
+}
+
+func (aggOrder) TableName() string { return "agg_orders" }
+
+type aggTag struct {
+	orm.Model
+	Name string `db:"name"`
+}
+
+func (aggTag) TableName() string { return "agg_tags" }
+
+func setupAggregateDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sqlStr := range []string{
+		`CREATE TABLE agg_customers (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, referrer_id INTEGER, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE agg_orders (id INTEGER PRIMARY KEY AUTOINCREMENT, customer_id INTEGER, total REAL, status TEXT, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME)`,
+		`CREATE TABLE agg_tags (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE agg_customer_tag (customer_id INTEGER, tag_id INTEGER)`,
+		`INSERT INTO agg_customers (id, name, referrer_id) VALUES (1, 'ada', NULL), (2, 'bob', 1), (3, 'cy', 1)`,
+		`INSERT INTO agg_orders (customer_id, total, status, deleted_at) VALUES (1, 10, 'paid', NULL), (1, 30, 'open', NULL), (1, 99, 'paid', '2026-01-01'), (2, 5, 'paid', NULL)`,
+		`INSERT INTO agg_tags (id, name) VALUES (1, 'vip'), (2, 'new')`,
+		`INSERT INTO agg_customer_tag (customer_id, tag_id) VALUES (1, 1), (1, 2), (3, 2)`,
+	} {
+		if _, err := db.Exec(sqlStr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orm.Configure(db, "sqlite")
+	return db
+}
+
+func TestRelationAggregatesHydrateFields(t *testing.T) {
+	db := setupAggregateDB(t)
+	defer db.Close()
+
+	var selects int
+	orm.SetQueryListener(func(e query.Executed) {
+		if strings.HasPrefix(e.SQL, "SELECT") {
+			selects++
+		}
+	})
+	defer orm.SetQueryListener(nil)
+
+	customers, err := orm.Query[aggCustomer]().
+		WithCount("Orders").
+		WithSum("Orders", "total").
+		WithAvg("orders", "total").
+		WithMax("Orders", "total").
+		WithCount("Orders as paid_count", func(q *orm.Querier[aggOrder]) { q.Where("status", "paid") }).
+		WithExists("Orders").
+		WithCount("Tags").
+		WithCount("Referrals").
+		OrderBy("id").
+		Get()
+	if err != nil || len(customers) != 3 {
+		t.Fatalf("customers=%+v err=%v", customers, err)
+	}
+	if selects != 1 {
+		t.Fatalf("selects=%d", selects)
+	}
+
+	ada, bob, cy := customers[0], customers[1], customers[2]
+	if ada.OrdersCount != 2 || ada.OrdersSum != 40 || ada.OrdersAvg != 20 || ada.OrdersMax != 30 || ada.PaidCount != 1 || !ada.HasOrders {
+		t.Fatalf("ada=%+v", ada)
+	}
+	if bob.OrdersCount != 1 || bob.OrdersSum != 5 || !bob.HasOrders || bob.TagsCount != 0 {
+		t.Fatalf("bob=%+v", bob)
+	}
+	if cy.OrdersCount != 0 || cy.OrdersSum != 0 || cy.HasOrders || cy.TagsCount != 1 {
+		t.Fatalf("cy=%+v", cy)
+	}
+	if ada.TagsCount != 2 || ada.ReferralCount != 2 || bob.ReferralCount != 0 {
+		t.Fatalf("tags/referrals ada=%+v bob=%+v", ada, bob)
+	}
+
+	ada.Name = "ada l."
+	if err := orm.Save(&ada); err != nil {
+		t.Fatalf("save with readonly aggregates: %v", err)
+	}
+}
+
+func TestRelationAggregateErrors(t *testing.T) {
+	db := setupAggregateDB(t)
+	defer db.Close()
+
+	if _, err := orm.Query[aggCustomer]().WithCount("Invoices").Get(); err == nil {
+		t.Fatal("expected unknown relation error")
+	}
+	if _, err := orm.Query[aggCustomer]().WithCount("Invoices").Count(); err == nil {
+		t.Fatal("expected unknown relation error from Count")
+	}
+	if _, err := orm.Query[aggCustomer]().WithCount("Orders", func(q *orm.Querier[aggTag]) {}).Get(); err == nil {
+		t.Fatal("expected constraint type error")
+	}
+}
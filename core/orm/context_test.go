@@ -0,0 +1,42 @@
+package orm_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/zatrano/framework/core/orm"
+)
+
+func TestContextAwareFindSaveAndTransaction(t *testing.T) {
+	db := setupORMDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	m := &fillModel{Title: "ctx"}
+	if err := orm.SaveContext(ctx, m); err != nil || m.ID == 0 {
+		t.Fatalf("save id=%d err=%v", m.ID, err)
+	}
+	found, err := orm.FindContext[fillModel](ctx, m.ID)
+	if err != nil || found.Title != "ctx" {
+		t.Fatalf("find=%+v err=%v", found, err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := orm.FindContext[fillModel](cancelled, m.ID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("find after cancel err=%v", err)
+	}
+	if _, err := orm.Query[fillModel]().WithContext(cancelled).Get(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("get after cancel err=%v", err)
+	}
+	m.Title = "changed"
+	if err := orm.SaveContext(cancelled, m); !errors.Is(err, context.Canceled) {
+		t.Fatalf("save after cancel err=%v", err)
+	}
+	err = orm.TransactionContext(cancelled, func(tx *sql.Tx) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("transaction after cancel err=%v", err)
+	}
+}
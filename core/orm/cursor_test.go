@@ -0,0 +1,35 @@
+package orm_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zatrano/framework/core/orm"
+)
+
+func TestQuerierCursorPaginate(t *testing.T) {
+	db := setupORMDB(t)
+	defer db.Close()
+
+	for i := 1; i <= 5; i++ {
+		if err := orm.Save(&fillModel{Title: fmt.Sprintf("t%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	page, err := orm.Query[fillModel]().CursorPaginate(2, "", "/posts")
+	if err != nil || len(page.Data) != 2 || page.Data[0].Title != "t1" || page.PrevPageURL != "" {
+		t.Fatalf("page=%+v err=%v", page, err)
+	}
+	if !strings.HasPrefix(page.NextPageURL, "/posts?cursor=") {
+		t.Fatalf("next url=%q", page.NextPageURL)
+	}
+	page, err = orm.Query[fillModel]().CursorPaginate(2, page.NextCursor, "/posts")
+	if err != nil || len(page.Data) != 2 || page.Data[0].Title != "t3" || page.PrevCursor == "" || page.NextCursor == "" {
+		t.Fatalf("page=%+v err=%v", page, err)
+	}
+	page, err = orm.Query[fillModel]().Latest("id").CursorPaginate(10, "")
+	if err != nil || len(page.Data) != 5 || page.Data[0].Title != "t5" || page.NextCursor != "" {
+		t.Fatalf("latest page=%+v err=%v", page, err)
+	}
+}
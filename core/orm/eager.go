@@ -3,8 +3,6 @@ package orm
 import (
 	"fmt"
 	"reflect"
-
-	"github.com/zatrano/framework/core/database/query"
 )
 
 // LoadHasMany batch-loads a has-many relation onto parents.
@@ -29,7 +27,7 @@ func LoadHasManyFn[Parent, Related any](parents *[]Parent, field, foreignKey str
 		if val == nil {
 			continue
 		}
-		k := fmt.Sprint(val)
+		k := keyString(val)
 		keyIndex[k] = append(keyIndex[k], i)
 		keys = append(keys, val)
 	}
@@ -52,7 +50,7 @@ func LoadHasManyFn[Parent, Related any](parents *[]Parent, field, foreignKey str
 		if err != nil {
 			return err
 		}
-		grouped[fmt.Sprint(fk)] = append(grouped[fmt.Sprint(fk)], row)
+		grouped[keyString(fk)] = append(grouped[keyString(fk)], row)
 	}
 
 	relatedType := reflect.TypeOf((*Related)(nil)).Elem()
@@ -75,6 +73,10 @@ func LoadHasManyFn[Parent, Related any](parents *[]Parent, field, foreignKey str
 
 // LoadHasOne batch-loads a has-one relation onto parents.
 func LoadHasOne[Parent, Related any](parents *[]Parent, field, foreignKey string, localKey ...string) error {
+	return loadHasOne[Parent, Related](parents, field, foreignKey, nil, localKey...)
+}
+
+func loadHasOne[Parent, Related any](parents *[]Parent, field, foreignKey string, constrain func(*Querier[Related]), localKey ...string) error {
 	if parents == nil || len(*parents) == 0 {
 		return nil
 	}
@@ -90,7 +92,7 @@ func LoadHasOne[Parent, Related any](parents *[]Parent, field, foreignKey string
 		if val == nil {
 			continue
 		}
-		k := fmt.Sprint(val)
+		k := keyString(val)
 		keyIndex[k] = append(keyIndex[k], i)
 		keys = append(keys, val)
 	}
@@ -98,7 +100,11 @@ func LoadHasOne[Parent, Related any](parents *[]Parent, field, foreignKey string
 		return nil
 	}
 
-	related, err := Query[Related]().WhereIn(foreignKey, keys).Get()
+	q := Query[Related]().WhereIn(foreignKey, keys)
+	if constrain != nil {
+		constrain(q)
+	}
+	related, err := q.Get()
 	if err != nil {
 		return err
 	}
@@ -109,7 +115,7 @@ func LoadHasOne[Parent, Related any](parents *[]Parent, field, foreignKey string
 		if err != nil {
 			return err
 		}
-		k := fmt.Sprint(fk)
+		k := keyString(fk)
 		if _, exists := grouped[k]; !exists {
 			grouped[k] = row
 		}
@@ -146,6 +152,10 @@ func LoadHasOne[Parent, Related any](parents *[]Parent, field, foreignKey string
 
 // LoadBelongsTo batch-loads a belongs-to relation onto children.
 func LoadBelongsTo[Child, Parent any](children *[]Child, field, foreignKey string, ownerKey ...string) error {
+	return loadBelongsTo[Child, Parent](children, field, foreignKey, nil, ownerKey...)
+}
+
+func loadBelongsTo[Child, Parent any](children *[]Child, field, foreignKey string, constrain func(*Querier[Parent]), ownerKey ...string) error {
 	if children == nil || len(*children) == 0 {
 		return nil
 	}
@@ -161,7 +171,7 @@ func LoadBelongsTo[Child, Parent any](children *[]Child, field, foreignKey strin
 		if val == nil {
 			continue
 		}
-		k := fmt.Sprint(val)
+		k := keyString(val)
 		keyIndex[k] = append(keyIndex[k], i)
 		keys = append(keys, val)
 	}
@@ -169,7 +179,11 @@ func LoadBelongsTo[Child, Parent any](children *[]Child, field, foreignKey strin
 		return nil
 	}
 
-	parents, err := Query[Parent]().WhereIn(owner, keys).Get()
+	q := Query[Parent]().WhereIn(owner, keys)
+	if constrain != nil {
+		constrain(q)
+	}
+	parents, err := q.Get()
 	if err != nil {
 		return err
 	}
@@ -180,7 +194,7 @@ func LoadBelongsTo[Child, Parent any](children *[]Child, field, foreignKey strin
 		if err != nil {
 			return err
 		}
-		grouped[fmt.Sprint(id)] = row
+		grouped[keyString(id)] = row
 	}
 
 	parentType := reflect.TypeOf((*Parent)(nil)).Elem()
@@ -245,11 +259,41 @@ func LoadBelongsToMany[Parent, Related any](
 	parents *[]Parent,
 	field, pivotTable, foreignPivotKey, relatedPivotKey string,
 	parentKey ...string,
+) error {
+	return loadBelongsToMany[Parent, Related](parents, field, pivotTable, foreignPivotKey, relatedPivotKey, nil, parentKey...)
+}
+
+func loadBelongsToMany[Parent, Related any](
+	parents *[]Parent,
+	field, pivotTable, foreignPivotKey, relatedPivotKey string,
+	constrain func(*Querier[Related]),
+	parentKey ...string,
+) error {
+	return loadPivot(parents, field, pivotTable, foreignPivotKey, relatedPivotKey, nil, constrain, parentKey...)
+}
+
+// loadPivot batch-loads related models through pivotTable, reading only the
+// pivot rows that match scope.
+func loadPivot[Parent, Related any](
+	parents *[]Parent,
+	field, pivotTable, foreignPivotKey, relatedPivotKey string,
+	scope map[string]any,
+	constrain func(*Querier[Related]),
+	parentKey ...string,
 ) error {
 	if parents == nil || len(*parents) == 0 {
 		return nil
 	}
 	local := defaultLocalKey[Parent](parentKey...)
+	if len(parentKey) == 0 || parentKey[0] == "" {
+		if _, err := singleKeyName[Parent](); err != nil {
+			return err
+		}
+	}
+	relatedKey, err := singleKeyName[Related]()
+	if err != nil {
+		return err
+	}
 
 	keys := make([]any, 0, len(*parents))
 	keyIndex := make(map[string][]int, len(*parents))
@@ -261,7 +305,7 @@ func LoadBelongsToMany[Parent, Related any](
 		if val == nil {
 			continue
 		}
-		k := fmt.Sprint(val)
+		k := keyString(val)
 		keyIndex[k] = append(keyIndex[k], i)
 		keys = append(keys, val)
 	}
@@ -269,7 +313,7 @@ func LoadBelongsToMany[Parent, Related any](
 		return nil
 	}
 
-	pivotRows, err := query.New(DB, Driver, pivotTable).WhereIn(foreignPivotKey, keys).Get()
+	pivotRows, err := pivotQuery(DB, pivotTable, scope).WhereIn(foreignPivotKey, keys).Get()
 	if err != nil {
 		return err
 	}
@@ -281,27 +325,31 @@ func LoadBelongsToMany[Parent, Related any](
 	parentToRelated := map[string][]any{}
 	seenRelated := map[string]bool{}
 	for _, row := range pivotRows {
-		pk := fmt.Sprint(row[foreignPivotKey])
+		pk := keyString(row[foreignPivotKey])
 		rid := row[relatedPivotKey]
 		parentToRelated[pk] = append(parentToRelated[pk], rid)
-		rk := fmt.Sprint(rid)
+		rk := keyString(rid)
 		if !seenRelated[rk] {
 			seenRelated[rk] = true
 			relatedIDs = append(relatedIDs, rid)
 		}
 	}
 
-	related, err := Query[Related]().WhereIn(KeyName[Related](), relatedIDs).Get()
+	q := Query[Related]().WhereIn(relatedKey, relatedIDs)
+	if constrain != nil {
+		constrain(q)
+	}
+	related, err := q.Get()
 	if err != nil {
 		return err
 	}
 	byID := map[string]Related{}
 	for _, row := range related {
-		id, err := KeyValue(&row)
+		id, err := attribute(&row, relatedKey)
 		if err != nil {
 			return err
 		}
-		byID[fmt.Sprint(id)] = row
+		byID[keyString(id)] = row
 	}
 
 	relatedType := reflect.TypeOf((*Related)(nil)).Elem()
@@ -310,7 +358,7 @@ func LoadBelongsToMany[Parent, Related any](
 		ids := parentToRelated[key]
 		items := make([]Related, 0, len(ids))
 		for _, id := range ids {
-			if row, ok := byID[fmt.Sprint(id)]; ok {
+			if row, ok := byID[keyString(id)]; ok {
 				items = append(items, row)
 			}
 		}
@@ -339,6 +387,10 @@ func EagerBelongsToMany[Parent, Related any](
 
 // LoadMorphMany batch-loads a morph-many relation onto parents.
 func LoadMorphMany[Parent, Related any](parents *[]Parent, field, morphTypeCol, morphIDCol, typeValue string, localKey ...string) error {
+	return loadMorphMany[Parent, Related](parents, field, morphTypeCol, morphIDCol, typeValue, nil, localKey...)
+}
+
+func loadMorphMany[Parent, Related any](parents *[]Parent, field, morphTypeCol, morphIDCol, typeValue string, constrain func(*Querier[Related]), localKey ...string) error {
 	if parents == nil || len(*parents) == 0 {
 		return nil
 	}
@@ -354,7 +406,7 @@ func LoadMorphMany[Parent, Related any](parents *[]Parent, field, morphTypeCol,
 		if val == nil {
 			continue
 		}
-		k := fmt.Sprint(val)
+		k := keyString(val)
 		keyIndex[k] = append(keyIndex[k], i)
 		keys = append(keys, val)
 	}
@@ -362,7 +414,11 @@ func LoadMorphMany[Parent, Related any](parents *[]Parent, field, morphTypeCol,
 		return nil
 	}
 
-	related, err := Query[Related]().Where(morphTypeCol, typeValue).WhereIn(morphIDCol, keys).Get()
+	q := Query[Related]().Where(morphTypeCol, typeValue).WhereIn(morphIDCol, keys)
+	if constrain != nil {
+		constrain(q)
+	}
+	related, err := q.Get()
 	if err != nil {
 		return err
 	}
@@ -372,7 +428,7 @@ func LoadMorphMany[Parent, Related any](parents *[]Parent, field, morphTypeCol,
 		if err != nil {
 			return err
 		}
-		grouped[fmt.Sprint(fk)] = append(grouped[fmt.Sprint(fk)], row)
+		grouped[keyString(fk)] = append(grouped[keyString(fk)], row)
 	}
 
 	relatedType := reflect.TypeOf((*Related)(nil)).Elem()
@@ -399,13 +455,56 @@ func EagerMorphMany[Parent, Related any](field, morphTypeCol, morphIDCol, typeVa
 	}
 }
 
-// With registers eager loaders executed after Get() or First().
+// With registers eager loaders, such as EagerHasMany[Post, Comment]("Comments",
+// "post_id"), executed after Get(), First() and the other fetch methods.
 func (q *Querier[T]) With(loaders ...func([]T) error) *Querier[T] {
 	q.loaders = append(q.loaders, loaders...)
 	return q
 }
 
+// WithRelations eager loads dotted relation paths such as
+// "Posts.Comments.Author", resolved through the Relations() declared by each
+// model along the path. Relation paths run one query per level, however many
+// paths share it. An unknown relation fails the fetch without running it.
+func (q *Querier[T]) WithRelations(paths ...string) *Querier[T] {
+	for _, path := range paths {
+		q.withRelation(path, nil)
+	}
+	return q
+}
+
+// WithConstrained eager loads one relation path whose last level is
+// constrained by a func(*Querier[Related]):
+//
+//	orm.Query[User]().WithConstrained("Posts.Comments", func(q *orm.Querier[Comment]) {
+//		q.Where("approved", true)
+//	})
+func (q *Querier[T]) WithConstrained(path string, constrain any) *Querier[T] {
+	return q.withRelation(path, constrain)
+}
+
+func (q *Querier[T]) withRelation(path string, constrain any) *Querier[T] {
+	if err := relationsOf[T]().validate(path, constrain, q.table); err != nil {
+		return q.fail(err)
+	}
+	q.eager = append(q.eager, eagerLoad{path: path, constrain: constrain})
+	return q
+}
+
+// fail records the first error from building the query.
+func (q *Querier[T]) fail(err error) *Querier[T] {
+	if q.err == nil {
+		q.err = err
+	}
+	return q
+}
+
+func (q *Querier[T]) hasLoaders() bool {
+	return len(q.loaders) > 0 || len(q.eager) > 0
+}
+
 func (q *Querier[T]) runLoaders(items []T) error {
+	markCollection(items)
 	for _, loader := range q.loaders {
 		if loader == nil {
 			continue
@@ -414,7 +513,7 @@ func (q *Querier[T]) runLoaders(items []T) error {
 			return err
 		}
 	}
-	return nil
+	return q.runRelations(items)
 }
 
 func setRelationField(parent reflect.Value, name string, value reflect.Value) error {
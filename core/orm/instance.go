@@ -13,7 +13,7 @@ func DeleteModel[T any](model *T) (int64, error) {
 	if err := dispatchModel("deleting", model); err != nil {
 		return 0, err
 	}
-	n, err := Query[T]().Where(KeyName[T](), id).Delete()
+	n, err := Query[T]().WhereKey(id).Delete()
 	if err != nil {
 		return 0, err
 	}
@@ -30,7 +30,7 @@ func ForceDeleteModel[T any](model *T) (int64, error) {
 	if err := dispatchModel("deleting", model); err != nil {
 		return 0, err
 	}
-	n, err := Query[T]().WithTrashed().Where(KeyName[T](), id).ForceDelete()
+	n, err := Query[T]().WithTrashed().WhereKey(id).ForceDelete()
 	if err != nil {
 		return 0, err
 	}
@@ -45,7 +45,7 @@ func RestoreModel[T any](model *T) (int64, error) {
 		return 0, err
 	}
 	_ = dispatchModel("restoring", model)
-	n, err := Query[T]().OnlyTrashed().Where(KeyName[T](), id).Restore()
+	n, err := Query[T]().OnlyTrashed().WhereKey(id).Restore()
 	if err != nil {
 		return 0, err
 	}
@@ -61,9 +61,13 @@ func Replicate[T any](model *T) *T {
 	src := reflect.ValueOf(model).Elem()
 	dst := reflect.New(src.Type()).Elem()
 	dst.Set(src)
+	if m, ok := dst.Addr().Interface().(collectionMember); ok {
+		m.setLoadedInCollection(false)
+	}
 
-	keyName := KeyName[T]()
-	zeroColumn(dst, keyName)
+	for _, keyName := range KeyNames[T]() {
+		zeroColumn(dst, keyName)
+	}
 	if id := dst.FieldByName("ID"); id.IsValid() && id.CanSet() {
 		id.SetZero()
 	}
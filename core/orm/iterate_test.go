@@ -0,0 +1,83 @@
+package orm_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zatrano/framework/core/orm"
+)
+
+func TestCursorAndLazyStreamModels(t *testing.T) {
+	db := setupORMDB(t)
+	defer db.Close()
+
+	for i := 1; i <= 250; i++ {
+		if err := orm.Save(&fillModel{Title: fmt.Sprintf("t%03d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	seen := 0
+	for model, err := range orm.Query[fillModel]().OrderBy("id").Cursor() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen++
+		if model.Title != fmt.Sprintf("t%03d", seen) {
+			t.Fatalf("row %d title=%q", seen, model.Title)
+		}
+	}
+	if seen != 250 {
+		t.Fatalf("cursor saw %d models", seen)
+	}
+
+	// Loaders run per chunk before the chunk's models are yielded.
+	var batches []int
+	loader := func(items []fillModel) error {
+		batches = append(batches, len(items))
+		for i := range items {
+			items[i].Secret = "loaded"
+		}
+		return nil
+	}
+	seen = 0
+	for model, err := range orm.Query[fillModel]().With(loader).Lazy(100) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if model.Secret != "loaded" {
+			t.Fatalf("model %d not eager loaded", model.ID)
+		}
+		seen++
+	}
+	if seen != 250 || fmt.Sprint(batches) != "[100 100 50]" {
+		t.Fatalf("lazy seen=%d batches=%v", seen, batches)
+	}
+
+	batches = nil
+	for model, err := range orm.Query[fillModel]().With(loader).Cursor() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if model.ID == 150 {
+			break
+		}
+	}
+	if fmt.Sprint(batches) != "[100 100]" {
+		t.Fatalf("cursor batches=%v", batches)
+	}
+
+	last := int64(0)
+	for model, err := range orm.Query[fillModel]().Where("id", ">", 240).Lazy(3) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if model.ID <= last {
+			t.Fatalf("lazy out of order: %d after %d", model.ID, last)
+		}
+		last = model.ID
+	}
+	if last != 250 {
+		t.Fatalf("lazy last=%d", last)
+	}
+}
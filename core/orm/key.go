@@ -3,6 +3,7 @@ package orm
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 // PrimaryKey models declare a non-default primary key column name.
@@ -10,16 +11,55 @@ type PrimaryKey interface {
 	PrimaryKey() string
 }
 
-// KeyName returns the primary key column for model T (default "id").
+// CompositeKey models are identified by several columns, e.g. pivot tables.
+// Their key values are a map[string]any keyed by column (as returned by
+// KeyValue) or a []any in PrimaryKeys order. Relations reference a model
+// through one column, so pivot helpers (Attach, Detach, Sync, Toggle) and
+// belongs-to-many loaders return an error for composite-key models rather
+// than matching on the first column.
+type CompositeKey interface {
+	PrimaryKeys() []string
+}
+
+// KeyGenerator models generate their own primary key on create (see UUIDModel
+// and ULIDModel).
+type KeyGenerator interface {
+	NewKey() string
+}
+
+// KeyName returns the primary key column for model T (default "id"). For
+// composite keys it returns the first column; see KeyNames.
 func KeyName[T any]() string {
 	return keyNameForType(reflect.TypeOf((*T)(nil)).Elem())
 }
 
+// KeyNames returns every primary key column for model T.
+func KeyNames[T any]() []string {
+	return keyNamesForType(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+func keyNamesForType(rt reflect.Type) []string {
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if ck, ok := reflect.New(rt).Interface().(CompositeKey); ok {
+		if keys := ck.PrimaryKeys(); len(keys) > 0 {
+			return keys
+		}
+	}
+	return []string{keyNameForType(rt)}
+}
+
 func keyNameForType(rt reflect.Type) string {
 	if rt.Kind() == reflect.Ptr {
 		rt = rt.Elem()
 	}
 	zero := reflect.New(rt).Interface()
+	if ck, ok := zero.(CompositeKey); ok {
+		if keys := ck.PrimaryKeys(); len(keys) > 0 {
+			return keys[0]
+		}
+	}
 	if pk, ok := zero.(PrimaryKey); ok {
 		if name := pk.PrimaryKey(); name != "" {
 			return name
@@ -36,7 +76,8 @@ func keyNameForType(rt reflect.Type) string {
 	return "id"
 }
 
-// KeyValue returns the primary key value for a model instance.
+// KeyValue returns the primary key value for a model instance, or a
+// map[string]any of column values for composite keys.
 func KeyValue(model any) (any, error) {
 	if model == nil {
 		return nil, fmt.Errorf("model is nil")
@@ -48,7 +89,96 @@ func KeyValue(model any) (any, error) {
 		}
 		rv = rv.Elem()
 	}
-	keyName := keyNameForType(rv.Type())
+	keys := keyNamesForType(rv.Type())
+	if len(keys) == 1 {
+		return keyColumnValue(rv, keys[0])
+	}
+	out := make(map[string]any, len(keys))
+	for _, key := range keys {
+		value, err := keyColumnValue(rv, key)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = value
+	}
+	return out, nil
+}
+
+// singleKeyValue is KeyValue for relations that reference a model through
+// one column, which composite keys cannot satisfy.
+func singleKeyValue(model any) (any, error) {
+	value, err := KeyValue(model)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := value.(map[string]any); ok {
+		return nil, fmt.Errorf("%T has a composite primary key and cannot be referenced by one column", model)
+	}
+	return value, nil
+}
+
+// singleKeyName is KeyName for relations that reference T through one column.
+func singleKeyName[T any]() (string, error) {
+	keys := KeyNames[T]()
+	if len(keys) > 1 {
+		return "", fmt.Errorf("%s has a composite primary key and cannot be referenced by one column", reflect.TypeOf((*T)(nil)).Elem())
+	}
+	return keys[0], nil
+}
+
+// keyValues splits id into one value per key column.
+func keyValues(keys []string, id any) ([]any, error) {
+	if len(keys) == 1 {
+		return []any{id}, nil
+	}
+	switch v := id.(type) {
+	case map[string]any:
+		out := make([]any, len(keys))
+		for i, key := range keys {
+			value, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("composite key is missing column [%s]", key)
+			}
+			out[i] = value
+		}
+		return out, nil
+	case []any:
+		if len(v) != len(keys) {
+			return nil, fmt.Errorf("composite key needs %d values, got %d", len(keys), len(v))
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("composite key (%s) needs a map[string]any or []any, got %T", strings.Join(keys, ", "), id)
+	}
+}
+
+// rowKey extracts the key of row in the form KeyValue returns, reporting
+// whether every key column is present.
+func rowKey(keys []string, row map[string]any) (any, bool) {
+	if len(keys) == 1 {
+		value, ok := row[keys[0]]
+		return value, ok && !isZeroAny(value)
+	}
+	out := make(map[string]any, len(keys))
+	for _, key := range keys {
+		value, ok := row[key]
+		if !ok || isZeroAny(value) {
+			return nil, false
+		}
+		out[key] = value
+	}
+	return out, true
+}
+
+// keyString normalises a key value for grouping; drivers may return text keys as []byte.
+func keyString(value any) string {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
+
+func keyColumnValue(rv reflect.Value, keyName string) (any, error) {
 	fv, ok := fieldValueByColumn(rv, keyName)
 	if !ok || !fv.IsValid() {
 		return nil, fmt.Errorf("primary key field [%s] not found", keyName)
@@ -135,3 +265,14 @@ func setKeyField(rv reflect.Value, keyName string, value any) {
 	}
 	_ = setField(fv, value)
 }
+
+// generateKey assigns a new key to attrs when T is a KeyGenerator and no key
+// was given. It reports whether a key was generated.
+func generateKey[T any](attrs map[string]any, keyName string) bool {
+	gen, ok := any(new(T)).(KeyGenerator)
+	if !ok || len(KeyNames[T]()) > 1 || !isZeroAny(attrs[keyName]) {
+		return false
+	}
+	attrs[keyName] = gen.NewKey()
+	return true
+}
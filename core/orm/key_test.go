@@ -0,0 +1,269 @@
+package orm_test
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/orm"
+	"github.com/zatrano/framework/core/support/uuid"
+)
+
+type uuidDoc struct {
+	orm.UUIDModel
+	Title string    `db:"title"`
+	Tags  []uuidTag `db:"-"`
+}
+
+func (uuidDoc) TableName() string { return "uuid_docs" }
+
+type ulidDoc struct {
+	orm.ULIDModel
+	Title string `db:"title"`
+}
+
+func (ulidDoc) TableName() string { return "ulid_docs" }
+
+type uuidTag struct {
+	orm.Model
+	DocID string `db:"doc_id"`
+	Name  string `db:"name"`
+}
+
+func (uuidTag) TableName() string { return "uuid_tags" }
+
+type membership struct {
+	TeamID    int64      `db:"team_id"`
+	UserID    int64      `db:"user_id"`
+	Role      string     `db:"role"`
+	CreatedAt *time.Time `db:"created_at"`
+	UpdatedAt *time.Time `db:"updated_at"`
+}
+
+func (membership) TableName() string     { return "team_user" }
+func (membership) PrimaryKeys() []string { return []string{"team_id", "user_id"} }
+
+func setupKeyDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sqlStr := range []string{
+		`CREATE TABLE uuid_docs (id TEXT PRIMARY KEY, title TEXT, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE ulid_docs (id TEXT PRIMARY KEY, title TEXT, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE uuid_tags (id INTEGER PRIMARY KEY AUTOINCREMENT, doc_id TEXT, name TEXT, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE team_user (team_id INTEGER, user_id INTEGER, role TEXT, created_at DATETIME, updated_at DATETIME, PRIMARY KEY (team_id, user_id))`,
+	} {
+		if _, err := db.Exec(sqlStr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orm.Configure(db, "sqlite")
+	return db
+}
+
+func TestUUIDAndULIDModels(t *testing.T) {
+	db := setupKeyDB(t)
+	defer db.Close()
+
+	created, err := orm.Create[uuidDoc](map[string]any{"title": "created"})
+	if err != nil || !uuid.IsV4(created.ID) {
+		t.Fatalf("create=%+v err=%v", created, err)
+	}
+
+	saved := &uuidDoc{Title: "saved"}
+	if err := orm.Save(saved); err != nil || !uuid.IsV4(saved.ID) {
+		t.Fatalf("save=%+v err=%v", saved, err)
+	}
+	saved.Title = "renamed"
+	if err := orm.Save(saved); err != nil {
+		t.Fatal(err)
+	}
+	if err := orm.Refresh(saved); err != nil || saved.Title != "renamed" {
+		t.Fatalf("refresh=%+v err=%v", saved, err)
+	}
+	if n, _ := orm.Query[uuidDoc]().Count(); n != 2 {
+		t.Fatalf("count=%d", n)
+	}
+
+	_, _ = orm.Create[uuidTag](map[string]any{"doc_id": saved.ID, "name": "go"})
+	docs, err := orm.Query[uuidDoc]().
+		With(orm.EagerHasMany[uuidDoc, uuidTag]("Tags", "doc_id")).
+		OrderBy("title").
+		Get()
+	if err != nil || len(docs) != 2 || len(docs[1].Tags) != 1 || len(docs[0].Tags) != 0 {
+		t.Fatalf("eager docs=%+v err=%v", docs, err)
+	}
+
+	first, err := orm.Create[ulidDoc](map[string]any{"title": "a"})
+	if err != nil || !uuid.IsULID(first.ID) {
+		t.Fatalf("ulid create=%+v err=%v", first, err)
+	}
+	found, err := orm.Find[ulidDoc](first.ID)
+	if err != nil || found.Title != "a" {
+		t.Fatalf("ulid find=%+v err=%v", found, err)
+	}
+	if n, err := orm.DeleteModel(found); err != nil || n != 1 {
+		t.Fatalf("ulid delete n=%d err=%v", n, err)
+	}
+}
+
+func TestSavePresetGeneratedKeys(t *testing.T) {
+	db := setupKeyDB(t)
+	defer db.Close()
+
+	id := uuid.New()
+	doc := &uuidDoc{Title: "preset"}
+	doc.ID = id
+	if err := orm.Save(doc); err != nil || doc.ID != id {
+		t.Fatalf("save=%+v err=%v", doc, err)
+	}
+	found, err := orm.Find[uuidDoc](id)
+	if err != nil || found.Title != "preset" {
+		t.Fatalf("find=%+v err=%v", found, err)
+	}
+	found.Title = "updated"
+	if err := orm.Save(found); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := orm.Query[uuidDoc]().Count(); n != 1 {
+		t.Fatalf("count=%d", n)
+	}
+
+	ulid := &ulidDoc{Title: "preset"}
+	ulid.ID = "01J00000000000000000000000"
+	if err := orm.Save(ulid); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := orm.Find[ulidDoc](ulid.ID); err != nil || got.Title != "preset" {
+		t.Fatalf("find=%+v err=%v", got, err)
+	}
+}
+
+func TestSaveChecksGeneratedKeysOnWriter(t *testing.T) {
+	db := setupKeyDB(t)
+	defer db.Close()
+	replica, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replica.Close()
+	if _, err := replica.Exec(`CREATE TABLE uuid_docs (id TEXT PRIMARY KEY, title TEXT, created_at DATETIME, updated_at DATETIME)`); err != nil {
+		t.Fatal(err)
+	}
+	orm.SetReadConnection(replica, false)
+	defer orm.SetReadConnection(nil, false)
+
+	doc := &uuidDoc{Title: "preset"}
+	doc.ID = uuid.New()
+	if err := orm.Save(doc); err != nil {
+		t.Fatal(err)
+	}
+	doc.Title = "updated"
+	if err := orm.Save(doc); err != nil {
+		t.Fatalf("second save on a lagging replica: %v", err)
+	}
+	var title string
+	if err := db.QueryRow(`SELECT title FROM uuid_docs WHERE id = ?`, doc.ID).Scan(&title); err != nil || title != "updated" {
+		t.Fatalf("title=%q err=%v", title, err)
+	}
+}
+
+func TestStickyReadsWithoutContext(t *testing.T) {
+	db := setupKeyDB(t)
+	defer db.Close()
+	replica, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replica.Close()
+	if _, err := replica.Exec(`CREATE TABLE uuid_docs (id TEXT PRIMARY KEY, title TEXT, created_at DATETIME, updated_at DATETIME)`); err != nil {
+		t.Fatal(err)
+	}
+	orm.SetReadConnection(replica, true)
+	defer orm.SetReadConnection(nil, false)
+
+	doc := &uuidDoc{Title: "fresh"}
+	if err := orm.Save(doc); err != nil {
+		t.Fatal(err)
+	}
+	found, err := orm.Find[uuidDoc](doc.ID)
+	if err != nil || found.Title != "fresh" {
+		t.Fatalf("find after save=%+v err=%v", found, err)
+	}
+}
+
+func TestCompositeKeys(t *testing.T) {
+	db := setupKeyDB(t)
+	defer db.Close()
+
+	if keys := orm.KeyNames[membership](); len(keys) != 2 || keys[1] != "user_id" {
+		t.Fatalf("keys=%v", keys)
+	}
+
+	m := &membership{TeamID: 1, UserID: 7, Role: "member"}
+	if err := orm.Save(m); err != nil {
+		t.Fatal(err)
+	}
+	other := &membership{TeamID: 2, UserID: 7, Role: "owner"}
+	if err := orm.Save(other); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Role = "admin"
+	if err := orm.Save(m); err != nil {
+		t.Fatal(err)
+	}
+	key, err := orm.KeyValue(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err := orm.Find[membership](key)
+	if err != nil || found.Role != "admin" {
+		t.Fatalf("find by map=%+v err=%v", found, err)
+	}
+	found, err = orm.Find[membership]([]any{int64(2), int64(7)})
+	if err != nil || found.Role != "owner" {
+		t.Fatalf("find by slice=%+v err=%v", found, err)
+	}
+	if _, err := orm.Find[membership](int64(2)); err == nil {
+		t.Fatal("expected error for single value on composite key")
+	}
+
+	fresh, err := orm.Fresh(m)
+	if err != nil || fresh.TeamID != 1 || fresh.Role != "admin" {
+		t.Fatalf("fresh=%+v err=%v", fresh, err)
+	}
+
+	if n, err := orm.DeleteModel(m); err != nil || n != 1 {
+		t.Fatalf("delete n=%d err=%v", n, err)
+	}
+	if n, _ := orm.Query[membership]().Count(); n != 1 {
+		t.Fatalf("remaining=%d", n)
+	}
+	if err := orm.Attach(other, "team_user_tags", "membership_id", "tag_id", []any{1}); err == nil {
+		t.Fatal("expected composite key error from Attach")
+	}
+}
+
+func TestCompositeKeysRejectedByPivotRelations(t *testing.T) {
+	db := setupKeyDB(t)
+	defer db.Close()
+
+	m := &membership{TeamID: 1, UserID: 7}
+	if err := orm.Attach(m, "membership_tags", "membership_id", "tag_id", []any{1}); err == nil || !strings.Contains(err.Error(), "composite") {
+		t.Fatalf("attach err=%v", err)
+	}
+	docs := []uuidDoc{{Title: "a"}}
+	docs[0].ID = uuid.New()
+	if err := orm.LoadBelongsToMany[uuidDoc, membership](&docs, "Members", "doc_members", "doc_id", "membership_id"); err == nil || !strings.Contains(err.Error(), "composite") {
+		t.Fatalf("load err=%v", err)
+	}
+	if _, err := orm.BelongsToMany[uuidDoc, membership](&docs[0], "doc_members", "doc_id", "membership_id"); err == nil || !strings.Contains(err.Error(), "composite") {
+		t.Fatalf("belongs to many err=%v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrLazyLoading is returned by lazy relation helpers while PreventLazyLoading
+// is on and the model was loaded as part of a collection.
+var ErrLazyLoading = errors.New("lazy loading prevented")
+
+var (
+	preventLazy     atomic.Bool
+	lazyViolationMu sync.RWMutex
+	lazyViolation   func(model any, relation string)
+)
+
+// PreventLazyLoading makes the lazy relation helpers (HasMany, HasOne,
+// CountRelated, BelongsTo, BelongsToMany and the Morph* helpers) refuse to
+// query for a model that came from a query returning several rows, where a
+// loop over the results would run one query per model. Eager load those
+// relations with With instead. Meant for development and tests:
+//
+//	orm.PreventLazyLoading(!app.IsProduction())
+//
+// Only models embedding Model, UUIDModel or ULIDModel are tracked.
+func PreventLazyLoading(on bool) {
+	preventLazy.Store(on)
+}
+
+// LazyLoadingPrevented reports whether PreventLazyLoading is on.
+func LazyLoadingPrevented() bool {
+	return preventLazy.Load()
+}
+
+// HandleLazyLoadingViolationUsing calls fn instead of returning ErrLazyLoading,
+// and lets the query run; use it to log violations. nil restores the error.
+func HandleLazyLoadingViolationUsing(fn func(model any, relation string)) {
+	lazyViolationMu.Lock()
+	defer lazyViolationMu.Unlock()
+	lazyViolation = fn
+}
+
+// collectionMember is implemented by the base models, which remember whether
+// they were loaded together with other rows.
+type collectionMember interface {
+	loadedInCollection() bool
+	setLoadedInCollection(bool)
+}
+
+func (m *Model) loadedInCollection() bool         { return m.inCollection }
+func (m *Model) setLoadedInCollection(v bool)     { m.inCollection = v }
+func (m *UUIDModel) loadedInCollection() bool     { return m.inCollection }
+func (m *UUIDModel) setLoadedInCollection(v bool) { m.inCollection = v }
+func (m *ULIDModel) loadedInCollection() bool     { return m.inCollection }
+func (m *ULIDModel) setLoadedInCollection(v bool) { m.inCollection = v }
+
+// markCollection flags models fetched by a query that returned more than one row.
+func markCollection[T any](items []T) {
+	if len(items) < 2 {
+		return
+	}
+	for i := range items {
+		m, ok := any(&items[i]).(collectionMember)
+		if !ok {
+			return
+		}
+		m.setLoadedInCollection(true)
+	}
+}
+
+// guardLazyLoad is called by the lazy relation helpers before they query.
+func guardLazyLoad(model any, relation string) error {
+	if !preventLazy.Load() {
+		return nil
+	}
+	m, ok := model.(collectionMember)
+	if !ok || !m.loadedInCollection() {
+		return nil
+	}
+	lazyViolationMu.RLock()
+	fn := lazyViolation
+	lazyViolationMu.RUnlock()
+	if fn != nil {
+		fn(model, relation)
+		return nil
+	}
+	return fmt.Errorf("%w: attempted to lazy load [%s] on model [%T] loaded in a collection; eager load it with With", ErrLazyLoading, relation, model)
+}
+
+// typeName names T in lazy loading errors, e.g. models.Post.
+func typeName[T any]() string {
+	return reflect.TypeOf((*T)(nil)).Elem().String()
+}
@@ -0,0 +1,89 @@
+package orm_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/orm"
+)
+
+type lazyAuthor struct {
+	orm.Model
+	Name string `db:"name"`
+}
+
+func (lazyAuthor) TableName() string { return "lazy_authors" }
+
+type lazyBook struct {
+	orm.Model
+	AuthorID int64  `db:"author_id"`
+	Title    string `db:"title"`
+}
+
+func (lazyBook) TableName() string { return "lazy_books" }
+
+func setupLazyDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sqlStr := range []string{
+		`CREATE TABLE lazy_authors (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE lazy_books (id INTEGER PRIMARY KEY AUTOINCREMENT, author_id INTEGER, title TEXT, created_at DATETIME, updated_at DATETIME)`,
+		`INSERT INTO lazy_authors (name) VALUES ('ann'), ('ben')`,
+		`INSERT INTO lazy_books (author_id, title) VALUES (1, 'one'), (2, 'two')`,
+	} {
+		if _, err := db.Exec(sqlStr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orm.Configure(db, "sqlite")
+	return db
+}
+
+func TestPreventLazyLoading(t *testing.T) {
+	db := setupLazyDB(t)
+	defer db.Close()
+
+	orm.PreventLazyLoading(true)
+	defer orm.PreventLazyLoading(false)
+
+	authors, err := orm.Query[lazyAuthor]().OrderBy("id").Get()
+	if err != nil || len(authors) != 2 {
+		t.Fatalf("authors=%+v err=%v", authors, err)
+	}
+	for _, author := range authors {
+		if _, err := orm.HasMany[lazyAuthor, lazyBook](&author, "author_id"); !errors.Is(err, orm.ErrLazyLoading) {
+			t.Fatalf("expected lazy loading error, got %v", err)
+		}
+	}
+
+	single, _ := orm.Find[lazyAuthor](int64(1))
+	if books, err := orm.HasMany[lazyAuthor, lazyBook](single, "author_id"); err != nil || len(books) != 1 {
+		t.Fatalf("single model books=%+v err=%v", books, err)
+	}
+
+	books, _ := orm.Query[lazyBook]().Get()
+	var violations []string
+	orm.HandleLazyLoadingViolationUsing(func(model any, relation string) {
+		violations = append(violations, relation)
+	})
+	defer orm.HandleLazyLoadingViolationUsing(nil)
+	author, err := orm.BelongsTo[lazyBook, lazyAuthor](&books[0], "author_id")
+	if err != nil || author == nil || author.Name != "ann" {
+		t.Fatalf("author=%+v err=%v", author, err)
+	}
+	if len(violations) != 1 || violations[0] != "orm_test.lazyAuthor" {
+		t.Fatalf("violations=%v", violations)
+	}
+
+	if replica := orm.Replicate(&authors[0]); replica == nil {
+		t.Fatal("replicate returned nil")
+	} else if _, err := orm.HasMany[lazyAuthor, lazyBook](replica, "author_id"); err != nil {
+		t.Fatalf("replica still flagged: %v", err)
+	}
+}
@@ -1,15 +1,18 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"iter"
 	"reflect"
 	"strings"
 	"time"
 
 	"github.com/zatrano/framework/core/database/query"
 	"github.com/zatrano/framework/core/pagination"
+	"github.com/zatrano/framework/core/support/uuid"
 )
 
 // DB is the active database connection used by ORM.
@@ -23,8 +26,34 @@ type Model struct {
 	ID        int64      `db:"id" json:"id"`
 	CreatedAt *time.Time `db:"created_at" json:"created_at,omitempty"`
 	UpdatedAt *time.Time `db:"updated_at" json:"updated_at,omitempty"`
+
+	inCollection bool
+}
+
+// UUIDModel is a base model keyed by a UUID v4 generated on create.
+type UUIDModel struct {
+	ID        string     `db:"id" json:"id"`
+	CreatedAt *time.Time `db:"created_at" json:"created_at,omitempty"`
+	UpdatedAt *time.Time `db:"updated_at" json:"updated_at,omitempty"`
+
+	inCollection bool
+}
+
+// NewKey generates the UUID assigned on create.
+func (UUIDModel) NewKey() string { return uuid.New() }
+
+// ULIDModel is a base model keyed by a time-ordered ULID generated on create.
+type ULIDModel struct {
+	ID        string     `db:"id" json:"id"`
+	CreatedAt *time.Time `db:"created_at" json:"created_at,omitempty"`
+	UpdatedAt *time.Time `db:"updated_at" json:"updated_at,omitempty"`
+
+	inCollection bool
 }
 
+// NewKey generates the ULID assigned on create.
+func (ULIDModel) NewKey() string { return uuid.NewULID() }
+
 // SoftDeletes adds soft delete support.
 type SoftDeletes struct {
 	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
@@ -40,6 +69,13 @@ type Querier[T any] struct {
 	globalsApplied   bool
 	removedScopes    map[string]bool
 	loaders          []func([]T) error
+	eager            []eagerLoad
+	remember         *remember
+	// tx is set when the querier runs inside a transaction (QueryTx).
+	tx *sql.Tx
+	// err is the first error from building the query, such as an unknown
+	// relation; the fetch methods return it without running the query.
+	err error
 }
 
 // Configure sets the global database connection for ORM.
@@ -48,6 +84,36 @@ func Configure(db *sql.DB, driver string) {
 	Driver = driver
 }
 
+var (
+	queryListener func(query.Executed)
+	readDB        query.DBTX
+	readSticky    bool
+)
+
+// SetQueryListener reports every ORM statement to fn (see database.Manager.QueryListener).
+func SetQueryListener(fn func(query.Executed)) {
+	queryListener = fn
+}
+
+// SetReadConnection routes ORM reads on DB to reader (see database.Manager.ReadConnection).
+// Queries on a transaction via QueryTx/QueryOn always stay on that transaction.
+// With sticky on, reads on a query.TrackWrites context (the request context
+// passed to SaveContext, FindContext or WithContext) follow that context's
+// writes to the primary; reads without one, such as Save then Find, use the
+// primary for query.SetStickyWindow after any write.
+func SetReadConnection(reader query.DBTX, sticky bool) {
+	readDB = reader
+	readSticky = sticky
+}
+
+func newBuilder(db query.DBTX, table string) *query.Builder {
+	builder := query.New(db, Driver, table).Listen(queryListener)
+	if readDB != nil && db == DB {
+		builder.UseReadConnection(readDB, readSticky)
+	}
+	return builder
+}
+
 // Table resolves the table name for a model type.
 func Table[T any]() string {
 	var zero T
@@ -77,7 +143,7 @@ func Table[T any]() string {
 func Query[T any]() *Querier[T] {
 	table := Table[T]()
 	return &Querier[T]{
-		builder:    query.New(DB, Driver, table),
+		builder:    newBuilder(DB, table),
 		table:      table,
 		softDelete: hasSoftDeletes[T](),
 	}
@@ -90,7 +156,15 @@ func Where[T any](column string, args ...any) *Querier[T] {
 
 // Find finds a model by primary key.
 func Find[T any](id any) (*T, error) {
-	return Query[T]().Where(KeyName[T](), id).First()
+	return FindContext[T](context.Background(), id)
+}
+
+// FindContext finds a model by primary key using ctx.
+func FindContext[T any](ctx context.Context, id any) (*T, error) {
+	if _, err := keyValues(KeyNames[T](), id); err != nil {
+		return nil, err
+	}
+	return Query[T]().WithContext(ctx).WhereKey(id).First()
 }
 
 // FindOrFail finds a model by primary key or returns an error.
@@ -120,22 +194,27 @@ func Create[T any](attrs map[string]any) (*T, error) {
 	if _, ok := attrs["updated_at"]; !ok {
 		attrs["updated_at"] = now
 	}
+	if col := versionColumn[T](); col != "" && isZeroAny(attrs[col]) {
+		attrs[col] = int64(1)
+	}
+	keyName := KeyName[T]()
+	generateKey[T](attrs, keyName)
 
 	draft := attrsToModel[T](attrs)
 	if err := dispatchModel("creating", draft); err != nil {
 		return nil, err
 	}
 
-	id, err := query.New(DB, Driver, Table[T]()).Insert(attrs)
+	id, err := newBuilder(DB, Table[T]()).Insert(attrs)
 	if err != nil {
 		return nil, err
 	}
+	flushRemembered(Table[T]())
 	var model *T
-	keyName := KeyName[T]()
-	if id > 0 && keyName == "id" {
-		model, err = Find[T](id)
-	} else if keyVal, ok := attrs[keyName]; ok {
+	if keyVal, ok := rowKey(KeyNames[T](), attrs); ok {
 		model, err = Find[T](keyVal)
+	} else if id > 0 && keyName == "id" {
+		model, err = Find[T](id)
 	} else {
 		// Fallback for drivers without LastInsertId support.
 		q := Query[T]()
@@ -159,6 +238,22 @@ func (q *Querier[T]) Where(column string, args ...any) *Querier[T] {
 	return q
 }
 
+// WhereKey constrains the query to the model with primary key id. Composite
+// keys take a map[string]any by column or a []any in PrimaryKeys order; a
+// malformed composite key matches nothing.
+func (q *Querier[T]) WhereKey(id any) *Querier[T] {
+	keys := KeyNames[T]()
+	values, err := keyValues(keys, id)
+	if err != nil {
+		q.builder.WhereRaw("1 = 0")
+		return q
+	}
+	for i, key := range keys {
+		q.builder.Where(key, values[i])
+	}
+	return q
+}
+
 // OrWhere adds an OR where clause.
 func (q *Querier[T]) OrWhere(column string, args ...any) *Querier[T] {
 	q.builder.OrWhere(column, args...)
@@ -377,13 +472,17 @@ func (q *Querier[T]) OrWhereNotLike(column string, pattern string) *Querier[T] {
 
 // Value returns a single column value from the first matching row.
 func (q *Querier[T]) Value(column string) (any, error) {
-	q.prepare()
+	if err := q.prepare(); err != nil {
+		return nil, err
+	}
 	return q.builder.Value(column)
 }
 
 // Pluck returns a slice of values for the given column.
 func (q *Querier[T]) Pluck(column string) ([]any, error) {
-	q.prepare()
+	if err := q.prepare(); err != nil {
+		return nil, err
+	}
 	return q.builder.Pluck(column)
 }
 
@@ -529,6 +628,10 @@ func (q *Querier[T]) Clone() *Querier[T] {
 		globalsApplied:   q.globalsApplied,
 		removedScopes:    copyBoolMap(q.removedScopes),
 		loaders:          loaders,
+		eager:            append([]eagerLoad(nil), q.eager...),
+		remember:         q.remember,
+		tx:               q.tx,
+		err:              q.err,
 	}
 }
 
@@ -543,6 +646,12 @@ func (q *Querier[T]) Builder() *query.Builder {
 	return q.builder
 }
 
+// WithContext binds ctx to every statement the querier executes.
+func (q *Querier[T]) WithContext(ctx context.Context) *Querier[T] {
+	q.builder.WithContext(ctx)
+	return q
+}
+
 func copyBoolMap(in map[string]bool) map[string]bool {
 	if in == nil {
 		return nil
@@ -601,6 +710,13 @@ func (q *Querier[T]) SharedLock() *Querier[T] {
 	return q
 }
 
+// UseWriteConnection reads from the primary connection even when a read
+// connection is set (see SetReadConnection).
+func (q *Querier[T]) UseWriteConnection() *Querier[T] {
+	q.builder.UseWriteConnection()
+	return q
+}
+
 // SkipLocked appends SKIP LOCKED to the lock clause.
 func (q *Querier[T]) SkipLocked() *Querier[T] {
 	q.builder.SkipLocked()
@@ -628,7 +744,16 @@ func (q *Querier[T]) OnlyTrashed() *Querier[T] {
 
 // Get returns all matching models.
 func (q *Querier[T]) Get() ([]T, error) {
-	q.prepare()
+	if q.remember != nil {
+		return q.remembered("", q.get)
+	}
+	return q.get()
+}
+
+func (q *Querier[T]) get() ([]T, error) {
+	if err := q.prepare(); err != nil {
+		return nil, err
+	}
 	rows, err := q.builder.Get()
 	if err != nil {
 		return nil, err
@@ -649,7 +774,22 @@ func (q *Querier[T]) Get() ([]T, error) {
 
 // First returns the first matching model.
 func (q *Querier[T]) First() (*T, error) {
-	q.prepare()
+	if q.remember != nil {
+		items, err := q.remembered("first", func() ([]T, error) {
+			q.builder.Limit(1)
+			return q.get()
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			return nil, sql.ErrNoRows
+		}
+		return &items[0], nil
+	}
+	if err := q.prepare(); err != nil {
+		return nil, err
+	}
 	row, err := q.builder.First()
 	if err != nil {
 		return nil, err
@@ -667,7 +807,9 @@ func (q *Querier[T]) First() (*T, error) {
 
 // Sole returns the single matching model or an error if none/multiple match.
 func (q *Querier[T]) Sole() (*T, error) {
-	q.prepare()
+	if err := q.prepare(); err != nil {
+		return nil, err
+	}
 	rows, err := q.builder.Limit(2).Get()
 	if err != nil {
 		return nil, err
@@ -691,37 +833,49 @@ func (q *Querier[T]) Sole() (*T, error) {
 
 // Count returns matching count.
 func (q *Querier[T]) Count() (int64, error) {
-	q.prepare()
+	if err := q.prepare(); err != nil {
+		return 0, err
+	}
 	return q.builder.Count()
 }
 
 // Sum returns the sum of a column.
 func (q *Querier[T]) Sum(column string) (float64, error) {
-	q.prepare()
+	if err := q.prepare(); err != nil {
+		return 0, err
+	}
 	return q.builder.Sum(column)
 }
 
 // Avg returns the average of a column.
 func (q *Querier[T]) Avg(column string) (float64, error) {
-	q.prepare()
+	if err := q.prepare(); err != nil {
+		return 0, err
+	}
 	return q.builder.Avg(column)
 }
 
 // Min returns the minimum of a column.
 func (q *Querier[T]) Min(column string) (any, error) {
-	q.prepare()
+	if err := q.prepare(); err != nil {
+		return nil, err
+	}
 	return q.builder.Min(column)
 }
 
 // Max returns the maximum of a column.
 func (q *Querier[T]) Max(column string) (any, error) {
-	q.prepare()
+	if err := q.prepare(); err != nil {
+		return nil, err
+	}
 	return q.builder.Max(column)
 }
 
 // Exists reports whether any match exists.
 func (q *Querier[T]) Exists() (bool, error) {
-	q.prepare()
+	if err := q.prepare(); err != nil {
+		return false, err
+	}
 	return q.builder.Exists()
 }
 
@@ -734,7 +888,9 @@ func (q *Querier[T]) Paginate(page, perPage int, path ...string) (*pagination.Le
 		perPage = 15
 	}
 
-	q.prepare()
+	if err := q.prepare(); err != nil {
+		return nil, err
+	}
 	total, err := q.builder.Count()
 	if err != nil {
 		return nil, err
@@ -773,7 +929,9 @@ func (q *Querier[T]) SimplePaginate(page, perPage int, path ...string) (*paginat
 	if perPage < 1 {
 		perPage = 15
 	}
-	q.prepare()
+	if err := q.prepare(); err != nil {
+		return nil, err
+	}
 	rows, hasMore, err := q.builder.SimplePaginate(page, perPage)
 	if err != nil {
 		return nil, err
@@ -796,21 +954,59 @@ func (q *Querier[T]) SimplePaginate(page, perPage int, path ...string) (*paginat
 	return pagination.NewSimple(items, page, perPage, basePath, hasMore), nil
 }
 
+// CursorPaginate returns a page of models using keyset pagination on the
+// query's order columns (id when unordered).
+func (q *Querier[T]) CursorPaginate(perPage int, cursor string, path ...string) (*pagination.Cursor[T], error) {
+	if err := q.prepare(); err != nil {
+		return nil, err
+	}
+	rows, next, prev, err := q.builder.CursorPaginate(perPage, cursor)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]T, 0, len(rows))
+	for _, row := range rows {
+		model, err := mapToModel[T](row)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *model)
+	}
+	if err := q.runLoaders(items); err != nil {
+		return nil, err
+	}
+	basePath := ""
+	if len(path) > 0 {
+		basePath = path[0]
+	}
+	return pagination.NewCursor(items, perPage, basePath, next, prev), nil
+}
+
 // Increment increments a numeric column on matching rows.
 func (q *Querier[T]) Increment(column string, amount ...int64) (int64, error) {
 	q.prepare()
-	return q.builder.Increment(column, amount...)
+	n, err := q.builder.Increment(column, amount...)
+	if err == nil {
+		q.flushRemembered()
+	}
+	return n, err
 }
 
 // Decrement decrements a numeric column on matching rows.
 func (q *Querier[T]) Decrement(column string, amount ...int64) (int64, error) {
 	q.prepare()
-	return q.builder.Decrement(column, amount...)
+	n, err := q.builder.Decrement(column, amount...)
+	if err == nil {
+		q.flushRemembered()
+	}
+	return n, err
 }
 
 // Chunk iterates matching models in batches of size.
 func (q *Querier[T]) Chunk(size int, callback func([]T) error) error {
-	q.prepare()
+	if err := q.prepare(); err != nil {
+		return err
+	}
 	return q.builder.Chunk(size, func(rows []map[string]any) error {
 		items := make([]T, 0, len(rows))
 		for _, row := range rows {
@@ -829,7 +1025,9 @@ func (q *Querier[T]) Chunk(size int, callback func([]T) error) error {
 
 // ChunkById iterates models using keyset pagination on id.
 func (q *Querier[T]) ChunkById(size int, callback func([]T) error, column ...string) error {
-	q.prepare()
+	if err := q.prepare(); err != nil {
+		return err
+	}
 	return q.builder.ChunkById(size, func(rows []map[string]any) error {
 		items := make([]T, 0, len(rows))
 		for _, row := range rows {
@@ -846,6 +1044,88 @@ func (q *Querier[T]) ChunkById(size int, callback func([]T) error, column ...str
 	}, column...)
 }
 
+// Cursor streams matching models from a single result set. Eager loaders
+// registered with With run for every lazyChunkSize models, on a separate
+// connection while the result set is still open.
+func (q *Querier[T]) Cursor() iter.Seq2[T, error] {
+	if err := q.prepare(); err != nil {
+		return failed[T](err)
+	}
+	return q.iterate(q.builder.Cursor(), lazyChunkSize)
+}
+
+// Lazy iterates matching models one at a time, fetching chunks of size via
+// keyset pagination on id (or column). Eager loaders run per chunk.
+func (q *Querier[T]) Lazy(size int, column ...string) iter.Seq2[T, error] {
+	if size < 1 {
+		size = lazyChunkSize
+	}
+	if err := q.prepare(); err != nil {
+		return failed[T](err)
+	}
+	return q.iterate(q.builder.Lazy(size, column...), size)
+}
+
+// lazyChunkSize is the default eager-loading batch for Cursor and Lazy.
+const lazyChunkSize = 100
+
+// failed is an iterator that yields err once.
+func failed[T any](err error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		yield(zero, err)
+	}
+}
+
+// iterate maps rows to models, buffering chunk models at a time when eager
+// loaders must run before they are yielded.
+func (q *Querier[T]) iterate(rows iter.Seq2[map[string]any, error], chunk int) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		buffer := make([]T, 0, chunk)
+		flush := func() bool {
+			if err := q.runLoaders(buffer); err != nil {
+				yield(zero, err)
+				return false
+			}
+			for _, item := range buffer {
+				if !yield(item, nil) {
+					return false
+				}
+			}
+			buffer = buffer[:0]
+			return true
+		}
+		for row, err := range rows {
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			model, err := mapToModel[T](row)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if m, ok := any(model).(collectionMember); ok {
+				m.setLoadedInCollection(true)
+			}
+			if !q.hasLoaders() {
+				if !yield(*model, nil) {
+					return
+				}
+				continue
+			}
+			buffer = append(buffer, *model)
+			if len(buffer) >= chunk && !flush() {
+				return
+			}
+		}
+		if len(buffer) > 0 {
+			flush()
+		}
+	}
+}
+
 // InsertMany inserts multiple attribute maps and returns affected rows.
 // Each row goes through mass assignment and fires creating/created when a dispatcher is set.
 func InsertMany[T any](rows []map[string]any) (int64, error) {
@@ -862,31 +1142,50 @@ func InsertMany[T any](rows []map[string]any) (int64, error) {
 		if _, ok := attrs["updated_at"]; !ok {
 			attrs["updated_at"] = now
 		}
+		generateKey[T](attrs, KeyName[T]())
 		draft := attrsToModel[T](attrs)
 		if err := dispatchModel("creating", draft); err != nil {
 			return 0, err
 		}
 		prepared = append(prepared, attrs)
 	}
-	n, err := query.New(DB, Driver, Table[T]()).InsertBatch(prepared)
+	n, err := newBuilder(DB, Table[T]()).InsertBatch(prepared)
 	if err != nil {
 		return 0, err
 	}
+	flushRemembered(Table[T]())
 	for _, attrs := range prepared {
 		_ = dispatchModel("created", attrsToModel[T](attrs))
 	}
 	return n, nil
 }
 
-// Update updates matching rows.
+// Update updates matching rows. On versioned models the version column is
+// incremented, and a version given in attrs must still match the row.
 func (q *Querier[T]) Update(attrs map[string]any) (int64, error) {
+	versionCol := versionColumn[T]()
+	expected, guarded := attrs[versionCol]
 	attrs = filterMassAssignment[T](attrs)
 	if _, ok := attrs["updated_at"]; !ok {
 		now := time.Now()
 		attrs["updated_at"] = now
 	}
+	if versionCol != "" {
+		if guarded {
+			q.builder.Where(versionCol, expected)
+		}
+		attrs[versionCol] = query.Raw(versionCol + " + 1")
+	}
 	q.prepare()
-	return q.builder.Update(attrs)
+	return q.written(q.builder.Update(attrs))
+}
+
+// written invalidates remembered results after a successful write.
+func (q *Querier[T]) written(n int64, err error) (int64, error) {
+	if err == nil {
+		q.flushRemembered()
+	}
+	return n, err
 }
 
 // Delete deletes matching rows (soft delete when available).
@@ -894,17 +1193,17 @@ func (q *Querier[T]) Delete() (int64, error) {
 	if hasSoftDeletes[T]() {
 		now := time.Now()
 		q.prepare()
-		return q.builder.Update(map[string]any{"deleted_at": now, "updated_at": now})
+		return q.written(q.builder.Update(map[string]any{"deleted_at": now, "updated_at": now}))
 	}
 	q.prepare()
-	return q.builder.Delete()
+	return q.written(q.builder.Delete())
 }
 
 // ForceDelete permanently deletes matching rows.
 func (q *Querier[T]) ForceDelete() (int64, error) {
 	q.softDelete = false
 	q.prepare()
-	return q.builder.Delete()
+	return q.written(q.builder.Delete())
 }
 
 // Restore clears deleted_at on soft-deleted rows.
@@ -914,10 +1213,10 @@ func (q *Querier[T]) Restore() (int64, error) {
 	}
 	q.softDelete = false
 	now := time.Now()
-	return q.builder.Update(map[string]any{
+	return q.written(q.builder.Update(map[string]any{
 		"deleted_at": nil,
 		"updated_at": now,
-	})
+	}))
 }
 
 // SoftDelete soft-deletes a model by id.
@@ -927,11 +1226,11 @@ func SoftDelete[T any](id any) (int64, error) {
 
 // RestoreByID restores a soft-deleted model by id.
 func RestoreByID[T any](id any) (int64, error) {
-	model, _ := Query[T]().OnlyTrashed().Where(KeyName[T](), id).First()
+	model, _ := Query[T]().OnlyTrashed().WhereKey(id).First()
 	if model != nil {
 		_ = dispatchModel("restoring", model)
 	}
-	n, err := Query[T]().OnlyTrashed().Where(KeyName[T](), id).Restore()
+	n, err := Query[T]().OnlyTrashed().WhereKey(id).Restore()
 	if err != nil {
 		return 0, err
 	}
@@ -943,13 +1242,13 @@ func RestoreByID[T any](id any) (int64, error) {
 
 // ForceDeleteByID permanently deletes a model by id (including trashed).
 func ForceDeleteByID[T any](id any) (int64, error) {
-	model, _ := Query[T]().WithTrashed().Where(KeyName[T](), id).First()
+	model, _ := Query[T]().WithTrashed().WhereKey(id).First()
 	if model != nil {
 		if err := dispatchModel("deleting", model); err != nil {
 			return 0, err
 		}
 	}
-	n, err := Query[T]().WithTrashed().Where(KeyName[T](), id).ForceDelete()
+	n, err := Query[T]().WithTrashed().WhereKey(id).ForceDelete()
 	if err != nil {
 		return 0, err
 	}
@@ -998,23 +1297,69 @@ func Trashed(model any) bool {
 
 // Save inserts or updates a model pointer.
 func Save[T any](model *T) error {
+	return SaveContext(context.Background(), model)
+}
+
+// SaveContext inserts or updates a model pointer using ctx.
+func SaveContext[T any](ctx context.Context, model *T) error {
 	rv := reflect.ValueOf(model).Elem()
 	attrs := filterMassAssignment[T](modelToMap(rv))
 	keyName := KeyName[T]()
+	keyNames := KeyNames[T]()
 	keyVal, keyErr := KeyValue(model)
 	now := time.Now()
 
-	if keyErr == nil && keyVal != nil && !isZeroAny(keyVal) {
-		delete(attrs, keyName)
+	versionCol := versionColumn[T]()
+
+	exists := keyErr == nil && keyVal != nil && !isZeroAny(keyVal)
+	_, generated := any(model).(KeyGenerator)
+	if exists && (len(keyNames) > 1 || generated) {
+		// Composite keys and UUID/ULID keys may be assigned by the caller,
+		// so only the table can tell an update from an insert. Ask the
+		// primary: a replica may not have seen the row yet.
+		found, err := Query[T]().WithContext(ctx).UseWriteConnection().WithTrashed().WhereKey(keyVal).Exists()
+		if err != nil {
+			return err
+		}
+		exists = found
+	}
+
+	if exists {
+		keys, err := keyValues(keyNames, keyVal)
+		if err != nil {
+			return err
+		}
+		builder := newBuilder(DB, Table[T]()).WithContext(ctx)
+		for i, key := range keyNames {
+			delete(attrs, key)
+			builder.Where(key, keys[i])
+		}
 		attrs["updated_at"] = now
 		before := snapshotOriginal(model)
 		if err := dispatchModel("updating", model); err != nil {
 			return err
 		}
-		_, err := query.New(DB, Driver, Table[T]()).Where(keyName, keyVal).Update(attrs)
+		var version int64
+		if versionCol != "" {
+			loaded, err := loadedVersion(model, versionCol, keyVal)
+			if err != nil {
+				return err
+			}
+			version = loaded + 1
+			builder.Where(versionCol, loaded)
+			attrs[versionCol] = version
+		}
+		n, err := builder.Update(attrs)
 		if err != nil {
 			return err
 		}
+		flushRemembered(Table[T]())
+		if versionCol != "" {
+			if n == 0 {
+				return fmt.Errorf("%w: [%s] %v is no longer at version %d", ErrStaleModel, Table[T](), keyVal, version-1)
+			}
+			setKeyField(rv, versionCol, version)
+		}
 		setTimeField(rv, "UpdatedAt", now)
 		recordChanges(model, before)
 		SyncOriginal(model)
@@ -1024,19 +1369,27 @@ func Save[T any](model *T) error {
 
 	attrs["created_at"] = now
 	attrs["updated_at"] = now
-	delete(attrs, keyName)
+	if len(keyNames) == 1 && !generated {
+		delete(attrs, keyName)
+	}
+	if generateKey[T](attrs, keyName) {
+		setKeyField(rv, keyName, attrs[keyName])
+	}
+	if versionCol != "" && isZeroAny(attrs[versionCol]) {
+		attrs[versionCol] = int64(1)
+		setKeyField(rv, versionCol, int64(1))
+	}
 	if err := dispatchModel("creating", model); err != nil {
 		return err
 	}
-	id, err := query.New(DB, Driver, Table[T]()).Insert(attrs)
+	id, err := newBuilder(DB, Table[T]()).WithContext(ctx).Insert(attrs)
 	if err != nil {
 		return err
 	}
+	flushRemembered(Table[T]())
 	idField, _ := fieldValueByColumn(rv, keyName)
-	if idField.IsValid() && idField.CanSet() && id > 0 && keyName == "id" {
+	if _, assigned := attrs[keyName]; !assigned && idField.IsValid() && idField.CanSet() && id > 0 && keyName == "id" {
 		_ = setField(idField, id)
-	} else if v, ok := attrs[keyName]; ok {
-		setKeyField(rv, keyName, v)
 	}
 	setTimeField(rv, "CreatedAt", now)
 	setTimeField(rv, "UpdatedAt", now)
@@ -1072,7 +1425,7 @@ func Destroy[T any](id any) (int64, error) {
 			return 0, err
 		}
 	}
-	n, err := Query[T]().Where(KeyName[T](), id).Delete()
+	n, err := Query[T]().WhereKey(id).Delete()
 	if err != nil {
 		return 0, err
 	}
@@ -1170,7 +1523,10 @@ func collectAttrs(rv reflect.Value, out map[string]any) {
 			collectAttrs(fv, out)
 			continue
 		}
-		if tag := field.Tag.Get("db"); tag == "-" {
+		if !field.IsExported() {
+			continue
+		}
+		if tag := field.Tag.Get("db"); tag == "-" || readOnly(field) {
 			continue
 		}
 		column := columnName(field)
@@ -1202,6 +1558,13 @@ func columnName(field reflect.StructField) string {
 	return toSnake(field.Name)
 }
 
+// readOnly reports whether a field is tagged db:"name,readonly": it is
+// hydrated from query results (e.g. WithCount aliases) but never written.
+func readOnly(field reflect.StructField) bool {
+	_, opts, _ := strings.Cut(field.Tag.Get("db"), ",")
+	return strings.Contains(","+opts+",", ",readonly,")
+}
+
 func setField(fv reflect.Value, value any) error {
 	if !fv.CanSet() {
 		return nil
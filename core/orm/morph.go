@@ -1,13 +1,16 @@
 package orm
 
 import (
+	"context"
 	"fmt"
+	"reflect"
 	"sync"
 )
 
 var (
-	morphMu  sync.RWMutex
-	morphMap = map[string]func(id any) (any, error){}
+	morphMu    sync.RWMutex
+	morphMap   = map[string]func(id any) (any, error){}
+	morphTypes = map[reflect.Type]string{}
 )
 
 // RegisterMorph registers a morph type name to a finder used by MorphTo.
@@ -17,16 +20,52 @@ func RegisterMorph(typeName string, finder func(id any) (any, error)) {
 	morphMap[typeName] = finder
 }
 
+// RegisterMorphModel registers T under typeName: MorphTo finds it with
+// Find[T], and MorphType[T] (used by the morph pivot relations) returns typeName.
+func RegisterMorphModel[T any](typeName string) {
+	RegisterMorph(typeName, func(id any) (any, error) {
+		return Find[T](id)
+	})
+	morphMu.Lock()
+	defer morphMu.Unlock()
+	morphTypes[reflect.TypeOf((*T)(nil)).Elem()] = typeName
+}
+
+// MorphType returns the morph type stored for T: the name given to
+// RegisterMorphModel, or the table name when T is not registered.
+func MorphType[T any]() string {
+	morphMu.RLock()
+	name, ok := morphTypes[reflect.TypeOf((*T)(nil)).Elem()]
+	morphMu.RUnlock()
+	if ok {
+		return name
+	}
+	return Table[T]()
+}
+
 // MorphMany returns polymorphic related models for a parent.
 func MorphMany[Parent, Related any](
 	parent *Parent,
 	morphTypeCol, morphIDCol, typeValue string,
 ) ([]Related, error) {
-	parentKey, err := KeyValue(parent)
+	return MorphManyContext[Parent, Related](context.Background(), parent, morphTypeCol, morphIDCol, typeValue)
+}
+
+// MorphManyContext is MorphMany using ctx.
+func MorphManyContext[Parent, Related any](
+	ctx context.Context,
+	parent *Parent,
+	morphTypeCol, morphIDCol, typeValue string,
+) ([]Related, error) {
+	if err := guardLazyLoad(parent, typeName[Related]()); err != nil {
+		return nil, err
+	}
+	parentKey, err := singleKeyValue(parent)
 	if err != nil {
 		return nil, err
 	}
 	return Query[Related]().
+		WithContext(ctx).
 		Where(morphTypeCol, typeValue).
 		Where(morphIDCol, parentKey).
 		Get()
@@ -37,7 +76,16 @@ func MorphOne[Parent, Related any](
 	parent *Parent,
 	morphTypeCol, morphIDCol, typeValue string,
 ) (*Related, error) {
-	items, err := MorphMany[Parent, Related](parent, morphTypeCol, morphIDCol, typeValue)
+	return MorphOneContext[Parent, Related](context.Background(), parent, morphTypeCol, morphIDCol, typeValue)
+}
+
+// MorphOneContext is MorphOne using ctx.
+func MorphOneContext[Parent, Related any](
+	ctx context.Context,
+	parent *Parent,
+	morphTypeCol, morphIDCol, typeValue string,
+) (*Related, error) {
+	items, err := MorphManyContext[Parent, Related](ctx, parent, morphTypeCol, morphIDCol, typeValue)
 	if err != nil {
 		return nil, err
 	}
@@ -52,6 +100,18 @@ func MorphToByTable[Child, Parent any](
 	child *Child,
 	morphTypeCol, morphIDCol, expectedType string,
 ) (*Parent, error) {
+	return MorphToByTableContext[Child, Parent](context.Background(), child, morphTypeCol, morphIDCol, expectedType)
+}
+
+// MorphToByTableContext is MorphToByTable using ctx.
+func MorphToByTableContext[Child, Parent any](
+	ctx context.Context,
+	child *Child,
+	morphTypeCol, morphIDCol, expectedType string,
+) (*Parent, error) {
+	if err := guardLazyLoad(child, typeName[Parent]()); err != nil {
+		return nil, err
+	}
 	morphType, err := attribute(child, morphTypeCol)
 	if err != nil {
 		return nil, err
@@ -66,11 +126,15 @@ func MorphToByTable[Child, Parent any](
 	if morphID == nil {
 		return nil, nil
 	}
-	return Find[Parent](morphID)
+	return FindContext[Parent](ctx, morphID)
 }
 
-// MorphTo resolves a morph parent using RegisterMorph.
+// MorphTo resolves a morph parent using RegisterMorph. The finder runs its
+// own query, so it is not bound to a request context.
 func MorphTo[Child any](child *Child, morphTypeCol, morphIDCol string) (any, error) {
+	if err := guardLazyLoad(child, morphTypeCol); err != nil {
+		return nil, err
+	}
 	morphType, err := attribute(child, morphTypeCol)
 	if err != nil {
 		return nil, err
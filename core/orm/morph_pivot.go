@@ -0,0 +1,134 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/zatrano/framework/core/database/query"
+)
+
+// MorphToMany returns the models attached to parent through a polymorphic
+// pivot. For a taggables pivot (tag_id, taggable_type, taggable_id):
+//
+//	tags, err := orm.MorphToMany[Post, Tag](post, "taggables", "taggable", "tag_id")
+//
+// The stored type is MorphType[Parent]().
+func MorphToMany[Parent, Related any](parent *Parent, pivotTable, morphName, relatedPivotKey string) ([]Related, error) {
+	return MorphToManyContext[Parent, Related](context.Background(), parent, pivotTable, morphName, relatedPivotKey)
+}
+
+// MorphToManyContext is MorphToMany using ctx.
+func MorphToManyContext[Parent, Related any](ctx context.Context, parent *Parent, pivotTable, morphName, relatedPivotKey string) ([]Related, error) {
+	if err := guardLazyLoad(parent, typeName[Related]()); err != nil {
+		return nil, err
+	}
+	idColumn, scope := morphPivot[Parent](morphName)
+	parentID, err := singleKeyValue(parent)
+	if err != nil {
+		return nil, err
+	}
+	return pivotRelated[Related](ctx, pivotQuery(DB, pivotTable, scope).Where(idColumn, parentID), relatedPivotKey)
+}
+
+// MorphedByMany is the inverse of MorphToMany: the Related models of one morph
+// type attached to owner.
+//
+//	posts, err := orm.MorphedByMany[Tag, Post](tag, "taggables", "taggable", "tag_id")
+func MorphedByMany[Owner, Related any](owner *Owner, pivotTable, morphName, foreignPivotKey string) ([]Related, error) {
+	return MorphedByManyContext[Owner, Related](context.Background(), owner, pivotTable, morphName, foreignPivotKey)
+}
+
+// MorphedByManyContext is MorphedByMany using ctx.
+func MorphedByManyContext[Owner, Related any](ctx context.Context, owner *Owner, pivotTable, morphName, foreignPivotKey string) ([]Related, error) {
+	if err := guardLazyLoad(owner, typeName[Related]()); err != nil {
+		return nil, err
+	}
+	idColumn, scope := morphPivot[Related](morphName)
+	ownerID, err := singleKeyValue(owner)
+	if err != nil {
+		return nil, err
+	}
+	return pivotRelated[Related](ctx, pivotQuery(DB, pivotTable, scope).Where(foreignPivotKey, ownerID), idColumn)
+}
+
+// LoadMorphToMany batch-loads a morph-to-many relation onto parents.
+func LoadMorphToMany[Parent, Related any](parents *[]Parent, field, pivotTable, morphName, relatedPivotKey string) error {
+	return loadMorphToMany[Parent, Related](parents, field, pivotTable, morphName, relatedPivotKey, nil)
+}
+
+func loadMorphToMany[Parent, Related any](parents *[]Parent, field, pivotTable, morphName, relatedPivotKey string, constrain func(*Querier[Related])) error {
+	idColumn, scope := morphPivot[Parent](morphName)
+	return loadPivot(parents, field, pivotTable, idColumn, relatedPivotKey, scope, constrain)
+}
+
+// EagerMorphToMany returns a With() loader for morph-to-many.
+func EagerMorphToMany[Parent, Related any](field, pivotTable, morphName, relatedPivotKey string) func([]Parent) error {
+	return func(parents []Parent) error {
+		return LoadMorphToMany[Parent, Related](&parents, field, pivotTable, morphName, relatedPivotKey)
+	}
+}
+
+// LoadMorphedByMany batch-loads a morphed-by-many relation onto owners.
+func LoadMorphedByMany[Owner, Related any](owners *[]Owner, field, pivotTable, morphName, foreignPivotKey string) error {
+	return loadMorphedByMany[Owner, Related](owners, field, pivotTable, morphName, foreignPivotKey, nil)
+}
+
+func loadMorphedByMany[Owner, Related any](owners *[]Owner, field, pivotTable, morphName, foreignPivotKey string, constrain func(*Querier[Related])) error {
+	idColumn, scope := morphPivot[Related](morphName)
+	return loadPivot(owners, field, pivotTable, foreignPivotKey, idColumn, scope, constrain)
+}
+
+// EagerMorphedByMany returns a With() loader for morphed-by-many.
+func EagerMorphedByMany[Owner, Related any](field, pivotTable, morphName, foreignPivotKey string) func([]Owner) error {
+	return func(owners []Owner) error {
+		return LoadMorphedByMany[Owner, Related](&owners, field, pivotTable, morphName, foreignPivotKey)
+	}
+}
+
+// MorphAttach inserts morph pivot rows linking parent to related ids,
+// writing <morphName>_type and <morphName>_id.
+func MorphAttach[Parent any](parent *Parent, pivotTable, morphName, relatedPivotKey string, relatedIDs []any, extra ...map[string]any) error {
+	idColumn, scope := morphPivot[Parent](morphName)
+	return attachOn(DB, parent, pivotTable, idColumn, relatedPivotKey, scope, relatedIDs, extra...)
+}
+
+// MorphDetach removes parent's morph pivot rows for the given related ids
+// (or all when ids empty). Rows of other morph types are left alone.
+func MorphDetach[Parent any](parent *Parent, pivotTable, morphName, relatedPivotKey string, relatedIDs ...any) (int64, error) {
+	idColumn, scope := morphPivot[Parent](morphName)
+	return detachOn(DB, parent, pivotTable, idColumn, relatedPivotKey, scope, relatedIDs...)
+}
+
+// MorphSync makes relatedIDs the exact set attached to parent inside a transaction.
+func MorphSync[Parent any](parent *Parent, pivotTable, morphName, relatedPivotKey string, relatedIDs []any, extra ...map[string]any) error {
+	idColumn, scope := morphPivot[Parent](morphName)
+	return Transaction(func(tx *sql.Tx) error {
+		return syncOn(tx, parent, pivotTable, idColumn, relatedPivotKey, scope, relatedIDs, false, extra...)
+	})
+}
+
+// morphPivot returns the id column of a morph pivot and the type column
+// scope for Model.
+func morphPivot[Model any](morphName string) (string, map[string]any) {
+	return morphName + "_id", map[string]any{morphName + "_type": MorphType[Model]()}
+}
+
+// pivotRelated loads the Related models referenced by the pivot rows.
+func pivotRelated[Related any](ctx context.Context, pivot *query.Builder, relatedPivotKey string) ([]Related, error) {
+	relatedKey, err := singleKeyName[Related]()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := pivot.WithContext(ctx).Get()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []Related{}, nil
+	}
+	ids := make([]any, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row[relatedPivotKey])
+	}
+	return Query[Related]().WithContext(ctx).WhereIn(relatedKey, ids).Get()
+}
@@ -0,0 +1,159 @@
+package orm_test
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/orm"
+)
+
+type taggedPost struct {
+	orm.Model
+	Title string      `db:"title"`
+	Tags  []taggedTag `db:"-"`
+}
+
+func (taggedPost) TableName() string { return "tagged_posts" }
+
+func (taggedPost) Relations() orm.Relations {
+	return orm.Relations{
+		"Tags": orm.MorphToManyRelation[taggedPost, taggedTag]("taggables", "taggable", "tag_id"),
+	}
+}
+
+type taggedVideo struct {
+	orm.Model
+	Title string `db:"title"`
+}
+
+func (taggedVideo) TableName() string { return "tagged_videos" }
+
+type taggedTag struct {
+	orm.Model
+	Name       string        `db:"name"`
+	Posts      []taggedPost  `db:"-"`
+	Videos     []taggedVideo `db:"-"`
+	PostsCount int64         `db:"posts_count,readonly"`
+}
+
+func (taggedTag) TableName() string { return "tagged_tags" }
+
+func (taggedTag) Relations() orm.Relations {
+	return orm.Relations{
+		"Posts":  orm.MorphedByManyRelation[taggedTag, taggedPost]("taggables", "taggable", "tag_id"),
+		"Videos": orm.MorphedByManyRelation[taggedTag, taggedVideo]("taggables", "taggable", "tag_id"),
+	}
+}
+
+func setupTaggableDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sqlStr := range []string{
+		`CREATE TABLE tagged_posts (id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE tagged_videos (id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE tagged_tags (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE taggables (tag_id INTEGER, taggable_type TEXT, taggable_id INTEGER)`,
+	} {
+		if _, err := db.Exec(sqlStr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orm.Configure(db, "sqlite")
+	orm.RegisterMorphModel[taggedPost]("post")
+	return db
+}
+
+func TestMorphToManyAttachSyncDetach(t *testing.T) {
+	db := setupTaggableDB(t)
+	defer db.Close()
+
+	if orm.MorphType[taggedPost]() != "post" || orm.MorphType[taggedVideo]() != "tagged_videos" {
+		t.Fatalf("morph types=%s %s", orm.MorphType[taggedPost](), orm.MorphType[taggedVideo]())
+	}
+
+	post, _ := orm.Create[taggedPost](map[string]any{"title": "post"})
+	video, _ := orm.Create[taggedVideo](map[string]any{"title": "video"})
+	goTag, _ := orm.Create[taggedTag](map[string]any{"name": "go"})
+	sqlTag, _ := orm.Create[taggedTag](map[string]any{"name": "sql"})
+
+	if err := orm.MorphAttach(post, "taggables", "taggable", "tag_id", []any{goTag.ID, sqlTag.ID}); err != nil {
+		t.Fatal(err)
+	}
+	// Same id as the post on purpose: only the type tells them apart.
+	if err := orm.MorphAttach(video, "taggables", "taggable", "tag_id", []any{goTag.ID}); err != nil {
+		t.Fatal(err)
+	}
+	var stored string
+	if err := db.QueryRow(`SELECT taggable_type FROM taggables WHERE tag_id = ? AND taggable_id = ? ORDER BY taggable_type LIMIT 1`, sqlTag.ID, post.ID).Scan(&stored); err != nil || stored != "post" {
+		t.Fatalf("stored type=%q err=%v", stored, err)
+	}
+
+	tags, err := orm.MorphToMany[taggedPost, taggedTag](post, "taggables", "taggable", "tag_id")
+	if err != nil || len(tags) != 2 {
+		t.Fatalf("post tags=%+v err=%v", tags, err)
+	}
+	posts, err := orm.MorphedByMany[taggedTag, taggedPost](goTag, "taggables", "taggable", "tag_id")
+	if err != nil || len(posts) != 1 || posts[0].Title != "post" {
+		t.Fatalf("tag posts=%+v err=%v", posts, err)
+	}
+
+	if err := orm.MorphSync(post, "taggables", "taggable", "tag_id", []any{sqlTag.ID}); err != nil {
+		t.Fatal(err)
+	}
+	videos, err := orm.MorphedByMany[taggedTag, taggedVideo](goTag, "taggables", "taggable", "tag_id")
+	if err != nil || len(videos) != 1 {
+		t.Fatalf("sync touched other morph types: videos=%+v err=%v", videos, err)
+	}
+	if tags, _ := orm.MorphToMany[taggedPost, taggedTag](post, "taggables", "taggable", "tag_id"); len(tags) != 1 || tags[0].Name != "sql" {
+		t.Fatalf("synced tags=%+v", tags)
+	}
+
+	if n, err := orm.MorphDetach(post, "taggables", "taggable", "tag_id"); err != nil || n != 1 {
+		t.Fatalf("detach n=%d err=%v", n, err)
+	}
+	if n, _ := orm.MorphDetach(video, "taggables", "taggable", "tag_id", goTag.ID); n != 1 {
+		t.Fatalf("video detach n=%d", n)
+	}
+}
+
+func TestMorphToManyEagerLoading(t *testing.T) {
+	db := setupTaggableDB(t)
+	defer db.Close()
+
+	first, _ := orm.Create[taggedPost](map[string]any{"title": "first"})
+	second, _ := orm.Create[taggedPost](map[string]any{"title": "second"})
+	video, _ := orm.Create[taggedVideo](map[string]any{"title": "video"})
+	goTag, _ := orm.Create[taggedTag](map[string]any{"name": "go"})
+	sqlTag, _ := orm.Create[taggedTag](map[string]any{"name": "sql"})
+	_ = orm.MorphAttach(first, "taggables", "taggable", "tag_id", []any{goTag.ID, sqlTag.ID})
+	_ = orm.MorphAttach(second, "taggables", "taggable", "tag_id", []any{sqlTag.ID})
+	_ = orm.MorphAttach(video, "taggables", "taggable", "tag_id", []any{goTag.ID})
+
+	posts, err := orm.Query[taggedPost]().
+		With(orm.EagerMorphToMany[taggedPost, taggedTag]("Tags", "taggables", "taggable", "tag_id")).
+		OrderBy("id").
+		Get()
+	if err != nil || len(posts[0].Tags) != 2 || len(posts[1].Tags) != 1 {
+		t.Fatalf("eager posts=%+v err=%v", posts, err)
+	}
+
+	tags, err := orm.Query[taggedTag]().
+		WithRelations("Posts", "Videos").
+		WithCount("Posts").
+		OrderBy("id").
+		Get()
+	if err != nil || len(tags) != 2 {
+		t.Fatalf("tags=%+v err=%v", tags, err)
+	}
+	if len(tags[0].Posts) != 1 || len(tags[0].Videos) != 1 || tags[0].PostsCount != 1 {
+		t.Fatalf("go tag=%+v", tags[0])
+	}
+	if len(tags[1].Posts) != 2 || len(tags[1].Videos) != 0 || tags[1].PostsCount != 2 {
+		t.Fatalf("sql tag=%+v", tags[1])
+	}
+}
@@ -3,6 +3,7 @@ package orm
 import (
 	"database/sql"
 	"fmt"
+	"sort"
 
 	"github.com/zatrano/framework/core/database/query"
 )
@@ -17,7 +18,13 @@ func AttachOn[Parent any](db query.DBTX, parent *Parent, pivotTable, foreignPivo
 	if db == nil {
 		db = DB
 	}
-	parentID, err := KeyValue(parent)
+	return attachOn(db, parent, pivotTable, foreignPivotKey, relatedPivotKey, nil, relatedIDs, extra...)
+}
+
+// attachOn inserts pivot rows; scope columns (such as a morph type) are
+// written on every row.
+func attachOn[Parent any](db query.DBTX, parent *Parent, pivotTable, foreignPivotKey, relatedPivotKey string, scope map[string]any, relatedIDs []any, extra ...map[string]any) error {
+	parentID, err := singleKeyValue(parent)
 	if err != nil {
 		return err
 	}
@@ -36,7 +43,10 @@ func AttachOn[Parent any](db query.DBTX, parent *Parent, pivotTable, foreignPivo
 		for k, v := range extras {
 			attrs[k] = v
 		}
-		if _, err := query.New(db, Driver, pivotTable).Insert(attrs); err != nil {
+		for k, v := range scope {
+			attrs[k] = v
+		}
+		if _, err := newBuilder(db, pivotTable).Insert(attrs); err != nil {
 			return err
 		}
 	}
@@ -53,11 +63,16 @@ func DetachOn[Parent any](db query.DBTX, parent *Parent, pivotTable, foreignPivo
 	if db == nil {
 		db = DB
 	}
-	parentID, err := KeyValue(parent)
+	return detachOn(db, parent, pivotTable, foreignPivotKey, relatedPivotKey, nil, relatedIDs...)
+}
+
+// detachOn removes pivot rows matching scope.
+func detachOn[Parent any](db query.DBTX, parent *Parent, pivotTable, foreignPivotKey, relatedPivotKey string, scope map[string]any, relatedIDs ...any) (int64, error) {
+	parentID, err := singleKeyValue(parent)
 	if err != nil {
 		return 0, err
 	}
-	q := query.New(db, Driver, pivotTable).Where(foreignPivotKey, parentID)
+	q := pivotQuery(db, pivotTable, scope).Where(foreignPivotKey, parentID)
 	if len(relatedIDs) > 0 {
 		q.WhereIn(relatedPivotKey, relatedIDs)
 	}
@@ -68,25 +83,25 @@ func DetachOn[Parent any](db query.DBTX, parent *Parent, pivotTable, foreignPivo
 // Optional extra attributes are applied to newly attached pivot rows.
 func Sync[Parent any](parent *Parent, pivotTable, foreignPivotKey, relatedPivotKey string, relatedIDs []any, extra ...map[string]any) error {
 	return Transaction(func(tx *sql.Tx) error {
-		return syncOn(tx, parent, pivotTable, foreignPivotKey, relatedPivotKey, relatedIDs, false, extra...)
+		return syncOn(tx, parent, pivotTable, foreignPivotKey, relatedPivotKey, nil, relatedIDs, false, extra...)
 	})
 }
 
 // SyncWithoutDetaching attaches missing related ids without removing existing ones.
 func SyncWithoutDetaching[Parent any](parent *Parent, pivotTable, foreignPivotKey, relatedPivotKey string, relatedIDs []any, extra ...map[string]any) error {
 	return Transaction(func(tx *sql.Tx) error {
-		return syncOn(tx, parent, pivotTable, foreignPivotKey, relatedPivotKey, relatedIDs, true, extra...)
+		return syncOn(tx, parent, pivotTable, foreignPivotKey, relatedPivotKey, nil, relatedIDs, true, extra...)
 	})
 }
 
 // Toggle attaches missing ids and detaches existing ones for the given related ids.
 func Toggle[Parent any](parent *Parent, pivotTable, foreignPivotKey, relatedPivotKey string, relatedIDs []any, extra ...map[string]any) error {
 	return Transaction(func(tx *sql.Tx) error {
-		parentID, err := KeyValue(parent)
+		parentID, err := singleKeyValue(parent)
 		if err != nil {
 			return err
 		}
-		rows, err := query.New(tx, Driver, pivotTable).Where(foreignPivotKey, parentID).Get()
+		rows, err := newBuilder(tx, pivotTable).Where(foreignPivotKey, parentID).Get()
 		if err != nil {
 			return err
 		}
@@ -153,7 +168,7 @@ func applyRelationExists[Parent, Related any](q *Querier[Parent], not bool, fore
 	parentTable := q.table
 
 	subQ := &Querier[Related]{
-		builder:    query.New(DB, Driver, relatedTable),
+		builder:    newBuilder(DB, relatedTable),
 		table:      relatedTable,
 		softDelete: hasSoftDeletes[Related](),
 	}
@@ -177,15 +192,16 @@ func syncOn[Parent any](
 	db query.DBTX,
 	parent *Parent,
 	pivotTable, foreignPivotKey, relatedPivotKey string,
+	scope map[string]any,
 	relatedIDs []any,
 	withoutDetaching bool,
 	extra ...map[string]any,
 ) error {
-	parentID, err := KeyValue(parent)
+	parentID, err := singleKeyValue(parent)
 	if err != nil {
 		return err
 	}
-	rows, err := query.New(db, Driver, pivotTable).Where(foreignPivotKey, parentID).Get()
+	rows, err := pivotQuery(db, pivotTable, scope).Where(foreignPivotKey, parentID).Get()
 	if err != nil {
 		return err
 	}
@@ -206,7 +222,7 @@ func syncOn[Parent any](
 			}
 		}
 		if len(detachIDs) > 0 {
-			if _, err := DetachOn(db, parent, pivotTable, foreignPivotKey, relatedPivotKey, detachIDs...); err != nil {
+			if _, err := detachOn(db, parent, pivotTable, foreignPivotKey, relatedPivotKey, scope, detachIDs...); err != nil {
 				return err
 			}
 		}
@@ -218,7 +234,21 @@ func syncOn[Parent any](
 		}
 	}
 	if len(attachIDs) > 0 {
-		return AttachOn(db, parent, pivotTable, foreignPivotKey, relatedPivotKey, attachIDs, extra...)
+		return attachOn(db, parent, pivotTable, foreignPivotKey, relatedPivotKey, scope, attachIDs, extra...)
 	}
 	return nil
 }
+
+// pivotQuery starts a query on pivotTable limited to the scope columns.
+func pivotQuery(db query.DBTX, pivotTable string, scope map[string]any) *query.Builder {
+	q := newBuilder(db, pivotTable)
+	columns := make([]string, 0, len(scope))
+	for column := range scope {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	for _, column := range columns {
+		q.Where(column, scope[column])
+	}
+	return q
+}
@@ -0,0 +1,221 @@
+package orm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/zatrano/framework/core/schedule"
+)
+
+// DefaultPruneChunk is the number of models deleted per query by PruneModel
+// when no chunk size is given.
+const DefaultPruneChunk = 1000
+
+// Prunable is implemented by models whose stale rows are deleted by
+// model:prune. Matching models are loaded in chunks and force-deleted one at
+// a time, firing the "pruning", "deleting" and "forceDeleted" model events
+// and calling Pruning when the model implements PruningHook:
+//
+//	func (Post) Prunable() *orm.Querier[Post] {
+//		return orm.Query[Post]().OnlyTrashed().Where("deleted_at", "<", time.Now().AddDate(0, -1, 0))
+//	}
+type Prunable[T any] interface {
+	Prunable() *Querier[T]
+}
+
+// MassPrunable is the bulk variant of Prunable: matching rows are deleted a
+// chunk per query without being loaded, so no per-model events or hooks run.
+type MassPrunable[T any] interface {
+	MassPrunable() *Querier[T]
+}
+
+// PruningHook lets a Prunable model clean up (files, related rows) before it
+// is deleted. An error stops pruning.
+type PruningHook interface {
+	Pruning() error
+}
+
+// Pruned is dispatched as "<model>.pruned" after a model has been pruned.
+type Pruned struct {
+	Model string
+	Count int64
+}
+
+// PrunableModel is a model registered with RegisterPrunable.
+type PrunableModel struct {
+	Name    string
+	prune   func(chunk int) (int64, error)
+	pretend func() (int64, error)
+}
+
+// Prune deletes the model's prunable rows, chunk at a time.
+func (m PrunableModel) Prune(chunk int) (int64, error) {
+	return m.prune(chunk)
+}
+
+// Pretend counts the rows Prune would delete.
+func (m PrunableModel) Pretend() (int64, error) {
+	return m.pretend()
+}
+
+var (
+	prunableMu     sync.RWMutex
+	prunableModels = map[string]PrunableModel{}
+)
+
+// RegisterPrunable adds T, which must implement Prunable or MassPrunable, to
+// the models pruned by model:prune and PruneAll.
+func RegisterPrunable[T any]() {
+	name := typeName[T]()
+	prunableMu.Lock()
+	defer prunableMu.Unlock()
+	prunableModels[name] = PrunableModel{
+		Name:  name,
+		prune: func(chunk int) (int64, error) { return PruneModel[T](chunk) },
+		pretend: func() (int64, error) {
+			q, _, err := prunableQuery[T]()
+			if err != nil {
+				return 0, err
+			}
+			return q.Count()
+		},
+	}
+}
+
+// PrunableModels returns the registered prunable models sorted by name.
+func PrunableModels() []PrunableModel {
+	prunableMu.RLock()
+	models := make([]PrunableModel, 0, len(prunableModels))
+	for _, model := range prunableModels {
+		models = append(models, model)
+	}
+	prunableMu.RUnlock()
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+	return models
+}
+
+// PruneAll prunes every registered model, or only those named (as
+// "models.Post" or "Post"), and returns the rows deleted per model. Schedule
+// it with SchedulePrune.
+func PruneAll(chunk int, names ...string) (map[string]int64, error) {
+	pruned := map[string]int64{}
+	for _, model := range PrunableModels() {
+		if !PrunableNameMatches(model.Name, names) {
+			continue
+		}
+		n, err := model.Prune(chunk)
+		pruned[model.Name] = n
+		if err != nil {
+			return pruned, fmt.Errorf("orm: prune [%s]: %w", model.Name, err)
+		}
+	}
+	return pruned, nil
+}
+
+// SchedulePrune registers PruneAll for the named models (every registered
+// model without names) on s as the daily "model:prune" event. Chain another
+// frequency on the returned event to change it.
+func SchedulePrune(s *schedule.Scheduler, names ...string) *schedule.Event {
+	return s.Command("model:prune", func() error {
+		_, err := PruneAll(0, names...)
+		return err
+	}).Daily()
+}
+
+// PrunableNameMatches reports whether a registered model name is selected by
+// names; an empty list selects every model.
+func PrunableNameMatches(name string, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	short := name[strings.LastIndex(name, ".")+1:]
+	for _, want := range names {
+		if strings.EqualFold(want, name) || strings.EqualFold(want, short) {
+			return true
+		}
+	}
+	return false
+}
+
+// PruneModel deletes T's prunable rows, chunk rows per query, and returns the
+// number deleted.
+func PruneModel[T any](chunk int) (int64, error) {
+	if chunk < 1 {
+		chunk = DefaultPruneChunk
+	}
+	q, mass, err := prunableQuery[T]()
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	if mass {
+		total, err = massPrune[T](q, chunk)
+	} else {
+		err = q.ChunkById(chunk, func(items []T) error {
+			for i := range items {
+				model := &items[i]
+				// Each model is pruned on its own, so its hook may load
+				// relations lazily even when lazy loading is prevented.
+				if m, ok := any(model).(collectionMember); ok {
+					m.setLoadedInCollection(false)
+				}
+				if err := dispatchModel("pruning", model); err != nil {
+					return err
+				}
+				if hook, ok := any(model).(PruningHook); ok {
+					if err := hook.Pruning(); err != nil {
+						return err
+					}
+				}
+				n, err := ForceDeleteModel(model)
+				if err != nil {
+					return err
+				}
+				total += n
+			}
+			return nil
+		}, KeyName[T]())
+	}
+	if total > 0 {
+		if d := Dispatcher(); d != nil {
+			if subject := eventSubject(new(T)); subject != "" {
+				_ = d.Dispatch(subject+".pruned", Pruned{Model: typeName[T](), Count: total})
+			}
+		}
+	}
+	return total, err
+}
+
+// massPrune deletes matching rows by key, chunk keys at a time, since not
+// every driver supports DELETE ... LIMIT. The delete reuses q, so it sees
+// the same rows (and global scopes) as the pluck; a chunk that deletes
+// nothing stops the loop instead of plucking the same keys forever.
+func massPrune[T any](q *Querier[T], chunk int) (int64, error) {
+	key := KeyName[T]()
+	var total int64
+	for {
+		ids, err := q.Clone().Limit(chunk).Pluck(key)
+		if err != nil || len(ids) == 0 {
+			return total, err
+		}
+		n, err := q.Clone().WhereIn(key, ids).ForceDelete()
+		total += n
+		if err != nil || n == 0 || len(ids) < chunk {
+			return total, err
+		}
+	}
+}
+
+// prunableQuery returns T's prunable query and whether T is MassPrunable.
+func prunableQuery[T any]() (*Querier[T], bool, error) {
+	model := any(new(T))
+	if m, ok := model.(MassPrunable[T]); ok {
+		return m.MassPrunable(), true, nil
+	}
+	if m, ok := model.(Prunable[T]); ok {
+		return m.Prunable(), false, nil
+	}
+	return nil, false, fmt.Errorf("orm: model [%s] implements neither Prunable nor MassPrunable", typeName[T]())
+}
@@ -0,0 +1,163 @@
+package orm_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/events"
+	"github.com/zatrano/framework/core/orm"
+	"github.com/zatrano/framework/core/schedule"
+)
+
+type prunedPost struct {
+	orm.Model
+	orm.SoftDeletes
+	Title string `db:"title"`
+}
+
+func (prunedPost) TableName() string { return "pruned_posts" }
+
+func (prunedPost) Prunable() *orm.Querier[prunedPost] {
+	return orm.Query[prunedPost]().OnlyTrashed().Where("deleted_at", "<", time.Now().Add(-time.Hour))
+}
+
+var prunedTitles []string
+
+func (p *prunedPost) Pruning() error {
+	if p.Title == "keep" {
+		return errors.New("refusing to prune")
+	}
+	if _, err := orm.HasMany[prunedPost, prunedLog](p, "post_id"); err != nil {
+		return err
+	}
+	prunedTitles = append(prunedTitles, p.Title)
+	return nil
+}
+
+type prunedLog struct {
+	orm.Model
+	PostID *int64 `db:"post_id"`
+	Level  string `db:"level"`
+}
+
+func (prunedLog) TableName() string { return "pruned_logs" }
+
+func (prunedLog) MassPrunable() *orm.Querier[prunedLog] {
+	return orm.Query[prunedLog]().Where("level", "debug")
+}
+
+func setupPruneDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	for _, stmt := range []struct {
+		sql  string
+		args []any
+	}{
+		{sql: `CREATE TABLE pruned_posts (id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME)`},
+		{sql: `CREATE TABLE pruned_logs (id INTEGER PRIMARY KEY AUTOINCREMENT, post_id INTEGER, level TEXT, created_at DATETIME, updated_at DATETIME)`},
+		{sql: `INSERT INTO pruned_posts (title, deleted_at) VALUES ('a', ?), ('b', ?), ('c', ?), ('live', NULL), ('recent', ?)`, args: []any{old, old, old, time.Now()}},
+		{sql: `INSERT INTO pruned_logs (level) VALUES ('debug'), ('debug'), ('debug'), ('error'), ('debug')`},
+	} {
+		if _, err := db.Exec(stmt.sql, stmt.args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orm.Configure(db, "sqlite")
+	return db
+}
+
+func TestPruneModels(t *testing.T) {
+	db := setupPruneDB(t)
+	defer db.Close()
+
+	dispatcher := events.New()
+	orm.SetDispatcher(dispatcher)
+	defer orm.SetDispatcher(nil)
+	pruning := 0
+	var pruned []orm.Pruned
+	dispatcher.Listen("prunedpost.pruning", func(any) error { pruning++; return nil })
+	dispatcher.Listen("prunedpost.pruned", func(e any) error { pruned = append(pruned, e.(orm.Pruned)); return nil })
+	dispatcher.Listen("prunedlog.pruned", func(e any) error { pruned = append(pruned, e.(orm.Pruned)); return nil })
+
+	orm.PreventLazyLoading(true)
+	defer orm.PreventLazyLoading(false)
+	orm.RegisterPrunable[prunedPost]()
+	orm.RegisterPrunable[prunedLog]()
+
+	scheduler := schedule.New()
+	if event := orm.SchedulePrune(scheduler); event.DisplayName() != "model:prune" || len(scheduler.Events()) != 1 {
+		t.Fatalf("events=%d", len(scheduler.Events()))
+	}
+
+	want := map[string]int64{"orm_test.prunedLog": 4, "orm_test.prunedPost": 3}
+	for _, model := range orm.PrunableModels() {
+		if n, err := model.Pretend(); err != nil || n != want[model.Name] {
+			t.Fatalf("pretend %s n=%d err=%v", model.Name, n, err)
+		}
+	}
+
+	counts, err := orm.PruneAll(2, "prunedPost", "orm_test.prunedLog")
+	if err != nil || counts["orm_test.prunedPost"] != 3 || counts["orm_test.prunedLog"] != 4 {
+		t.Fatalf("counts=%v err=%v", counts, err)
+	}
+	if pruning != 3 || len(prunedTitles) != 3 || len(pruned) != 2 || pruned[0].Count+pruned[1].Count != 7 {
+		t.Fatalf("pruning=%d titles=%v pruned=%+v", pruning, prunedTitles, pruned)
+	}
+	if remaining, _ := orm.Query[prunedPost]().WithTrashed().Count(); remaining != 2 {
+		t.Fatalf("remaining posts=%d", remaining)
+	}
+	if remaining, _ := orm.Query[prunedLog]().Count(); remaining != 1 {
+		t.Fatalf("remaining logs=%d", remaining)
+	}
+
+	if _, err := db.Exec(`UPDATE pruned_posts SET title = 'keep', deleted_at = ?`, time.Now().Add(-48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orm.PruneModel[prunedPost](0); err == nil {
+		t.Fatal("expected the pruning hook error")
+	}
+}
+
+type tenantLog struct {
+	orm.Model
+	TenantID int64  `db:"tenant_id"`
+	Level    string `db:"level"`
+}
+
+func (tenantLog) TableName() string { return "tenant_logs" }
+
+func (tenantLog) MassPrunable() *orm.Querier[tenantLog] {
+	return orm.Query[tenantLog]().WithoutGlobalScopes().Where("level", "debug")
+}
+
+func TestMassPruneDeletesWithThePrunableScopes(t *testing.T) {
+	db := setupPruneDB(t)
+	defer db.Close()
+	for _, stmt := range []string{
+		`CREATE TABLE tenant_logs (id INTEGER PRIMARY KEY AUTOINCREMENT, tenant_id INTEGER, level TEXT, created_at DATETIME, updated_at DATETIME)`,
+		`INSERT INTO tenant_logs (tenant_id, level) VALUES (1, 'debug'), (2, 'debug'), (2, 'debug'), (2, 'debug'), (2, 'error')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orm.AddGlobalScope[tenantLog]("tenant", func(q *orm.Querier[tenantLog]) *orm.Querier[tenantLog] {
+		return q.Where("tenant_id", 1)
+	})
+
+	n, err := orm.PruneModel[tenantLog](2)
+	if err != nil || n != 4 {
+		t.Fatalf("pruned=%d err=%v", n, err)
+	}
+	if remaining, _ := orm.Query[tenantLog]().WithoutGlobalScopes().Count(); remaining != 1 {
+		t.Fatalf("remaining=%d", remaining)
+	}
+}
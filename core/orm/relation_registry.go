@@ -0,0 +1,306 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/zatrano/framework/core/database/query"
+)
+
+// Relation describes how to eager load one relation of a model. Build one
+// with HasManyRelation, HasOneRelation, BelongsToRelation,
+// BelongsToManyRelation, MorphManyRelation, MorphToManyRelation or
+// MorphedByManyRelation.
+type Relation interface {
+	loadEager(parents any, field string, constrain any, nested []eagerLoad) error
+	subquery(parentTable, field string, constraints []any) (*query.Builder, string, error)
+	validate(field, path string, constrain any) error
+}
+
+// Relations maps a model's relation fields to their definitions.
+type Relations map[string]Relation
+
+// HasRelations models declare their relations so WithRelations and
+// WithConstrained can load them by name, e.g. WithRelations("Posts.Comments"):
+//
+//	func (User) Relations() orm.Relations {
+//		return orm.Relations{
+//			"Posts": orm.HasManyRelation[User, Post]("user_id"),
+//		}
+//	}
+type HasRelations interface {
+	Relations() Relations
+}
+
+// HasManyRelation declares a has-many relation for the registry.
+func HasManyRelation[Parent, Related any](foreignKey string, localKey ...string) Relation {
+	local := defaultLocalKey[Parent](localKey...)
+	return relation[Parent, Related]{
+		load: func(parents *[]Parent, field string, constrain func(*Querier[Related])) error {
+			return LoadHasManyFn(parents, field, foreignKey, constrain, localKey...)
+		},
+		correlate: func(sub *query.Builder, related, parent string) {
+			sub.WhereColumn(related+"."+foreignKey, "=", parent+"."+local)
+		},
+	}
+}
+
+// HasOneRelation declares a has-one relation for the registry.
+func HasOneRelation[Parent, Related any](foreignKey string, localKey ...string) Relation {
+	local := defaultLocalKey[Parent](localKey...)
+	return relation[Parent, Related]{
+		load: func(parents *[]Parent, field string, constrain func(*Querier[Related])) error {
+			return loadHasOne(parents, field, foreignKey, constrain, localKey...)
+		},
+		correlate: func(sub *query.Builder, related, parent string) {
+			sub.WhereColumn(related+"."+foreignKey, "=", parent+"."+local)
+		},
+	}
+}
+
+// BelongsToRelation declares a belongs-to relation for the registry.
+func BelongsToRelation[Child, Parent any](foreignKey string, ownerKey ...string) Relation {
+	owner := defaultLocalKey[Parent](ownerKey...)
+	return relation[Child, Parent]{
+		load: func(children *[]Child, field string, constrain func(*Querier[Parent])) error {
+			return loadBelongsTo(children, field, foreignKey, constrain, ownerKey...)
+		},
+		correlate: func(sub *query.Builder, related, child string) {
+			sub.WhereColumn(related+"."+owner, "=", child+"."+foreignKey)
+		},
+	}
+}
+
+// BelongsToManyRelation declares a belongs-to-many relation for the registry.
+func BelongsToManyRelation[Parent, Related any](pivotTable, foreignPivotKey, relatedPivotKey string, parentKey ...string) Relation {
+	local := defaultLocalKey[Parent](parentKey...)
+	return relation[Parent, Related]{
+		load: func(parents *[]Parent, field string, constrain func(*Querier[Related])) error {
+			return loadBelongsToMany(parents, field, pivotTable, foreignPivotKey, relatedPivotKey, constrain, parentKey...)
+		},
+		correlate: func(sub *query.Builder, related, parent string) {
+			sub.Join(pivotTable, pivotTable+"."+relatedPivotKey, "=", related+"."+KeyName[Related]()).
+				WhereColumn(pivotTable+"."+foreignPivotKey, "=", parent+"."+local)
+		},
+	}
+}
+
+// MorphManyRelation declares a morph-many relation for the registry.
+func MorphManyRelation[Parent, Related any](morphTypeCol, morphIDCol, typeValue string, localKey ...string) Relation {
+	local := defaultLocalKey[Parent](localKey...)
+	return relation[Parent, Related]{
+		load: func(parents *[]Parent, field string, constrain func(*Querier[Related])) error {
+			return loadMorphMany(parents, field, morphTypeCol, morphIDCol, typeValue, constrain, localKey...)
+		},
+		correlate: func(sub *query.Builder, related, parent string) {
+			sub.Where(related+"."+morphTypeCol, typeValue).
+				WhereColumn(related+"."+morphIDCol, "=", parent+"."+local)
+		},
+	}
+}
+
+// MorphToManyRelation declares a polymorphic many-to-many relation, such as
+// tags through a taggables pivot, for the registry.
+func MorphToManyRelation[Parent, Related any](pivotTable, morphName, relatedPivotKey string) Relation {
+	return relation[Parent, Related]{
+		load: func(parents *[]Parent, field string, constrain func(*Querier[Related])) error {
+			return loadMorphToMany(parents, field, pivotTable, morphName, relatedPivotKey, constrain)
+		},
+		correlate: func(sub *query.Builder, related, parent string) {
+			sub.Join(pivotTable, pivotTable+"."+relatedPivotKey, "=", related+"."+KeyName[Related]()).
+				Where(pivotTable+"."+morphName+"_type", MorphType[Parent]()).
+				WhereColumn(pivotTable+"."+morphName+"_id", "=", parent+"."+KeyName[Parent]())
+		},
+	}
+}
+
+// MorphedByManyRelation declares the inverse of MorphToManyRelation for the registry.
+func MorphedByManyRelation[Owner, Related any](pivotTable, morphName, foreignPivotKey string) Relation {
+	return relation[Owner, Related]{
+		load: func(owners *[]Owner, field string, constrain func(*Querier[Related])) error {
+			return loadMorphedByMany(owners, field, pivotTable, morphName, foreignPivotKey, constrain)
+		},
+		correlate: func(sub *query.Builder, related, owner string) {
+			sub.Join(pivotTable, pivotTable+"."+morphName+"_id", "=", related+"."+KeyName[Related]()).
+				Where(pivotTable+"."+morphName+"_type", MorphType[Related]()).
+				WhereColumn(pivotTable+"."+foreignPivotKey, "=", owner+"."+KeyName[Owner]())
+		},
+	}
+}
+
+// relation adapts a typed batch loader to the Relation interface. Nested
+// paths are handed to the related query's With, so every level is loaded for
+// all parents at once. correlate ties a subquery on the related table to the
+// parent row for aggregates such as WithCount.
+type relation[Parent, Related any] struct {
+	load      func(parents *[]Parent, field string, constrain func(*Querier[Related])) error
+	correlate func(sub *query.Builder, related, parent string)
+}
+
+func (r relation[Parent, Related]) loadEager(parents any, field string, constrain any, nested []eagerLoad) error {
+	items, ok := parents.(*[]Parent)
+	if !ok {
+		return fmt.Errorf("relation [%s] does not belong to %T", field, parents)
+	}
+	fn, err := r.constraint(field, constrain)
+	if err != nil {
+		return err
+	}
+	return r.load(items, field, func(q *Querier[Related]) {
+		q.eager = append(q.eager, nested...)
+		if fn != nil {
+			fn(q)
+		}
+	})
+}
+
+// validate checks the constraint of field, or the rest of a dotted path
+// against the related model's relations.
+func (r relation[Parent, Related]) validate(field, path string, constrain any) error {
+	if path != "" {
+		return relationsOf[Related]().validate(path, constrain, Table[Related]())
+	}
+	_, err := r.constraint(field, constrain)
+	return err
+}
+
+// subquery builds the constrained related query correlated to parentTable and
+// returns it with the table name its columns should be qualified with. A
+// self-referencing relation aliases the related table to <table>_related.
+func (r relation[Parent, Related]) subquery(parentTable, field string, constraints []any) (*query.Builder, string, error) {
+	table := Table[Related]()
+	related, from := table, table
+	if table == parentTable {
+		related = table + "_related"
+		from = table + " AS " + related
+	}
+	sub := &Querier[Related]{
+		builder:    newBuilder(DB, from),
+		table:      related,
+		softDelete: hasSoftDeletes[Related](),
+	}
+	for _, constrain := range constraints {
+		fn, err := r.constraint(field, constrain)
+		if err != nil {
+			return nil, "", err
+		}
+		if fn != nil {
+			fn(sub)
+		}
+	}
+	sub.prepare()
+	r.correlate(sub.builder, related, parentTable)
+	return sub.builder, related, nil
+}
+
+func (r relation[Parent, Related]) constraint(field string, constrain any) (func(*Querier[Related]), error) {
+	if constrain == nil {
+		return nil, nil
+	}
+	fn, ok := constrain.(func(*Querier[Related]))
+	if !ok {
+		related := reflect.TypeOf((*Related)(nil)).Elem()
+		return nil, fmt.Errorf("constraint for relation [%s] must be func(*orm.Querier[%s]), got %T", field, related.Name(), constrain)
+	}
+	return fn, nil
+}
+
+// eagerLoad is a relation path registered with WithRelations, relative to the querier's model.
+type eagerLoad struct {
+	path      string
+	constrain any
+}
+
+// eagerLevel is one relation loaded for the first segment of one or more paths.
+type eagerLevel struct {
+	field     string
+	relation  Relation
+	constrain any
+	nested    []eagerLoad
+}
+
+// group merges paths by the relation their first segment resolves to,
+// keeping first-seen order.
+func (r Relations) group(loads []eagerLoad, model string) ([]*eagerLevel, error) {
+	levels := make([]*eagerLevel, 0, len(loads))
+	byField := make(map[string]*eagerLevel, len(loads))
+	for _, load := range loads {
+		head, rest, _ := strings.Cut(strings.TrimSpace(load.path), ".")
+		if head == "" {
+			continue
+		}
+		field, rel, ok := r.lookup(head)
+		if !ok {
+			return nil, fmt.Errorf("relation [%s] is not defined on model [%s]", head, model)
+		}
+		level, ok := byField[field]
+		if !ok {
+			level = &eagerLevel{field: field, relation: rel}
+			byField[field] = level
+			levels = append(levels, level)
+		}
+		if rest == "" {
+			if load.constrain != nil {
+				level.constrain = load.constrain
+			}
+			continue
+		}
+		level.nested = append(level.nested, eagerLoad{path: rest, constrain: load.constrain})
+	}
+	return levels, nil
+}
+
+// validate resolves every level of a dotted relation path on model.
+func (r Relations) validate(path string, constrain any, model string) error {
+	head, rest, _ := strings.Cut(strings.TrimSpace(path), ".")
+	if head == "" {
+		return nil
+	}
+	field, rel, ok := r.lookup(head)
+	if !ok {
+		return fmt.Errorf("relation [%s] is not defined on model [%s]", head, model)
+	}
+	return rel.validate(field, rest, constrain)
+}
+
+func (q *Querier[T]) runRelations(items []T) error {
+	if len(q.eager) == 0 || len(items) == 0 {
+		return nil
+	}
+	levels, err := relationsOf[T]().group(q.eager, q.table)
+	if err != nil {
+		return err
+	}
+	for _, level := range levels {
+		if err := level.relation.loadEager(&items, level.field, level.constrain, level.nested); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func relationsOf[T any]() Relations {
+	var zero T
+	if r, ok := any(zero).(HasRelations); ok {
+		return r.Relations()
+	}
+	if r, ok := any(&zero).(HasRelations); ok {
+		return r.Relations()
+	}
+	return nil
+}
+
+// lookup finds a relation by field name, falling back to a case-insensitive
+// or snake_case match so "posts" and "blog_posts" resolve too.
+func (r Relations) lookup(name string) (string, Relation, bool) {
+	if rel, ok := r[name]; ok {
+		return name, rel, true
+	}
+	for field, rel := range r {
+		if strings.EqualFold(field, name) || toSnake(field) == name {
+			return field, rel, true
+		}
+	}
+	return "", nil, false
+}
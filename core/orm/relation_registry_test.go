@@ -0,0 +1,156 @@
+package orm_test
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/database/query"
+	"github.com/zatrano/framework/core/orm"
+)
+
+type regUser struct {
+	orm.Model
+	Name  string    `db:"name"`
+	Posts []regPost `db:"-"`
+}
+
+func (regUser) TableName() string { return "reg_users" }
+
+func (regUser) Relations() orm.Relations {
+	return orm.Relations{
+		"Posts": orm.HasManyRelation[regUser, regPost]("user_id"),
+	}
+}
+
+type regPost struct {
+	orm.Model
+	UserID   int64        `db:"user_id"`
+	Title    string       `db:"title"`
+	Comments []regComment `db:"-"`
+}
+
+func (regPost) TableName() string { return "reg_posts" }
+
+func (regPost) Relations() orm.Relations {
+	return orm.Relations{
+		"Comments": orm.HasManyRelation[regPost, regComment]("post_id"),
+	}
+}
+
+type regComment struct {
+	orm.Model
+	PostID   int64    `db:"post_id"`
+	AuthorID int64    `db:"author_id"`
+	Body     string   `db:"body"`
+	Author   *regUser `db:"-"`
+}
+
+func (regComment) TableName() string { return "reg_comments" }
+
+func (regComment) Relations() orm.Relations {
+	return orm.Relations{
+		"Author": orm.BelongsToRelation[regComment, regUser]("author_id"),
+	}
+}
+
+func setupRegistryDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sqlStr := range []string{
+		`CREATE TABLE reg_users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE reg_posts (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER, title TEXT, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE reg_comments (id INTEGER PRIMARY KEY AUTOINCREMENT, post_id INTEGER, author_id INTEGER, body TEXT, created_at DATETIME, updated_at DATETIME)`,
+	} {
+		if _, err := db.Exec(sqlStr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orm.Configure(db, "sqlite")
+
+	ada, _ := orm.Create[regUser](map[string]any{"name": "ada"})
+	bob, _ := orm.Create[regUser](map[string]any{"name": "bob"})
+	p1, _ := orm.Create[regPost](map[string]any{"user_id": ada.ID, "title": "first"})
+	p2, _ := orm.Create[regPost](map[string]any{"user_id": ada.ID, "title": "second"})
+	p3, _ := orm.Create[regPost](map[string]any{"user_id": bob.ID, "title": "third"})
+	_, _ = orm.Create[regComment](map[string]any{"post_id": p1.ID, "author_id": bob.ID, "body": "nice"})
+	_, _ = orm.Create[regComment](map[string]any{"post_id": p1.ID, "author_id": ada.ID, "body": "thanks"})
+	_, _ = orm.Create[regComment](map[string]any{"post_id": p2.ID, "author_id": bob.ID, "body": "spam"})
+	_, _ = orm.Create[regComment](map[string]any{"post_id": p3.ID, "author_id": ada.ID, "body": "hello"})
+	return db
+}
+
+func TestWithNestedRelationPath(t *testing.T) {
+	db := setupRegistryDB(t)
+	defer db.Close()
+
+	selects := 0
+	orm.SetQueryListener(func(e query.Executed) {
+		if strings.HasPrefix(e.SQL, "SELECT") {
+			selects++
+		}
+	})
+	defer orm.SetQueryListener(nil)
+
+	users, err := orm.Query[regUser]().WithRelations("Posts.Comments.Author", "posts").OrderBy("id").Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selects != 4 {
+		t.Fatalf("selects=%d, want one per level", selects)
+	}
+	if len(users) != 2 || len(users[0].Posts) != 2 || len(users[1].Posts) != 1 {
+		t.Fatalf("users=%+v", users)
+	}
+	comments := users[0].Posts[0].Comments
+	if len(comments) != 2 {
+		t.Fatalf("comments=%+v", comments)
+	}
+	for _, c := range comments {
+		if c.Author == nil || c.Author.ID != c.AuthorID {
+			t.Fatalf("author=%+v comment=%+v", c.Author, c)
+		}
+	}
+	if users[0].Posts[0].Comments[0].Author.Posts != nil {
+		t.Fatal("author relations should not be loaded")
+	}
+}
+
+func TestWithConstrainedNestedLevel(t *testing.T) {
+	db := setupRegistryDB(t)
+	defer db.Close()
+
+	users, err := orm.Query[regUser]().
+		WithConstrained("Posts.Comments", func(q *orm.Querier[regComment]) {
+			q.Where("body", "!=", "spam")
+		}).
+		OrderBy("id").
+		Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users[0].Posts) != 2 {
+		t.Fatalf("posts=%+v", users[0].Posts)
+	}
+	if n := len(users[0].Posts[1].Comments); n != 0 {
+		t.Fatalf("constrained comments=%d", n)
+	}
+
+	_, err = orm.Query[regUser]().WithConstrained("Posts", func(q *orm.Querier[regComment]) {}).Get()
+	if err == nil || !strings.Contains(err.Error(), "constraint for relation [Posts]") {
+		t.Fatalf("mismatched constraint err=%v", err)
+	}
+
+	selects := 0
+	orm.SetQueryListener(func(query.Executed) { selects++ })
+	defer orm.SetQueryListener(nil)
+	_, err = orm.Query[regUser]().WithRelations("Posts.Likes").Get()
+	if err == nil || !strings.Contains(err.Error(), "relation [Likes] is not defined") || selects != 0 {
+		t.Fatalf("unknown relation err=%v selects=%d", err, selects)
+	}
+}
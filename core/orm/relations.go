@@ -1,30 +1,50 @@
 package orm
 
 import (
+	"context"
 	"fmt"
 	"reflect"
-
-	"github.com/zatrano/framework/core/database/query"
 )
 
 // HasMany returns related models using a foreign key.
 func HasMany[Parent any, Related any](parent *Parent, foreignKey string, localKey ...string) ([]Related, error) {
+	return HasManyContext[Parent, Related](context.Background(), parent, foreignKey, localKey...)
+}
+
+// HasManyContext is HasMany using ctx, so the query reaches the request's
+// query log and the inspector can flag N+1 loops:
+//
+//	for i := range posts {
+//		comments, err := orm.HasManyContext[Post, Comment](req.Context(), &posts[i], "post_id")
+//	}
+func HasManyContext[Parent any, Related any](ctx context.Context, parent *Parent, foreignKey string, localKey ...string) ([]Related, error) {
+	if err := guardLazyLoad(parent, typeName[Related]()); err != nil {
+		return nil, err
+	}
 	local := defaultLocalKey[Parent](localKey...)
 	parentID, err := attribute(parent, local)
 	if err != nil {
 		return nil, err
 	}
-	return Where[Related](foreignKey, parentID).Get()
+	return Where[Related](foreignKey, parentID).WithContext(ctx).Get()
 }
 
 // CountRelated counts related models for a parent.
 func CountRelated[Parent any, Related any](parent *Parent, foreignKey string, localKey ...string) (int64, error) {
+	return CountRelatedContext[Parent, Related](context.Background(), parent, foreignKey, localKey...)
+}
+
+// CountRelatedContext is CountRelated using ctx.
+func CountRelatedContext[Parent any, Related any](ctx context.Context, parent *Parent, foreignKey string, localKey ...string) (int64, error) {
+	if err := guardLazyLoad(parent, typeName[Related]()); err != nil {
+		return 0, err
+	}
 	local := defaultLocalKey[Parent](localKey...)
 	parentID, err := attribute(parent, local)
 	if err != nil {
 		return 0, err
 	}
-	return Where[Related](foreignKey, parentID).Count()
+	return Where[Related](foreignKey, parentID).WithContext(ctx).Count()
 }
 
 // WithCount returns a map of parent local-key values to related counts (single batched query).
@@ -58,7 +78,7 @@ func WithCount[Parent any, Related any](parents []Parent, foreignKey string, loc
 	}
 
 	relatedTable := Table[Related]()
-	rows, err := query.New(DB, Driver, relatedTable).
+	rows, err := newBuilder(DB, relatedTable).
 		SelectRaw(foreignKey+", COUNT(*) as aggregate").
 		WhereIn(foreignKey, keys).
 		GroupBy(foreignKey).
@@ -80,7 +100,12 @@ func WithCount[Parent any, Related any](parents []Parent, foreignKey string, loc
 
 // HasOne returns a related model using a foreign key.
 func HasOne[Parent any, Related any](parent *Parent, foreignKey string, localKey ...string) (*Related, error) {
-	items, err := HasMany[Parent, Related](parent, foreignKey, localKey...)
+	return HasOneContext[Parent, Related](context.Background(), parent, foreignKey, localKey...)
+}
+
+// HasOneContext is HasOne using ctx.
+func HasOneContext[Parent any, Related any](ctx context.Context, parent *Parent, foreignKey string, localKey ...string) (*Related, error) {
+	items, err := HasManyContext[Parent, Related](ctx, parent, foreignKey, localKey...)
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +117,14 @@ func HasOne[Parent any, Related any](parent *Parent, foreignKey string, localKey
 
 // BelongsTo returns the parent model for a child.
 func BelongsTo[Child any, Parent any](child *Child, foreignKey string, ownerKey ...string) (*Parent, error) {
+	return BelongsToContext[Child, Parent](context.Background(), child, foreignKey, ownerKey...)
+}
+
+// BelongsToContext is BelongsTo using ctx.
+func BelongsToContext[Child any, Parent any](ctx context.Context, child *Child, foreignKey string, ownerKey ...string) (*Parent, error) {
+	if err := guardLazyLoad(child, typeName[Parent]()); err != nil {
+		return nil, err
+	}
 	owner := defaultLocalKey[Parent](ownerKey...)
 	fk, err := attribute(child, foreignKey)
 	if err != nil {
@@ -100,7 +133,7 @@ func BelongsTo[Child any, Parent any](child *Child, foreignKey string, ownerKey
 	if fk == nil {
 		return nil, nil
 	}
-	return Where[Parent](owner, fk).First()
+	return Where[Parent](owner, fk).WithContext(ctx).First()
 }
 
 // BelongsToMany returns related models through a pivot table.
@@ -109,13 +142,30 @@ func BelongsToMany[Parent any, Related any](
 	pivotTable, foreignPivotKey, relatedPivotKey string,
 	parentKey ...string,
 ) ([]Related, error) {
+	return BelongsToManyContext[Parent, Related](context.Background(), parent, pivotTable, foreignPivotKey, relatedPivotKey, parentKey...)
+}
+
+// BelongsToManyContext is BelongsToMany using ctx.
+func BelongsToManyContext[Parent any, Related any](
+	ctx context.Context,
+	parent *Parent,
+	pivotTable, foreignPivotKey, relatedPivotKey string,
+	parentKey ...string,
+) ([]Related, error) {
+	if err := guardLazyLoad(parent, typeName[Related]()); err != nil {
+		return nil, err
+	}
+	relatedKey, err := singleKeyName[Related]()
+	if err != nil {
+		return nil, err
+	}
 	key := defaultLocalKey[Parent](parentKey...)
 	parentID, err := attribute(parent, key)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := query.New(DB, Driver, pivotTable).Where(foreignPivotKey, parentID).Get()
+	rows, err := newBuilder(DB, pivotTable).WithContext(ctx).Where(foreignPivotKey, parentID).Get()
 	if err != nil {
 		return nil, err
 	}
@@ -127,7 +177,7 @@ func BelongsToMany[Parent any, Related any](
 	for _, row := range rows {
 		ids = append(ids, row[relatedPivotKey])
 	}
-	return Query[Related]().WhereIn(KeyName[Related](), ids).Get()
+	return Query[Related]().WithContext(ctx).WhereIn(relatedKey, ids).Get()
 }
 
 func attribute(model any, name string) (any, error) {
@@ -0,0 +1,240 @@
+package orm
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zatrano/framework/core/cache"
+)
+
+var (
+	cacheMu    sync.RWMutex
+	cacheStore cache.Store
+
+	// pendingFlushes holds the tables written inside transactions started by
+	// TransactionContext, invalidated once the transaction commits.
+	pendingMu      sync.Mutex
+	pendingFlushes = map[*sql.Tx]map[string]bool{}
+)
+
+func init() {
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+	gob.Register(time.Time{})
+}
+
+// SetCacheStore sets the store used by Querier.Remember, which also holds the
+// per-table generations that invalidate every remembered result. The
+// application wires its default cache store here.
+func SetCacheStore(store cache.Store) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cacheStore = store
+}
+
+// remember holds the Remember settings of a querier.
+type remember struct {
+	store cache.Store
+	ttl   time.Duration
+	key   string
+}
+
+// Remember caches the models returned by Get and First for ttl. Without a key
+// one is derived from the SQL, bindings and eager load paths; pass a key when
+// With is given loader functions. Entries are dropped when Save, Create,
+// DeleteModel, Querier.Update or another ORM write touches the model's table.
+// Changes to eager-loaded tables do not invalidate them. Queries bound to a
+// transaction (QueryTx) bypass the cache, and writes made inside
+// TransactionContext invalidate only once it commits.
+func (q *Querier[T]) Remember(ttl time.Duration, key ...string) *Querier[T] {
+	r := &remember{ttl: ttl}
+	if q.remember != nil {
+		r.store = q.remember.store
+	}
+	if len(key) > 0 {
+		r.key = key[0]
+	}
+	q.remember = r
+	return q
+}
+
+// RememberForever caches the results until the table is written to.
+func (q *Querier[T]) RememberForever(key ...string) *Querier[T] {
+	return q.Remember(0, key...)
+}
+
+// CacheStore makes Remember keep results in store instead of the one set with
+// SetCacheStore. Table generations stay in the SetCacheStore store, so
+// without one, results in store are only dropped by their ttl.
+func (q *Querier[T]) CacheStore(store cache.Store) *Querier[T] {
+	if q.remember == nil {
+		q.remember = &remember{}
+	}
+	q.remember.store = store
+	return q
+}
+
+// remembered runs fetch through the cache when Remember is set; suffix
+// distinguishes Get from First for derived keys.
+func (q *Querier[T]) remembered(suffix string, fetch func() ([]T, error)) ([]T, error) {
+	store := q.rememberStore()
+	if store == nil || q.tx != nil {
+		return fetch()
+	}
+	key := q.rememberKey(store, suffix)
+	if raw, ok := store.Get(key); ok {
+		if items, err := decodeCached[T](raw); err == nil {
+			return items, nil
+		}
+	}
+	items, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := encodeCached(items)
+	if err != nil {
+		return nil, fmt.Errorf("orm: cache [%s]: %w", key, err)
+	}
+	if q.remember.ttl > 0 {
+		err = store.Put(key, encoded, q.remember.ttl)
+	} else {
+		err = store.Forever(key, encoded)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (q *Querier[T]) rememberStore() cache.Store {
+	if q.remember == nil {
+		return nil
+	}
+	if q.remember.store != nil {
+		return q.remember.store
+	}
+	cacheMu.RLock()
+	defer cacheMu.RUnlock()
+	return cacheStore
+}
+
+// rememberKey prefixes the key with the table's current generation, so
+// bumping the generation orphans every entry cached for the table.
+func (q *Querier[T]) rememberKey(store cache.Store, suffix string) string {
+	key := q.remember.key
+	if key == "" {
+		q.prepare()
+		sqlStr, bindings := q.builder.ToSQL()
+		paths := make([]string, 0, len(q.eager))
+		for _, load := range q.eager {
+			paths = append(paths, load.path)
+		}
+		sum := sha1.Sum(fmt.Appendf(nil, "%s|%v|%s|%d", sqlStr, bindings, strings.Join(paths, ","), len(q.loaders)))
+		key = hex.EncodeToString(sum[:])
+	}
+	if suffix != "" {
+		key += ":" + suffix
+	}
+	return fmt.Sprintf("orm:%s:%s:%s", q.table, tableGeneration(store, q.table), key)
+}
+
+func generationKey(table string) string {
+	return "orm:generation:" + table
+}
+
+func tableGeneration(store cache.Store, table string) string {
+	cacheMu.RLock()
+	if cacheStore != nil {
+		store = cacheStore
+	}
+	cacheMu.RUnlock()
+	if gen, ok := store.Get(generationKey(table)); ok {
+		return fmt.Sprint(gen)
+	}
+	return "0"
+}
+
+// flushRemembered invalidates results cached with Remember for table.
+func flushRemembered(table string) {
+	cacheMu.RLock()
+	store := cacheStore
+	cacheMu.RUnlock()
+	if store != nil {
+		_, _ = cache.Increment(store, generationKey(table))
+	}
+}
+
+// flushRemembered invalidates the querier's table after a write. Inside a
+// transaction started by TransactionContext the flush waits for the commit,
+// so concurrent readers cannot cache rows that are not committed yet.
+func (q *Querier[T]) flushRemembered() {
+	if q.tx != nil {
+		pendingMu.Lock()
+		tables, tracked := pendingFlushes[q.tx]
+		if tracked {
+			tables[q.table] = true
+		}
+		pendingMu.Unlock()
+		if tracked {
+			return
+		}
+	}
+	flushRemembered(q.table)
+}
+
+// trackTransaction starts collecting the tables written through tx.
+func trackTransaction(tx *sql.Tx) {
+	pendingMu.Lock()
+	pendingFlushes[tx] = map[string]bool{}
+	pendingMu.Unlock()
+}
+
+// finishTransaction stops tracking tx and, when it committed, invalidates
+// the tables written through it.
+func finishTransaction(tx *sql.Tx, committed bool) {
+	pendingMu.Lock()
+	tables := pendingFlushes[tx]
+	delete(pendingFlushes, tx)
+	pendingMu.Unlock()
+	if !committed {
+		return
+	}
+	for table := range tables {
+		flushRemembered(table)
+	}
+}
+
+// encodeCached gob-encodes models into a string every store can hold,
+// keeping fields that encoding/json would skip.
+func encodeCached[T any](items []T) (string, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(items); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func decodeCached[T any](raw any) ([]T, error) {
+	s, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected cached value %T", raw)
+	}
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	items := []T{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&items); err != nil {
+		return nil, err
+	}
+	markCollection(items)
+	return items, nil
+}
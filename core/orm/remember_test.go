@@ -0,0 +1,158 @@
+package orm_test
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/cache"
+	"github.com/zatrano/framework/core/database/query"
+	"github.com/zatrano/framework/core/orm"
+)
+
+type cachedProduct struct {
+	orm.Model
+	Name   string `db:"name"`
+	Secret string `db:"secret" json:"-"`
+	Price  int64  `db:"price"`
+}
+
+func (cachedProduct) TableName() string { return "cached_products" }
+
+func setupRememberDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE cached_products (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, secret TEXT, price INTEGER, created_at DATETIME, updated_at DATETIME)`); err != nil {
+		t.Fatal(err)
+	}
+	orm.Configure(db, "sqlite")
+	return db
+}
+
+func TestRememberCachesAndInvalidates(t *testing.T) {
+	db := setupRememberDB(t)
+	defer db.Close()
+
+	store, err := cache.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	orm.SetCacheStore(store)
+	defer orm.SetCacheStore(nil)
+
+	selects := 0
+	orm.SetQueryListener(func(e query.Executed) {
+		if strings.HasPrefix(e.SQL, "SELECT") {
+			selects++
+		}
+	})
+	defer orm.SetQueryListener(nil)
+
+	created, _ := orm.Create[cachedProduct](map[string]any{"name": "lamp", "secret": "s3", "price": 1_000_000})
+	selects = 0
+
+	cheap := func() ([]cachedProduct, error) {
+		return orm.Query[cachedProduct]().Where("price", ">", 10).Remember(time.Minute).Get()
+	}
+	for i := 0; i < 3; i++ {
+		items, err := cheap()
+		if err != nil || len(items) != 1 || items[0].Secret != "s3" || items[0].Price != 1_000_000 || items[0].CreatedAt == nil {
+			t.Fatalf("items=%+v err=%v", items, err)
+		}
+	}
+	if selects != 1 {
+		t.Fatalf("selects=%d", selects)
+	}
+
+	first, err := orm.Query[cachedProduct]().RememberForever("lamp").First()
+	if err != nil || first.Name != "lamp" {
+		t.Fatalf("first=%+v err=%v", first, err)
+	}
+
+	created.Name = "desk lamp"
+	if err := orm.Save(created); err != nil {
+		t.Fatal(err)
+	}
+	selects = 0
+	first, _ = orm.Query[cachedProduct]().RememberForever("lamp").First()
+	if items, _ := cheap(); len(items) != 1 || items[0].Name != "desk lamp" || first.Name != "desk lamp" || selects != 2 {
+		t.Fatalf("after save items=%+v first=%+v selects=%d", items, first, selects)
+	}
+
+	if _, err := orm.Query[cachedProduct]().Where("id", created.ID).Update(map[string]any{"price": 5}); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := cheap(); len(items) != 0 {
+		t.Fatalf("after update items=%+v", items)
+	}
+
+	memory := cache.NewMemoryStore()
+	if _, err := orm.Query[cachedProduct]().CacheStore(memory).Remember(time.Minute, "all").Get(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orm.DeleteModel(created); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := orm.Query[cachedProduct]().CacheStore(memory).Remember(time.Minute, "all").Get(); len(items) != 0 {
+		t.Fatalf("after delete items=%+v", items)
+	}
+	if _, err := orm.Query[cachedProduct]().RememberForever("lamp").First(); err != sql.ErrNoRows {
+		t.Fatalf("expected no rows, got %v", err)
+	}
+}
+
+func TestRememberInsideTransactions(t *testing.T) {
+	db := setupRememberDB(t)
+	defer db.Close()
+	orm.SetCacheStore(cache.NewMemoryStore())
+	defer orm.SetCacheStore(nil)
+
+	if _, err := orm.Create[cachedProduct](map[string]any{"name": "lamp", "price": 10}); err != nil {
+		t.Fatal(err)
+	}
+	names := func() string {
+		items, _ := orm.Query[cachedProduct]().RememberForever("names").Get()
+		out := []string{}
+		for _, item := range items {
+			out = append(out, item.Name)
+		}
+		return strings.Join(out, ",")
+	}
+
+	rollback := sql.ErrTxDone
+	err := orm.Transaction(func(tx *sql.Tx) error {
+		if _, err := orm.QueryOn[cachedProduct](tx).Where("id", 1).Update(map[string]any{"name": "draft"}); err != nil {
+			return err
+		}
+		if items, err := orm.QueryTx[cachedProduct](tx).RememberForever("names").Get(); err != nil || items[0].Name != "draft" {
+			t.Fatalf("inside tx items=%+v err=%v", items, err)
+		}
+		return rollback
+	})
+	if err != rollback {
+		t.Fatalf("err=%v", err)
+	}
+	if got := names(); got != "lamp" {
+		t.Fatalf("rolled back rows were cached: %q", got)
+	}
+
+	err = orm.Transaction(func(tx *sql.Tx) error {
+		_, err := orm.QueryTx[cachedProduct](tx).Where("id", 1).Update(map[string]any{"name": "desk lamp"})
+		if got := names(); got != "lamp" {
+			t.Fatalf("uncommitted write invalidated the cache: %q", got)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(); got != "desk lamp" {
+		t.Fatalf("commit did not invalidate: %q", got)
+	}
+}
@@ -183,7 +183,8 @@ func (q *Querier[T]) applyGlobalScopes() {
 	}
 }
 
-func (q *Querier[T]) prepare() {
+func (q *Querier[T]) prepare() error {
 	q.applyGlobalScopes()
 	q.applySoftDelete()
+	return q.err
 }
@@ -0,0 +1,406 @@
+package orm
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Hidden models leave the listed attributes out of ToMap, ToJSON and http.JSON.
+// Names may be JSON keys or column names.
+type Hidden interface {
+	Hidden() []string
+}
+
+// Visible models serialize only the listed attributes.
+type Visible interface {
+	Visible() []string
+}
+
+// Appends models add computed attributes to their serialized form. Each name
+// is read from an accessor: "full_name" calls GetFullNameAttribute().
+type Appends interface {
+	Appends() []string
+}
+
+// ToMap serializes a model the way encoding/json would, honouring Hidden,
+// Visible and Appends and replacing attributes that have a Get<Name>Attribute
+// accessor. Nested models are serialized by their own rules.
+func ToMap(model any) map[string]any {
+	rv := indirect(reflect.ValueOf(model))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	return modelMap(rv, nil)
+}
+
+// ToJSON encodes value with Serialize.
+func ToJSON(value any) ([]byte, error) {
+	return json.Marshal(Serialize(value))
+}
+
+// Serialize rewrites every serializable model inside value (itself, slices,
+// maps and structs such as paginators) into its ToMap form and returns other
+// values unchanged. The application installs it as http.JSON's transformer.
+func Serialize(value any) any {
+	if value == nil {
+		return nil
+	}
+	return serializeValue(reflect.ValueOf(value), nil)
+}
+
+// Visibility overrides Hidden and Visible for one serialization:
+//
+//	http.JSON(orm.MakeVisible(user, "email").MakeHidden("phone"))
+type Visibility struct {
+	value   any
+	hidden  map[string]bool
+	visible map[string]bool
+}
+
+// MakeHidden hides columns when value (a model or a slice of models) is serialized.
+func MakeHidden(value any, columns ...string) *Visibility {
+	return (&Visibility{value: value}).MakeHidden(columns...)
+}
+
+// MakeVisible shows columns that Hidden would leave out, and adds them to a Visible list.
+func MakeVisible(value any, columns ...string) *Visibility {
+	return (&Visibility{value: value}).MakeVisible(columns...)
+}
+
+// MakeHidden hides more columns.
+func (v *Visibility) MakeHidden(columns ...string) *Visibility {
+	if v.hidden == nil {
+		v.hidden = make(map[string]bool)
+	}
+	for _, column := range columns {
+		v.hidden[column] = true
+		delete(v.visible, column)
+	}
+	return v
+}
+
+// MakeVisible shows more columns.
+func (v *Visibility) MakeVisible(columns ...string) *Visibility {
+	if v.visible == nil {
+		v.visible = make(map[string]bool)
+	}
+	for _, column := range columns {
+		v.visible[column] = true
+		delete(v.hidden, column)
+	}
+	return v
+}
+
+// Value returns the serialized form with the overrides applied.
+func (v *Visibility) Value() any {
+	if v.value == nil {
+		return nil
+	}
+	return serializeValue(reflect.ValueOf(v.value), v)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v *Visibility) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Value())
+}
+
+func serializeValue(rv reflect.Value, o *Visibility) any {
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return serializeValue(rv.Elem(), o)
+	case reflect.Ptr:
+		if !rv.IsNil() && (o != nil || needsSerializing(rv.Type())) {
+			return serializeValue(rv.Elem(), o)
+		}
+	case reflect.Struct:
+		if isSerializable(rv.Type()) || (o != nil && isModelStruct(rv.Type())) {
+			return modelMap(rv, o)
+		}
+		if needsSerializing(rv.Type()) {
+			out := make(map[string]any)
+			eachJSONField(rv, func(key, _ string, fv reflect.Value) {
+				out[key] = serializeValue(fv, nil)
+			})
+			return out
+		}
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		if o != nil || needsSerializing(rv.Type().Elem()) {
+			out := make([]any, rv.Len())
+			for i := range out {
+				out[i] = serializeValue(rv.Index(i), o)
+			}
+			return out
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String && needsSerializing(rv.Type().Elem()) {
+			if rv.IsNil() {
+				return nil
+			}
+			out := make(map[string]any, rv.Len())
+			iter := rv.MapRange()
+			for iter.Next() {
+				out[iter.Key().String()] = serializeValue(iter.Value(), nil)
+			}
+			return out
+		}
+	}
+	if !rv.CanInterface() {
+		return nil
+	}
+	return rv.Interface()
+}
+
+// modelMap builds the serialized attributes of one model.
+func modelMap(rv reflect.Value, o *Visibility) map[string]any {
+	ptr := reflect.New(rv.Type())
+	ptr.Elem().Set(rv)
+	model := ptr.Interface()
+
+	hidden := map[string]bool{}
+	if h, ok := model.(Hidden); ok {
+		for _, name := range h.Hidden() {
+			hidden[name] = true
+		}
+	}
+	var visible map[string]bool
+	if v, ok := model.(Visible); ok {
+		visible = map[string]bool{}
+		for _, name := range v.Visible() {
+			visible[name] = true
+		}
+	}
+	if o != nil {
+		for name := range o.hidden {
+			hidden[name] = true
+		}
+		for name := range o.visible {
+			delete(hidden, name)
+			if visible != nil {
+				visible[name] = true
+			}
+		}
+	}
+	shown := func(key, column string) bool {
+		if hidden[key] || hidden[column] {
+			return false
+		}
+		return visible == nil || visible[key] || visible[column]
+	}
+
+	out := make(map[string]any)
+	eachJSONField(ptr.Elem(), func(key, column string, fv reflect.Value) {
+		if !shown(key, column) {
+			return
+		}
+		if value, ok := accessor(ptr, column); ok {
+			out[key] = value
+			return
+		}
+		out[key] = serializeValue(fv, nil)
+	})
+	if a, ok := model.(Appends); ok {
+		for _, name := range a.Appends() {
+			if !shown(name, name) {
+				continue
+			}
+			if value, ok := accessor(ptr, name); ok {
+				out[name] = value
+			}
+		}
+	}
+	return out
+}
+
+// accessor calls Get<Studly>Attribute on ptr when the model defines it.
+func accessor(ptr reflect.Value, name string) (any, bool) {
+	method := ptr.MethodByName("Get" + studly(name) + "Attribute")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() == 0 {
+		return nil, false
+	}
+	return serializeValue(method.Call(nil)[0], nil), true
+}
+
+// eachJSONField visits the fields encoding/json would encode, with their JSON
+// key and column name. Embedded structs are flattened; outer fields win.
+func eachJSONField(rv reflect.Value, visit func(key, column string, fv reflect.Value)) {
+	seen := map[string]bool{}
+	var walk func(rv reflect.Value)
+	walk = func(rv reflect.Value) {
+		rt := rv.Type()
+		var embedded []reflect.Value
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			fv := rv.Field(i)
+			if field.Anonymous && name == "" {
+				inner := indirect(fv)
+				if inner.Kind() == reflect.Struct {
+					embedded = append(embedded, inner)
+					continue
+				}
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if seen[name] || (strings.Contains(","+opts+",", ",omitempty,") && isEmptyJSON(fv)) {
+				seen[name] = true
+				continue
+			}
+			seen[name] = true
+			visit(name, columnName(field), fv)
+		}
+		for _, inner := range embedded {
+			walk(inner)
+		}
+	}
+	walk(rv)
+}
+
+func isEmptyJSON(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return fv.Len() == 0
+	case reflect.Bool:
+		return !fv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return fv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return fv.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return fv.IsNil()
+	}
+	return false
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	serializableTypes sync.Map
+)
+
+// isSerializable reports whether values of rt need ToMap rather than plain
+// encoding/json: models with Hidden, Visible, Appends or accessors.
+func isSerializable(rt reflect.Type) bool {
+	if rt.Kind() != reflect.Struct {
+		return false
+	}
+	ptr := reflect.PointerTo(rt)
+	for _, iface := range []reflect.Type{
+		reflect.TypeOf((*Hidden)(nil)).Elem(),
+		reflect.TypeOf((*Visible)(nil)).Elem(),
+		reflect.TypeOf((*Appends)(nil)).Elem(),
+	} {
+		if ptr.Implements(iface) {
+			return true
+		}
+	}
+	for i := 0; i < ptr.NumMethod(); i++ {
+		name := ptr.Method(i).Name
+		if len(name) > len("GetAttribute") && strings.HasPrefix(name, "Get") && strings.HasSuffix(name, "Attribute") {
+			return true
+		}
+	}
+	return false
+}
+
+// isModelStruct reports whether rt embeds one of the ORM base models.
+func isModelStruct(rt reflect.Type) bool {
+	if rt.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < rt.NumField(); i++ {
+		switch rt.Field(i).Type {
+		case reflect.TypeOf(Model{}), reflect.TypeOf(UUIDModel{}), reflect.TypeOf(ULIDModel{}):
+			return true
+		}
+	}
+	return false
+}
+
+// needsSerializing reports whether a serializable model can be reached from
+// rt, so Serialize must walk values of that type. Results are cached per type.
+func needsSerializing(rt reflect.Type) bool {
+	if cached, ok := serializableTypes.Load(rt); ok {
+		return cached.(bool)
+	}
+	result := reachesSerializable(rt, map[reflect.Type]bool{})
+	serializableTypes.Store(rt, result)
+	return result
+}
+
+func reachesSerializable(rt reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[rt] {
+		return false
+	}
+	visiting[rt] = true
+	switch rt.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return reachesSerializable(rt.Elem(), visiting)
+	case reflect.Map:
+		return rt.Key().Kind() == reflect.String && reachesSerializable(rt.Elem(), visiting)
+	case reflect.Interface:
+		// Only the dynamic value can tell; Serialize checks it when walking.
+		return rt.NumMethod() == 0
+	case reflect.Struct:
+		if isSerializable(rt) {
+			return true
+		}
+		if rt.Implements(jsonMarshalerType) || reflect.PointerTo(rt).Implements(jsonMarshalerType) {
+			return false
+		}
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			if (field.IsExported() || field.Anonymous) && field.Tag.Get("json") != "-" && reachesSerializable(field.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func indirect(rv reflect.Value) reflect.Value {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+// studly turns full_name into FullName.
+func studly(name string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' || r == '-' || r == ' ' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,115 @@
+package orm_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/zatrano/framework/core/orm"
+	"github.com/zatrano/framework/core/pagination"
+)
+
+type serialUser struct {
+	orm.Model
+	FirstName     string        `db:"first_name" json:"first_name"`
+	LastName      string        `db:"last_name" json:"last_name"`
+	Email         string        `db:"email" json:"email"`
+	Password      string        `db:"password" json:"password"`
+	RememberToken string        `db:"remember_token"`
+	Posts         []serialPost  `db:"-" json:"posts,omitempty"`
+	Manager       *serialUser   `db:"-" json:"manager,omitempty"`
+	Profile       serialProfile `db:"-" json:"profile"`
+}
+
+func (serialUser) Hidden() []string  { return []string{"password", "remember_token"} }
+func (serialUser) Appends() []string { return []string{"full_name"} }
+
+func (u serialUser) GetFullNameAttribute() string { return u.FirstName + " " + u.LastName }
+
+type serialPost struct {
+	ID     int64  `json:"id"`
+	Title  string `json:"title"`
+	Secret string `json:"secret"`
+}
+
+func (serialPost) Visible() []string { return []string{"id", "title"} }
+
+type serialProfile struct {
+	Bio string `json:"bio"`
+}
+
+func (p serialProfile) GetBioAttribute() string { return "bio: " + p.Bio }
+
+func newSerialUser() serialUser {
+	u := serialUser{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", Password: "hash", RememberToken: "tok"}
+	u.ID = 1
+	u.Posts = []serialPost{{ID: 9, Title: "Notes", Secret: "draft"}}
+	u.Profile = serialProfile{Bio: "math"}
+	return u
+}
+
+func TestToMapHidesAppendsAndNests(t *testing.T) {
+	out := orm.ToMap(newSerialUser())
+	if _, ok := out["password"]; ok {
+		t.Fatalf("password leaked: %v", out)
+	}
+	if _, ok := out["RememberToken"]; ok {
+		t.Fatalf("remember_token leaked by column name: %v", out)
+	}
+	if out["full_name"] != "Ada Lovelace" || out["id"] != int64(1) || out["email"] != "ada@example.com" {
+		t.Fatalf("attributes=%v", out)
+	}
+	if _, ok := out["manager"]; ok {
+		t.Fatalf("omitempty ignored: %v", out)
+	}
+	posts, ok := out["posts"].([]any)
+	if !ok || len(posts) != 1 {
+		t.Fatalf("posts=%#v", out["posts"])
+	}
+	if post := posts[0].(map[string]any); post["title"] != "Notes" || post["secret"] != nil {
+		t.Fatalf("visible post=%v", post)
+	}
+	if profile := out["profile"].(map[string]any); profile["bio"] != "bio: math" {
+		t.Fatalf("accessor override=%v", profile)
+	}
+}
+
+func TestSerializeWalksContainersAndOverrides(t *testing.T) {
+	page := pagination.New([]serialUser{newSerialUser()}, 1, 1, 15, "/users")
+	raw, err := orm.ToJSON(map[string]any{"users": page})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Users struct {
+			Data  []map[string]any `json:"data"`
+			Total int              `json:"total"`
+		} `json:"users"`
+	}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Users.Total != 1 || len(decoded.Users.Data) != 1 {
+		t.Fatalf("page=%s", raw)
+	}
+	if _, ok := decoded.Users.Data[0]["password"]; ok {
+		t.Fatalf("password leaked through paginator: %s", raw)
+	}
+
+	users := []serialUser{newSerialUser()}
+	raw, err = json.Marshal(orm.MakeVisible(users, "password").MakeHidden("email"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []map[string]any
+	if err := json.Unmarshal(raw, &list); err != nil {
+		t.Fatal(err)
+	}
+	if list[0]["password"] != "hash" || list[0]["email"] != nil || list[0]["RememberToken"] != nil {
+		t.Fatalf("overrides=%s", raw)
+	}
+
+	plain := map[string]any{"ok": true, "n": []int{1, 2}}
+	if raw, _ := orm.ToJSON(plain); string(raw) != `{"n":[1,2],"ok":true}` {
+		t.Fatalf("plain=%s", raw)
+	}
+}
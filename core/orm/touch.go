@@ -23,7 +23,7 @@ func Touch[T any](model *T, columns ...string) error {
 		}
 		attrs[col] = now
 	}
-	_, err = Query[T]().Where(KeyName[T](), keyVal).Update(attrs)
+	_, err = Query[T]().WhereKey(keyVal).Update(attrs)
 	if err != nil {
 		return err
 	}
@@ -34,7 +34,7 @@ func Touch[T any](model *T, columns ...string) error {
 
 // TouchByID updates updated_at for a row by id.
 func TouchByID[T any](id any) error {
-	_, err := Query[T]().Where(KeyName[T](), id).Update(map[string]any{
+	_, err := Query[T]().WhereKey(id).Update(map[string]any{
 		"updated_at": time.Now(),
 	})
 	return err
@@ -1,6 +1,7 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
@@ -11,25 +12,41 @@ import (
 // Use QueryTx / query.New(tx, ...) inside fn so ORM operations participate.
 // On success the transaction is committed; on error it is rolled back.
 func Transaction(fn func(tx *sql.Tx) error) (err error) {
+	return TransactionContext(context.Background(), fn)
+}
+
+// TransactionContext is Transaction bound to ctx; cancelling ctx rolls back.
+// Optional opts configure isolation level and read-only mode.
+func TransactionContext(ctx context.Context, fn func(tx *sql.Tx) error, opts ...*sql.TxOptions) (err error) {
 	if DB == nil {
 		return fmt.Errorf("orm database is not configured")
 	}
 	if fn == nil {
 		return fmt.Errorf("transaction callback is nil")
 	}
-	tx, err := DB.Begin()
+	var txOpts *sql.TxOptions
+	if len(opts) > 0 {
+		txOpts = opts[0]
+	}
+	tx, err := DB.BeginTx(ctx, txOpts)
 	if err != nil {
 		return err
 	}
+	trackTransaction(tx)
 	defer func() {
 		if p := recover(); p != nil {
 			_ = tx.Rollback()
+			finishTransaction(tx, false)
 			panic(p)
 		} else if err != nil {
 			_ = tx.Rollback()
 		} else {
 			err = tx.Commit()
+			if err == nil && readDB != nil {
+				query.RecordWrite(DB)
+			}
 		}
+		finishTransaction(tx, err == nil)
 	}()
 	err = fn(tx)
 	return err
@@ -43,9 +60,11 @@ func QueryTx[T any](tx *sql.Tx) *Querier[T] {
 // QueryOn starts a model query on any DBTX (*sql.DB or *sql.Tx).
 func QueryOn[T any](db query.DBTX) *Querier[T] {
 	table := Table[T]()
+	tx, _ := db.(*sql.Tx)
 	return &Querier[T]{
-		builder:    query.New(db, Driver, table),
+		builder:    newBuilder(db, table),
 		table:      table,
 		softDelete: hasSoftDeletes[T](),
+		tx:         tx,
 	}
 }
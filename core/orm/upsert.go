@@ -3,8 +3,6 @@ package orm
 import (
 	"fmt"
 	"time"
-
-	"github.com/zatrano/framework/core/database/query"
 )
 
 // Upsert inserts attrs or updates listed columns when uniqueBy conflicts.
@@ -47,5 +45,9 @@ func Upsert[T any](attrs map[string]any, uniqueBy []string, update ...string) (i
 			updateCols = append(updateCols, "updated_at")
 		}
 	}
-	return query.New(DB, Driver, Table[T]()).Upsert(attrs, uniqueBy, updateCols...)
+	n, err := newBuilder(DB, Table[T]()).Upsert(attrs, uniqueBy, updateCols...)
+	if err == nil {
+		flushRemembered(Table[T]())
+	}
+	return n, err
 }
@@ -0,0 +1,57 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrStaleModel is returned by Save when a versioned model was changed by
+// someone else after it was loaded.
+var ErrStaleModel = errors.New("stale model")
+
+// VersionColumn models use optimistic locking on the returned column. Models
+// with a "version" column opt in without implementing it.
+type VersionColumn interface {
+	VersionColumn() string
+}
+
+// versionColumn returns the optimistic lock column for T, or "" when T is not versioned.
+func versionColumn[T any]() string {
+	var zero T
+	if v, ok := any(&zero).(VersionColumn); ok {
+		return v.VersionColumn()
+	}
+	rv := reflect.ValueOf(&zero).Elem()
+	if rv.Kind() != reflect.Struct {
+		return ""
+	}
+	if _, ok := fieldValueByColumn(rv, "version"); ok {
+		return "version"
+	}
+	return ""
+}
+
+// loadedVersion returns the version the model was loaded with: the
+// SyncOriginal snapshot when it belongs to this row, else the field value.
+func loadedVersion[T any](model *T, column string, key any) (int64, error) {
+	value, _ := attribute(model, column)
+	if snap, ok := GetOriginal(model).(map[string]any); ok && snapshotKey[T](snap) == fmt.Sprint(key) {
+		if original, ok := snap[column]; ok && original != nil {
+			value = original
+		}
+	}
+	if value == nil {
+		return 0, nil
+	}
+	version, err := toInt64(value)
+	if err != nil {
+		return 0, fmt.Errorf("version column [%s]: %w", column, err)
+	}
+	return version, nil
+}
+
+func snapshotKey[T any](snap map[string]any) string {
+	key, _ := rowKey(KeyNames[T](), snap)
+	return fmt.Sprint(key)
+}
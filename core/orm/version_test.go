@@ -0,0 +1,131 @@
+package orm_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/orm"
+)
+
+type versionedModel struct {
+	orm.Model
+	Title   string `db:"title"`
+	Version int64  `db:"version"`
+}
+
+func (versionedModel) TableName() string { return "versioned_models" }
+
+type revisionModel struct {
+	orm.Model
+	Title    string `db:"title"`
+	Revision int64  `db:"revision"`
+}
+
+func (revisionModel) TableName() string     { return "revision_models" }
+func (revisionModel) VersionColumn() string { return "revision" }
+
+func setupVersionDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sqlStr := range []string{
+		`CREATE TABLE versioned_models (id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT, version INTEGER, created_at DATETIME, updated_at DATETIME)`,
+		`CREATE TABLE revision_models (id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT, revision INTEGER, created_at DATETIME, updated_at DATETIME)`,
+	} {
+		if _, err := db.Exec(sqlStr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	orm.Configure(db, "sqlite")
+	return db
+}
+
+func TestSaveOptimisticLocking(t *testing.T) {
+	db := setupVersionDB(t)
+	defer db.Close()
+
+	created, err := orm.Create[versionedModel](map[string]any{"title": "draft"})
+	if err != nil || created.Version != 1 {
+		t.Fatalf("create=%+v err=%v", created, err)
+	}
+
+	first, _ := orm.Find[versionedModel](created.ID)
+	second, _ := orm.Find[versionedModel](created.ID)
+
+	first.Title = "first edit"
+	if err := orm.Save(first); err != nil {
+		t.Fatal(err)
+	}
+	if first.Version != 2 || orm.IsDirty(first) {
+		t.Fatalf("after save version=%d dirty=%v", first.Version, orm.IsDirty(first))
+	}
+
+	second.Title = "second edit"
+	err = orm.Save(second)
+	if !errors.Is(err, orm.ErrStaleModel) {
+		t.Fatalf("expected stale model, got %v", err)
+	}
+	if second.Version != 1 {
+		t.Fatalf("stale model version changed to %d", second.Version)
+	}
+
+	stored, _ := orm.Find[versionedModel](created.ID)
+	if stored.Title != "first edit" || stored.Version != 2 {
+		t.Fatalf("stored=%+v", stored)
+	}
+
+	first.Title = "again"
+	if err := orm.Save(first); err != nil || first.Version != 3 {
+		t.Fatalf("second save version=%d err=%v", first.Version, err)
+	}
+}
+
+func TestSaveVersionColumnInterface(t *testing.T) {
+	db := setupVersionDB(t)
+	defer db.Close()
+
+	model := &revisionModel{Title: "new"}
+	if err := orm.Save(model); err != nil || model.Revision != 1 {
+		t.Fatalf("insert revision=%d err=%v", model.Revision, err)
+	}
+	model.Title = "changed"
+	if err := orm.Save(model); err != nil || model.Revision != 2 {
+		t.Fatalf("update revision=%d err=%v", model.Revision, err)
+	}
+	if _, err := db.Exec(`UPDATE revision_models SET revision = 5 WHERE id = ?`, model.ID); err != nil {
+		t.Fatal(err)
+	}
+	model.Title = "lost"
+	if err := orm.Save(model); !errors.Is(err, orm.ErrStaleModel) {
+		t.Fatalf("expected stale model, got %v", err)
+	}
+}
+
+func TestQuerierUpdateIncrementsVersion(t *testing.T) {
+	db := setupVersionDB(t)
+	defer db.Close()
+
+	created, _ := orm.Create[versionedModel](map[string]any{"title": "a"})
+
+	n, err := orm.Query[versionedModel]().Where("id", created.ID).Update(map[string]any{"title": "b"})
+	if err != nil || n != 1 {
+		t.Fatalf("update n=%d err=%v", n, err)
+	}
+	n, err = orm.Query[versionedModel]().Where("id", created.ID).Update(map[string]any{"title": "c", "version": 1})
+	if err != nil || n != 0 {
+		t.Fatalf("guarded update with old version n=%d err=%v", n, err)
+	}
+	n, err = orm.Query[versionedModel]().Where("id", created.ID).Update(map[string]any{"title": "c", "version": 2})
+	if err != nil || n != 1 {
+		t.Fatalf("guarded update n=%d err=%v", n, err)
+	}
+	stored, _ := orm.Find[versionedModel](created.ID)
+	if stored.Title != "c" || stored.Version != 3 {
+		t.Fatalf("stored=%+v", stored)
+	}
+}
@@ -0,0 +1,101 @@
+package pagination
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// Cursor holds a page of results fetched by keyset (cursor) pagination.
+type Cursor[T any] struct {
+	Data        []T    `json:"data"`
+	PerPage     int    `json:"per_page"`
+	Path        string `json:"path,omitempty"`
+	NextCursor  string `json:"next_cursor,omitempty"`
+	PrevCursor  string `json:"prev_cursor,omitempty"`
+	NextPageURL string `json:"next_page_url,omitempty"`
+	PrevPageURL string `json:"prev_page_url,omitempty"`
+}
+
+// NewCursor creates a cursor paginator. Empty next/prev mean no such page.
+func NewCursor[T any](items []T, perPage int, path, next, prev string) *Cursor[T] {
+	if perPage < 1 {
+		perPage = 15
+	}
+	return &Cursor[T]{
+		Data:        items,
+		PerPage:     perPage,
+		Path:        path,
+		NextCursor:  next,
+		PrevCursor:  prev,
+		NextPageURL: cursorURL(path, next),
+		PrevPageURL: cursorURL(path, prev),
+	}
+}
+
+// CursorParams is the decoded form of an opaque cursor: the ordering column
+// values of the boundary row and the direction it points to.
+type CursorParams struct {
+	Values       map[string]any
+	PointsToNext bool
+}
+
+// EncodeCursor encodes params as an opaque URL-safe base64 string.
+func EncodeCursor(params CursorParams) string {
+	payload := make(map[string]any, len(params.Values)+1)
+	for column, value := range params.Values {
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		payload[column] = value
+	}
+	payload["_pointsToNextItems"] = params.PointsToNext
+	raw, _ := json.Marshal(payload)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor decodes a cursor produced by EncodeCursor. Whole numbers decode
+// as int64 so large keys survive the round trip.
+func DecodeCursor(cursor string) (*CursorParams, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	payload := make(map[string]any)
+	if err := dec.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	next, ok := payload["_pointsToNextItems"].(bool)
+	if !ok {
+		return nil, fmt.Errorf("invalid cursor: missing direction")
+	}
+	delete(payload, "_pointsToNextItems")
+	for column, value := range payload {
+		if n, ok := value.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				payload[column] = i
+			} else if f, err := n.Float64(); err == nil {
+				payload[column] = f
+			}
+		}
+	}
+	return &CursorParams{Values: payload, PointsToNext: next}, nil
+}
+
+func cursorURL(path, cursor string) string {
+	if path == "" || cursor == "" {
+		return ""
+	}
+	sep := "?"
+	for i := 0; i < len(path); i++ {
+		if path[i] == '?' {
+			sep = "&"
+			break
+		}
+	}
+	return path + sep + "cursor=" + url.QueryEscape(cursor)
+}
@@ -16,3 +16,18 @@ func TestPaginator(t *testing.T) {
 		t.Fatal("expected next/prev urls")
 	}
 }
+
+func TestCursorRoundTrip(t *testing.T) {
+	encoded := pagination.EncodeCursor(pagination.CursorParams{
+		Values:       map[string]any{"id": int64(9007199254740993), "name": []byte("ada")},
+		PointsToNext: true,
+	})
+	params, err := pagination.DecodeCursor(encoded)
+	if err != nil || !params.PointsToNext || params.Values["id"] != int64(9007199254740993) || params.Values["name"] != "ada" {
+		t.Fatalf("params=%+v err=%v", params, err)
+	}
+	p := pagination.NewCursor([]int{1}, 10, "/api?sort=id", encoded, "")
+	if p.NextPageURL != "/api?sort=id&cursor="+encoded || p.PrevPageURL != "" {
+		t.Fatalf("unexpected cursor paginator: %#v", p)
+	}
+}
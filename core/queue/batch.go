@@ -0,0 +1,650 @@
+package queue
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zatrano/framework/core/database/query"
+	"github.com/zatrano/framework/core/support/uuid"
+)
+
+// ErrBatchNotFound is returned when no batch matches an id.
+var ErrBatchNotFound = errors.New("batch not found")
+
+// Batch tracks a group of jobs dispatched together. Follow-up jobs are queued
+// with "batch_id" added to their payload: Then once every job succeeded, Catch
+// on the first failure and Finally once every job has run.
+type Batch struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	Queue         string     `json:"queue"`
+	TotalJobs     int        `json:"total_jobs"`
+	PendingJobs   int        `json:"pending_jobs"`
+	FailedJobs    int        `json:"failed_jobs"`
+	FailedJobIDs  []string   `json:"failed_job_ids"`
+	AllowFailures bool       `json:"allow_failures"`
+	Then          []NamedJob `json:"then,omitempty"`
+	Catch         []NamedJob `json:"catch,omitempty"`
+	Finally       []NamedJob `json:"finally,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CancelledAt   *time.Time `json:"cancelled_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+}
+
+// ProcessedJobs returns the number of jobs that succeeded.
+func (b *Batch) ProcessedJobs() int {
+	return b.TotalJobs - b.PendingJobs
+}
+
+// Progress returns the percentage of jobs that succeeded.
+func (b *Batch) Progress() int {
+	if b.TotalJobs == 0 {
+		return 100
+	}
+	return b.ProcessedJobs() * 100 / b.TotalJobs
+}
+
+// Finished reports whether every job succeeded.
+func (b *Batch) Finished() bool { return b.FinishedAt != nil }
+
+// Cancelled reports whether the batch was cancelled.
+func (b *Batch) Cancelled() bool { return b.CancelledAt != nil }
+
+// HasFailures reports whether any job of the batch failed.
+func (b *Batch) HasFailures() bool { return b.FailedJobs > 0 }
+
+// ranOnce reports whether every job has either succeeded or failed.
+func (b *Batch) ranOnce() bool { return b.PendingJobs-b.FailedJobs == 0 }
+
+// BatchProgress summarizes a batch for the queue dashboard.
+type BatchProgress struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Total     int    `json:"total"`
+	Pending   int    `json:"pending"`
+	Processed int    `json:"processed"`
+	Failed    int    `json:"failed"`
+	Progress  int    `json:"progress"`
+	Finished  bool   `json:"finished"`
+	Cancelled bool   `json:"cancelled"`
+}
+
+// Summary returns the batch's progress summary.
+func (b *Batch) Summary() BatchProgress {
+	return BatchProgress{
+		ID:        b.ID,
+		Name:      b.Name,
+		Total:     b.TotalJobs,
+		Pending:   b.PendingJobs,
+		Processed: b.ProcessedJobs(),
+		Failed:    b.FailedJobs,
+		Progress:  b.Progress(),
+		Finished:  b.Finished(),
+		Cancelled: b.Cancelled(),
+	}
+}
+
+// BatchStore persists batches. JobSucceeded and JobFailed update the counts
+// atomically and return the batch as it is afterwards.
+type BatchStore interface {
+	Store(batch *Batch) error
+	Find(id string) (*Batch, error)
+	List(limit int) ([]Batch, error)
+	JobSucceeded(id string) (*Batch, error)
+	JobFailed(id, failedJobID string) (*Batch, error)
+	Cancel(id string) error
+	MarkFinished(id string) error
+	Delete(id string) error
+}
+
+// MemoryBatchStore keeps batches in memory.
+type MemoryBatchStore struct {
+	mu      sync.Mutex
+	batches map[string]*Batch
+}
+
+// NewMemoryBatchStore creates an in-memory batch store.
+func NewMemoryBatchStore() *MemoryBatchStore {
+	return &MemoryBatchStore{batches: map[string]*Batch{}}
+}
+
+// Store saves a batch.
+func (s *MemoryBatchStore) Store(batch *Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *batch
+	s.batches[batch.ID] = &stored
+	return nil
+}
+
+// Find returns a batch by id.
+func (s *MemoryBatchStore) Find(id string) (*Batch, error) {
+	return s.update(id, func(*Batch) {})
+}
+
+// List returns the most recent batches.
+func (s *MemoryBatchStore) List(limit int) ([]Batch, error) {
+	s.mu.Lock()
+	out := make([]Batch, 0, len(s.batches))
+	for _, batch := range s.batches {
+		out = append(out, *batch)
+	}
+	s.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// JobSucceeded decrements the pending jobs.
+func (s *MemoryBatchStore) JobSucceeded(id string) (*Batch, error) {
+	return s.update(id, func(b *Batch) { b.PendingJobs-- })
+}
+
+// JobFailed records a failed job.
+func (s *MemoryBatchStore) JobFailed(id, failedJobID string) (*Batch, error) {
+	return s.update(id, func(b *Batch) {
+		b.FailedJobs++
+		b.FailedJobIDs = append(b.FailedJobIDs, failedJobID)
+	})
+}
+
+// Cancel marks a batch as cancelled.
+func (s *MemoryBatchStore) Cancel(id string) error {
+	_, err := s.update(id, func(b *Batch) {
+		now := time.Now()
+		b.CancelledAt = &now
+	})
+	return err
+}
+
+// MarkFinished marks a batch as finished.
+func (s *MemoryBatchStore) MarkFinished(id string) error {
+	_, err := s.update(id, func(b *Batch) {
+		now := time.Now()
+		b.FinishedAt = &now
+	})
+	return err
+}
+
+// Delete removes a batch.
+func (s *MemoryBatchStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.batches, id)
+	return nil
+}
+
+func (s *MemoryBatchStore) update(id string, fn func(*Batch)) (*Batch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch, ok := s.batches[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: [%s]", ErrBatchNotFound, id)
+	}
+	fn(batch)
+	out := *batch
+	out.FailedJobIDs = append([]string(nil), batch.FailedJobIDs...)
+	return &out, nil
+}
+
+// DatabaseBatchStore stores batches in a database table.
+type DatabaseBatchStore struct {
+	db     *sql.DB
+	driver string
+	table  string
+}
+
+// NewDatabaseBatchStore creates a database-backed batch store. The driver
+// name picks the placeholder style, as for query.New.
+func NewDatabaseBatchStore(db *sql.DB, driver, table string) *DatabaseBatchStore {
+	if table == "" {
+		table = "job_batches"
+	}
+	return &DatabaseBatchStore{db: db, driver: driver, table: table}
+}
+
+// EnsureTable creates the job batches table with SQLite DDL, for tests and
+// SQLite apps; other drivers use the job_batches migration.
+func (s *DatabaseBatchStore) EnsureTable() error {
+	_, err := s.db.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(36) PRIMARY KEY,
+	name VARCHAR(255) NOT NULL DEFAULT '',
+	total_jobs INTEGER NOT NULL,
+	pending_jobs INTEGER NOT NULL,
+	failed_jobs INTEGER NOT NULL,
+	failed_job_ids TEXT NOT NULL,
+	options TEXT NOT NULL,
+	cancelled_at DATETIME NULL,
+	created_at DATETIME NOT NULL,
+	finished_at DATETIME NULL
+)`, s.table))
+	return err
+}
+
+// batchOptions holds the batch settings stored in the options column.
+type batchOptions struct {
+	Queue         string     `json:"queue"`
+	AllowFailures bool       `json:"allow_failures"`
+	Then          []NamedJob `json:"then,omitempty"`
+	Catch         []NamedJob `json:"catch,omitempty"`
+	Finally       []NamedJob `json:"finally,omitempty"`
+}
+
+const batchColumns = `id, name, total_jobs, pending_jobs, failed_jobs, failed_job_ids, options, cancelled_at, created_at, finished_at`
+
+// Store saves a new batch.
+func (s *DatabaseBatchStore) Store(batch *Batch) error {
+	options, err := json.Marshal(batchOptions{
+		Queue:         batch.Queue,
+		AllowFailures: batch.AllowFailures,
+		Then:          batch.Then,
+		Catch:         batch.Catch,
+		Finally:       batch.Finally,
+	})
+	if err != nil {
+		return err
+	}
+	failedIDs, err := json.Marshal(nonNilStrings(batch.FailedJobIDs))
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		s.bind(fmt.Sprintf(`INSERT INTO %s (id, name, total_jobs, pending_jobs, failed_jobs, failed_job_ids, options, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, s.table)),
+		batch.ID, batch.Name, batch.TotalJobs, batch.PendingJobs, batch.FailedJobs, string(failedIDs), string(options), batch.CreatedAt.Format("2006-01-02 15:04:05"),
+	)
+	return err
+}
+
+// Find returns a batch by id.
+func (s *DatabaseBatchStore) Find(id string) (*Batch, error) {
+	batch, err := scanBatch(s.db.QueryRow(s.bind(fmt.Sprintf(`SELECT %s FROM %s WHERE id = ?`, batchColumns, s.table)), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: [%s]", ErrBatchNotFound, id)
+	}
+	return batch, err
+}
+
+// List returns the most recent batches.
+func (s *DatabaseBatchStore) List(limit int) ([]Batch, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.db.Query(s.bind(fmt.Sprintf(`SELECT %s FROM %s ORDER BY created_at DESC LIMIT ?`, batchColumns, s.table)), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]Batch, 0)
+	for rows.Next() {
+		batch, err := scanBatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *batch)
+	}
+	return out, rows.Err()
+}
+
+// JobSucceeded decrements the pending jobs.
+func (s *DatabaseBatchStore) JobSucceeded(id string) (*Batch, error) {
+	return s.update(id, func(tx *sql.Tx) error {
+		_, err := tx.Exec(s.bind(fmt.Sprintf(`UPDATE %s SET pending_jobs = pending_jobs - 1 WHERE id = ?`, s.table)), id)
+		return err
+	})
+}
+
+// JobFailed records a failed job. The counter is bumped first so the row is
+// locked before failed_job_ids is read and appended to.
+func (s *DatabaseBatchStore) JobFailed(id, failedJobID string) (*Batch, error) {
+	return s.update(id, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(s.bind(fmt.Sprintf(`UPDATE %s SET failed_jobs = failed_jobs + 1 WHERE id = ?`, s.table)), id); err != nil {
+			return err
+		}
+		var raw string
+		if err := tx.QueryRow(s.bind(fmt.Sprintf(`SELECT failed_job_ids FROM %s WHERE id = ?`, s.table)), id).Scan(&raw); err != nil {
+			return err
+		}
+		var failedIDs []string
+		if err := json.Unmarshal([]byte(raw), &failedIDs); err != nil {
+			return err
+		}
+		encoded, err := json.Marshal(append(nonNilStrings(failedIDs), failedJobID))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(s.bind(fmt.Sprintf(`UPDATE %s SET failed_job_ids = ? WHERE id = ?`, s.table)), string(encoded), id)
+		return err
+	})
+}
+
+// Cancel marks a batch as cancelled.
+func (s *DatabaseBatchStore) Cancel(id string) error {
+	return s.mark(id, "cancelled_at")
+}
+
+// MarkFinished marks a batch as finished.
+func (s *DatabaseBatchStore) MarkFinished(id string) error {
+	return s.mark(id, "finished_at")
+}
+
+// Delete removes a batch.
+func (s *DatabaseBatchStore) Delete(id string) error {
+	_, err := s.db.Exec(s.bind(fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, s.table)), id)
+	return err
+}
+
+func (s *DatabaseBatchStore) mark(id, column string) error {
+	res, err := s.db.Exec(s.bind(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE id = ?`, s.table, column)), time.Now().Format("2006-01-02 15:04:05"), id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: [%s]", ErrBatchNotFound, id)
+	}
+	return nil
+}
+
+// update runs fn and re-reads the batch in one transaction. fn starts with
+// an UPDATE of the batch row, which holds the row lock until the commit.
+func (s *DatabaseBatchStore) update(id string, fn func(tx *sql.Tx) error) (*Batch, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	err = fn(tx)
+	var batch *Batch
+	if err == nil {
+		batch, err = scanBatch(tx.QueryRow(s.bind(fmt.Sprintf(`SELECT %s FROM %s WHERE id = ?`, batchColumns, s.table)), id))
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: [%s]", ErrBatchNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return batch, tx.Commit()
+}
+
+// bind rewrites ? placeholders for the store's driver.
+func (s *DatabaseBatchStore) bind(stmt string) string {
+	return query.Rebind(s.driver, stmt)
+}
+
+func scanBatch(row interface{ Scan(...any) error }) (*Batch, error) {
+	var batch Batch
+	var failedIDs, options, createdAt string
+	var cancelledAt, finishedAt sql.NullString
+	if err := row.Scan(&batch.ID, &batch.Name, &batch.TotalJobs, &batch.PendingJobs, &batch.FailedJobs, &failedIDs, &options, &cancelledAt, &createdAt, &finishedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(failedIDs), &batch.FailedJobIDs); err != nil {
+		return nil, err
+	}
+	var opts batchOptions
+	if err := json.Unmarshal([]byte(options), &opts); err != nil {
+		return nil, err
+	}
+	batch.Queue = opts.Queue
+	batch.AllowFailures = opts.AllowFailures
+	batch.Then, batch.Catch, batch.Finally = opts.Then, opts.Catch, opts.Finally
+	if t, ok := parseTimestamp(createdAt); ok {
+		batch.CreatedAt = t
+	}
+	if t, ok := parseTimestamp(cancelledAt.String); ok {
+		batch.CancelledAt = &t
+	}
+	if t, ok := parseTimestamp(finishedAt.String); ok {
+		batch.FinishedAt = &t
+	}
+	return &batch, nil
+}
+
+func parseTimestamp(value string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339Nano} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// PendingBatch configures a batch before it is dispatched.
+type PendingBatch struct {
+	manager *Manager
+	batch   Batch
+	jobs    []NamedJob
+}
+
+// Batch starts a batch of independent jobs:
+//
+//	batch, err := app.Queue().Batch(jobs...).
+//		Name("import users").
+//		Then(queue.NamedJob{Name: "import.done"}).
+//		Catch(queue.NamedJob{Name: "import.failed"}).
+//		Dispatch()
+func (m *Manager) Batch(jobs ...NamedJob) *PendingBatch {
+	return &PendingBatch{manager: m, jobs: jobs}
+}
+
+// Name names the batch.
+func (p *PendingBatch) Name(name string) *PendingBatch {
+	p.batch.Name = name
+	return p
+}
+
+// OnQueue pushes the jobs and follow-up jobs onto the named queue.
+func (p *PendingBatch) OnQueue(name string) *PendingBatch {
+	p.batch.Queue = name
+	return p
+}
+
+// AllowFailures keeps the batch running after a job fails; otherwise the
+// first failure cancels it.
+func (p *PendingBatch) AllowFailures() *PendingBatch {
+	p.batch.AllowFailures = true
+	return p
+}
+
+// Then queues jobs once every job of the batch succeeded.
+func (p *PendingBatch) Then(jobs ...NamedJob) *PendingBatch {
+	p.batch.Then = append(p.batch.Then, jobs...)
+	return p
+}
+
+// Catch queues jobs when the first job of the batch fails.
+func (p *PendingBatch) Catch(jobs ...NamedJob) *PendingBatch {
+	p.batch.Catch = append(p.batch.Catch, jobs...)
+	return p
+}
+
+// Finally queues jobs once every job of the batch has run, successfully or not.
+func (p *PendingBatch) Finally(jobs ...NamedJob) *PendingBatch {
+	p.batch.Finally = append(p.batch.Finally, jobs...)
+	return p
+}
+
+// Dispatch stores the batch and pushes its jobs. An empty batch finishes
+// straight away. A job that cannot be pushed is recorded as a failed job of
+// the batch and the rest are still pushed; the first push error is returned
+// with the batch.
+func (p *PendingBatch) Dispatch() (*Batch, error) {
+	m := p.manager
+	batch := p.batch
+	q := m.Queue(batch.Queue)
+	if q == nil {
+		return nil, fmt.Errorf("queue [%s] is not configured", batch.Queue)
+	}
+	batch.ID = uuid.New()
+	batch.TotalJobs = len(p.jobs)
+	batch.PendingJobs = len(p.jobs)
+	batch.CreatedAt = time.Now()
+	store := m.BatchStore()
+	if err := store.Store(&batch); err != nil {
+		return nil, err
+	}
+	if len(p.jobs) == 0 {
+		if err := store.MarkFinished(batch.ID); err != nil {
+			return nil, err
+		}
+		m.queueBatchJobs(&batch, batch.Then)
+		m.queueBatchJobs(&batch, batch.Finally)
+		return store.Find(batch.ID)
+	}
+	var pushErr error
+	for _, job := range p.jobs {
+		job.BatchID = batch.ID
+		if err := m.pushJob(q, job); err != nil {
+			failedID, logErr := m.logFailure(batch.Queue, job, err)
+			if pushErr == nil {
+				pushErr = errors.Join(err, logErr)
+			}
+			m.batchJobFailed(job, failedID)
+		}
+	}
+	found, err := store.Find(batch.ID)
+	if err != nil {
+		return nil, err
+	}
+	return found, pushErr
+}
+
+// syncBatchJob runs a batch job on a SyncQueue, which bypasses process, and
+// records the outcome on the batch like a worker would. A handler error is
+// recorded as a failed job rather than returned, so the rest of the batch
+// still runs; only an error recording the failure is returned.
+func (m *Manager) syncBatchJob(q Queue, job NamedJob, delay ...time.Duration) error {
+	if m.batchCancelled(job) {
+		m.batchJobSucceeded(job)
+		return nil
+	}
+	if err := q.Push(job, delay...); err != nil {
+		failedID, logErr := m.logFailure(m.defaultQueue, job, err)
+		m.batchJobFailed(job, failedID)
+		return logErr
+	}
+	m.batchJobSucceeded(job)
+	return nil
+}
+
+// SetBatchStore replaces the store batches are kept in.
+func (m *Manager) SetBatchStore(store BatchStore) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batches = store
+}
+
+// BatchStore returns the store batches are kept in.
+func (m *Manager) BatchStore() BatchStore {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.batches
+}
+
+// FindBatch returns a batch by id.
+func (m *Manager) FindBatch(id string) (*Batch, error) {
+	return m.BatchStore().Find(id)
+}
+
+// CancelBatch cancels a batch; its remaining jobs are skipped when reserved.
+func (m *Manager) CancelBatch(id string) error {
+	return m.BatchStore().Cancel(id)
+}
+
+// BatchProgress returns the progress of the most recent batches.
+func (m *Manager) BatchProgress(limit int) ([]BatchProgress, error) {
+	batches, err := m.BatchStore().List(limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]BatchProgress, 0, len(batches))
+	for i := range batches {
+		out = append(out, batches[i].Summary())
+	}
+	return out, nil
+}
+
+// batchCancelled reports whether the job belongs to a cancelled batch.
+func (m *Manager) batchCancelled(job NamedJob) bool {
+	if job.BatchID == "" {
+		return false
+	}
+	batch, err := m.BatchStore().Find(job.BatchID)
+	return err == nil && batch.Cancelled()
+}
+
+// batchJobSucceeded records a successful batch job and queues Then and
+// Finally once they are due.
+func (m *Manager) batchJobSucceeded(job NamedJob) {
+	if job.BatchID == "" {
+		return
+	}
+	store := m.BatchStore()
+	batch, err := store.JobSucceeded(job.BatchID)
+	if err != nil {
+		return
+	}
+	if batch.PendingJobs == 0 {
+		_ = store.MarkFinished(batch.ID)
+		if !batch.Cancelled() {
+			m.queueBatchJobs(batch, batch.Then)
+		}
+	}
+	if batch.ranOnce() {
+		m.queueBatchJobs(batch, batch.Finally)
+	}
+}
+
+// batchJobFailed records a failed batch job, cancels the batch unless it
+// allows failures, and queues Catch on the first failure and Finally once
+// every job has run.
+func (m *Manager) batchJobFailed(job NamedJob, failedJobID string) {
+	if job.BatchID == "" {
+		return
+	}
+	store := m.BatchStore()
+	batch, err := store.JobFailed(job.BatchID, failedJobID)
+	if err != nil {
+		return
+	}
+	if batch.FailedJobs == 1 {
+		if !batch.AllowFailures {
+			_ = store.Cancel(batch.ID)
+		}
+		m.queueBatchJobs(batch, batch.Catch)
+	}
+	if batch.ranOnce() {
+		m.queueBatchJobs(batch, batch.Finally)
+	}
+}
+
+func (m *Manager) queueBatchJobs(batch *Batch, jobs []NamedJob) {
+	q := m.Queue(batch.Queue)
+	if q == nil {
+		return
+	}
+	for _, job := range jobs {
+		payload := make(map[string]any, len(job.Payload)+1)
+		for k, v := range job.Payload {
+			payload[k] = v
+		}
+		payload["batch_id"] = batch.ID
+		job.Payload = payload
+		_ = m.pushJob(q, job)
+	}
+}
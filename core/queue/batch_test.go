@@ -0,0 +1,247 @@
+package queue_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zatrano/framework/core/queue"
+)
+
+func setupBatchQueue(t *testing.T) (*queue.Manager, func()) {
+	t.Helper()
+	db, m := setupFailedQueue(t)
+	batches := queue.NewDatabaseBatchStore(db, "sqlite", "job_batches")
+	if err := batches.EnsureTable(); err != nil {
+		t.Fatal(err)
+	}
+	m.SetBatchStore(batches)
+	return m, func() { db.Close() }
+}
+
+func TestBatchThenAndFinally(t *testing.T) {
+	m, done := setupBatchQueue(t)
+	defer done()
+
+	followUps := map[string]any{}
+	m.Register("row.import", func(map[string]any) error { return nil })
+	for _, name := range []string{"import.done", "import.failed", "import.cleanup"} {
+		m.Register(name, func(payload map[string]any) error {
+			followUps[name] = payload["batch_id"]
+			return nil
+		})
+	}
+
+	jobs := make([]queue.NamedJob, 3)
+	for i := range jobs {
+		jobs[i] = queue.NamedJob{Name: "row.import", Payload: map[string]any{"row": i}}
+	}
+	batch, err := m.Batch(jobs...).Name("import").
+		Then(queue.NamedJob{Name: "import.done"}).
+		Catch(queue.NamedJob{Name: "import.failed"}).
+		Finally(queue.NamedJob{Name: "import.cleanup"}).
+		Dispatch()
+	if err != nil || batch.TotalJobs != 3 || batch.PendingJobs != 3 {
+		t.Fatalf("batch=%+v err=%v", batch, err)
+	}
+
+	_ = m.Work()
+	found, _ := m.FindBatch(batch.ID)
+	if found.ProcessedJobs() != 1 || found.Progress() != 33 || found.Finished() {
+		t.Fatalf("progress=%+v", found)
+	}
+	queue.NewWorker(m, queue.WorkerOptions{StopWhenEmpty: true}).Run(context.Background())
+
+	found, _ = m.FindBatch(batch.ID)
+	if !found.Finished() || found.Progress() != 100 || found.HasFailures() {
+		t.Fatalf("finished=%+v", found)
+	}
+	if followUps["import.done"] != batch.ID || followUps["import.cleanup"] != batch.ID || followUps["import.failed"] != nil {
+		t.Fatalf("follow ups=%v", followUps)
+	}
+	if stats := m.Stats(); len(stats.Batches) != 1 || stats.Batches[0].Name != "import" || stats.Batches[0].Processed != 3 {
+		t.Fatalf("stats batches=%+v", stats.Batches)
+	}
+}
+
+func TestBatchFailureCancelsAndAllowFailures(t *testing.T) {
+	m, done := setupBatchQueue(t)
+	defer done()
+
+	ran := 0
+	var followUps []string
+	m.Register("row.import", func(payload map[string]any) error {
+		ran++
+		if payload["bad"] == true {
+			return errors.New("bad row")
+		}
+		return nil
+	})
+	for _, name := range []string{"import.done", "import.failed", "import.cleanup"} {
+		m.Register(name, func(map[string]any) error {
+			followUps = append(followUps, name)
+			return nil
+		})
+	}
+	jobs := []queue.NamedJob{
+		{Name: "row.import", Payload: map[string]any{"bad": true}},
+		{Name: "row.import"},
+		{Name: "row.import"},
+	}
+	batch, err := m.Batch(jobs...).
+		Then(queue.NamedJob{Name: "import.done"}).
+		Catch(queue.NamedJob{Name: "import.failed"}).
+		Finally(queue.NamedJob{Name: "import.cleanup"}).
+		Dispatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue.NewWorker(m, queue.WorkerOptions{StopWhenEmpty: true}).Run(context.Background())
+	found, _ := m.FindBatch(batch.ID)
+	if ran != 1 || !found.Cancelled() || found.FailedJobs != 1 || len(found.FailedJobIDs) != 1 || found.Finished() {
+		t.Fatalf("ran=%d batch=%+v", ran, found)
+	}
+	if len(followUps) != 2 || followUps[0] != "import.failed" || followUps[1] != "import.cleanup" {
+		t.Fatalf("follow ups=%v", followUps)
+	}
+	if failed := m.Failed(); len(failed) != 1 || failed[0].UUID != found.FailedJobIDs[0] {
+		t.Fatalf("failed=%+v", failed)
+	}
+
+	ran, followUps = 0, nil
+	batch, _ = m.Batch(jobs...).AllowFailures().
+		Then(queue.NamedJob{Name: "import.done"}).
+		Finally(queue.NamedJob{Name: "import.cleanup"}).
+		Dispatch()
+	queue.NewWorker(m, queue.WorkerOptions{StopWhenEmpty: true}).Run(context.Background())
+	found, _ = m.FindBatch(batch.ID)
+	if ran != 3 || found.Cancelled() || found.PendingJobs != 1 || found.FailedJobs != 1 {
+		t.Fatalf("ran=%d batch=%+v", ran, found)
+	}
+	if len(followUps) != 1 || followUps[0] != "import.cleanup" {
+		t.Fatalf("follow ups=%v", followUps)
+	}
+
+	if _, err := m.FindBatch("missing"); !errors.Is(err, queue.ErrBatchNotFound) {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestBatchOnSyncQueue(t *testing.T) {
+	m := queue.NewManager("sync", map[string]queue.Queue{"sync": queue.NewSyncQueue()})
+
+	ran := []string{}
+	m.Register("row.import", func(payload map[string]any) error {
+		if payload["fail"] == true {
+			return errors.New("bad row")
+		}
+		ran = append(ran, "row")
+		return nil
+	})
+	for _, name := range []string{"import.done", "import.failed", "import.cleanup"} {
+		m.Register(name, func(map[string]any) error {
+			ran = append(ran, name)
+			return nil
+		})
+	}
+
+	batch, err := m.Batch(queue.NamedJob{Name: "row.import"}, queue.NamedJob{Name: "row.import"}).
+		Then(queue.NamedJob{Name: "import.done"}).
+		Finally(queue.NamedJob{Name: "import.cleanup"}).
+		Dispatch()
+	if err != nil || batch.PendingJobs != 0 || !batch.Finished() {
+		t.Fatalf("batch=%+v err=%v", batch, err)
+	}
+	if len(ran) != 4 || ran[2] != "import.done" || ran[3] != "import.cleanup" {
+		t.Fatalf("ran=%v", ran)
+	}
+
+	ran = nil
+	batch, err = m.Batch(
+		queue.NamedJob{Name: "row.import", Payload: map[string]any{"fail": true}},
+		queue.NamedJob{Name: "row.import"},
+	).
+		Then(queue.NamedJob{Name: "import.done"}).
+		Catch(queue.NamedJob{Name: "import.failed"}).
+		Finally(queue.NamedJob{Name: "import.cleanup"}).
+		Dispatch()
+	if err != nil || batch.FailedJobs != 1 || !batch.Cancelled() || batch.ProcessedJobs() != 1 {
+		t.Fatalf("failed batch=%+v err=%v", batch, err)
+	}
+	if len(ran) != 2 || ran[0] != "import.failed" || ran[1] != "import.cleanup" {
+		t.Fatalf("ran=%v", ran)
+	}
+	if failed := m.Failed(); len(failed) != 1 || failed[0].Job.BatchID != batch.ID {
+		t.Fatalf("failed=%v", failed)
+	}
+}
+
+func TestDatabaseBatchStoreConcurrentFailures(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "batches.sqlite")+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	store := queue.NewDatabaseBatchStore(db, "sqlite", "job_batches")
+	if err := store.EnsureTable(); err != nil {
+		t.Fatal(err)
+	}
+	batch := &queue.Batch{ID: "b1", TotalJobs: 10, PendingJobs: 10, AllowFailures: true, CreatedAt: time.Now()}
+	if err := store.Store(batch); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.JobFailed("b1", fmt.Sprintf("failed-%d", i)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	found, err := store.Find("b1")
+	if err != nil || found.FailedJobs != 10 || len(found.FailedJobIDs) != 10 {
+		t.Fatalf("batch=%+v err=%v", found, err)
+	}
+	if _, err := store.JobFailed("missing", "x"); !errors.Is(err, queue.ErrBatchNotFound) {
+		t.Fatalf("err=%v", err)
+	}
+}
+
+func TestDatabaseBatchStoreBindsPostgresPlaceholders(t *testing.T) {
+	db := openPostgresStyleDB(t)
+	defer db.Close()
+
+	store := queue.NewDatabaseBatchStore(db, "pgsql", "job_batches")
+	if err := store.EnsureTable(); err != nil {
+		t.Fatal(err)
+	}
+	batch := &queue.Batch{ID: "b-1", TotalJobs: 2, PendingJobs: 2, CreatedAt: time.Now()}
+	if err := store.Store(batch); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.JobSucceeded("b-1"); err != nil {
+		t.Fatal(err)
+	}
+	found, err := store.JobFailed("b-1", "job-2")
+	if err != nil || found.PendingJobs != 1 || found.FailedJobs != 1 || len(found.FailedJobIDs) != 1 {
+		t.Fatalf("batch=%+v err=%v", found, err)
+	}
+	if err := store.Cancel("b-1"); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := store.List(10); err != nil || len(list) != 1 || !list[0].Cancelled() {
+		t.Fatalf("list=%+v err=%v", list, err)
+	}
+	if err := store.Delete("b-1"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -20,12 +20,13 @@ type JobRow struct {
 
 // Stats summarizes queue health for the dashboard.
 type Stats struct {
-	Connection string         `json:"connection"`
-	Pending    int            `json:"pending"`
-	Reserved   int            `json:"reserved"`
-	Total      int            `json:"total"`
-	Handlers   []string       `json:"handlers"`
-	Queues     map[string]int `json:"queues"`
+	Connection string          `json:"connection"`
+	Pending    int             `json:"pending"`
+	Reserved   int             `json:"reserved"`
+	Total      int             `json:"total"`
+	Handlers   []string        `json:"handlers"`
+	Queues     map[string]int  `json:"queues"`
+	Batches    []BatchProgress `json:"batches"`
 }
 
 // Stats returns manager-level queue stats.
@@ -35,6 +36,7 @@ func (m *Manager) Stats() Stats {
 		Handlers:   make([]string, 0),
 		Queues:     map[string]int{},
 	}
+	stats.Batches, _ = m.BatchProgress(10)
 	m.mu.RLock()
 	for name := range m.handlers {
 		stats.Handlers = append(stats.Handlers, name)
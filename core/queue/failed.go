@@ -0,0 +1,378 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zatrano/framework/core/database/query"
+	"github.com/zatrano/framework/core/support/uuid"
+)
+
+// ErrFailedJobNotFound is returned when no failed job matches an id or UUID.
+var ErrFailedJobNotFound = errors.New("failed job not found")
+
+// ErrMaxAttemptsExceeded is recorded for a job reserved again after using up
+// its attempts without finishing, for example because it crashed the worker.
+var ErrMaxAttemptsExceeded = errors.New("job attempted too many times")
+
+// ErrJobTimeout is the error recorded for an attempt that exceeded its Timeout.
+var ErrJobTimeout = errors.New("job timed out")
+
+// FailedJob records a job that ran out of attempts, failed in a chain or was
+// passed to Manager.Fail.
+type FailedJob struct {
+	ID       int64     `json:"id"`
+	UUID     string    `json:"uuid"`
+	Queue    string    `json:"queue"`
+	Job      NamedJob  `json:"job"`
+	Error    string    `json:"error"`
+	Trace    string    `json:"trace,omitempty"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// FailedJobStore persists failed jobs. Find and Forget accept the numeric id
+// or the UUID.
+type FailedJobStore interface {
+	Log(job *FailedJob) error
+	All() ([]FailedJob, error)
+	Find(id string) (*FailedJob, error)
+	Forget(id string) error
+	Flush() error
+}
+
+// MemoryFailedJobStore keeps failed jobs in memory.
+type MemoryFailedJobStore struct {
+	mu     sync.Mutex
+	jobs   []FailedJob
+	nextID int64
+}
+
+// NewMemoryFailedJobStore creates an in-memory failed job store.
+func NewMemoryFailedJobStore() *MemoryFailedJobStore {
+	return &MemoryFailedJobStore{}
+}
+
+// Log stores a failed job and assigns its id.
+func (s *MemoryFailedJobStore) Log(job *FailedJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	job.ID = s.nextID
+	s.jobs = append(s.jobs, *job)
+	return nil
+}
+
+// All returns the failed jobs, oldest first.
+func (s *MemoryFailedJobStore) All() ([]FailedJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]FailedJob, len(s.jobs))
+	copy(out, s.jobs)
+	return out, nil
+}
+
+// Find returns a failed job by id or UUID.
+func (s *MemoryFailedJobStore) Find(id string) (*FailedJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := s.index(id); i >= 0 {
+		job := s.jobs[i]
+		return &job, nil
+	}
+	return nil, fmt.Errorf("%w: [%s]", ErrFailedJobNotFound, id)
+}
+
+// Forget deletes a failed job by id or UUID.
+func (s *MemoryFailedJobStore) Forget(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(id)
+	if i < 0 {
+		return fmt.Errorf("%w: [%s]", ErrFailedJobNotFound, id)
+	}
+	s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+	return nil
+}
+
+// Flush deletes every failed job.
+func (s *MemoryFailedJobStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = nil
+	return nil
+}
+
+func (s *MemoryFailedJobStore) index(id string) int {
+	for i, job := range s.jobs {
+		if job.UUID == id || strconv.FormatInt(job.ID, 10) == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// DatabaseFailedJobStore stores failed jobs in a database table.
+type DatabaseFailedJobStore struct {
+	db     *sql.DB
+	driver string
+	table  string
+}
+
+// NewDatabaseFailedJobStore creates a database-backed failed job store. The
+// driver name picks the placeholder style, as for query.New.
+func NewDatabaseFailedJobStore(db *sql.DB, driver, table string) *DatabaseFailedJobStore {
+	if table == "" {
+		table = "failed_jobs"
+	}
+	return &DatabaseFailedJobStore{db: db, driver: driver, table: table}
+}
+
+// EnsureTable creates the failed jobs table with SQLite DDL, for tests and
+// SQLite apps; other drivers use the failed_jobs migration.
+func (s *DatabaseFailedJobStore) EnsureTable() error {
+	_, err := s.db.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	uuid VARCHAR(36) NOT NULL UNIQUE,
+	queue VARCHAR(255) NOT NULL DEFAULT 'default',
+	payload TEXT NOT NULL,
+	exception TEXT NOT NULL,
+	trace TEXT NULL,
+	failed_at DATETIME NOT NULL
+)`, s.table))
+	return err
+}
+
+// Log inserts a failed job and assigns its id.
+func (s *DatabaseFailedJobStore) Log(job *FailedJob) error {
+	raw, err := json.Marshal(job.Job)
+	if err != nil {
+		return err
+	}
+	stmt := fmt.Sprintf(`INSERT INTO %s (uuid, queue, payload, exception, trace, failed_at) VALUES (?, ?, ?, ?, ?, ?)`, s.table)
+	args := []any{job.UUID, job.Queue, string(raw), job.Error, job.Trace, job.FailedAt.Format("2006-01-02 15:04:05")}
+	if isPostgres(s.driver) {
+		// lib/pq has no LastInsertId.
+		return s.db.QueryRow(s.bind(stmt+" RETURNING id"), args...).Scan(&job.ID)
+	}
+	res, err := s.db.Exec(s.bind(stmt), args...)
+	if err != nil {
+		return err
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		job.ID = id
+	}
+	return nil
+}
+
+// All returns the failed jobs, oldest first.
+func (s *DatabaseFailedJobStore) All() ([]FailedJob, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`SELECT id, uuid, queue, payload, exception, trace, failed_at FROM %s ORDER BY id ASC`, s.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]FailedJob, 0)
+	for rows.Next() {
+		job, err := scanFailedJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *job)
+	}
+	return out, rows.Err()
+}
+
+// Find returns a failed job by id or UUID.
+func (s *DatabaseFailedJobStore) Find(id string) (*FailedJob, error) {
+	where, args := s.match(id)
+	job, err := scanFailedJob(s.db.QueryRow(s.bind(fmt.Sprintf(`SELECT id, uuid, queue, payload, exception, trace, failed_at FROM %s WHERE %s`, s.table, where)), args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: [%s]", ErrFailedJobNotFound, id)
+	}
+	return job, err
+}
+
+// Forget deletes a failed job by id or UUID.
+func (s *DatabaseFailedJobStore) Forget(id string) error {
+	where, args := s.match(id)
+	res, err := s.db.Exec(s.bind(fmt.Sprintf(`DELETE FROM %s WHERE %s`, s.table, where)), args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: [%s]", ErrFailedJobNotFound, id)
+	}
+	return nil
+}
+
+// Flush deletes every failed job.
+func (s *DatabaseFailedJobStore) Flush() error {
+	_, err := s.db.Exec(fmt.Sprintf(`DELETE FROM %s`, s.table))
+	return err
+}
+
+// bind rewrites ? placeholders for the store's driver.
+func (s *DatabaseFailedJobStore) bind(stmt string) string {
+	return query.Rebind(s.driver, stmt)
+}
+
+func (s *DatabaseFailedJobStore) match(id string) (string, []any) {
+	if n, err := strconv.ParseInt(id, 10, 64); err == nil {
+		return "id = ? OR uuid = ?", []any{n, id}
+	}
+	return "uuid = ?", []any{id}
+}
+
+func scanFailedJob(row interface{ Scan(...any) error }) (*FailedJob, error) {
+	var job FailedJob
+	var payload, failedAt string
+	var trace sql.NullString
+	if err := row.Scan(&job.ID, &job.UUID, &job.Queue, &payload, &job.Error, &trace, &failedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(payload), &job.Job); err != nil {
+		return nil, err
+	}
+	job.Trace = trace.String
+	for _, layout := range []string{"2006-01-02 15:04:05", time.RFC3339Nano} {
+		if t, err := time.ParseInLocation(layout, failedAt, time.Local); err == nil {
+			job.FailedAt = t
+			break
+		}
+	}
+	return &job, nil
+}
+
+// panicError is the error recorded for a job handler that panicked.
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e *panicError) Error() string { return fmt.Sprintf("job panicked: %v", e.value) }
+
+// runJob runs handler with panic recovery. With a Timeout the handler's
+// context is cancelled when it elapses and the attempt fails with
+// ErrJobTimeout. runJob always waits for the handler to return, so a plain
+// handler that ignores the context is never released or retried while it
+// is still running.
+func runJob(ctx context.Context, handler ContextHandler, job NamedJob) error {
+	call := func(ctx context.Context) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &panicError{value: r, stack: debug.Stack()}
+			}
+		}()
+		return handler(ctx, job.Payload)
+	}
+	if job.Timeout <= 0 {
+		return call(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, job.Timeout)
+	defer cancel()
+	err := call(ctx)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: [%s] exceeded %s", ErrJobTimeout, job.Name, job.Timeout)
+	}
+	return err
+}
+
+// isPostgres reports whether driver is a PostgreSQL driver name.
+func isPostgres(driver string) bool {
+	return driver == "pgsql" || driver == "postgres" || driver == "postgresql"
+}
+
+// failureTrace returns the stack of a panicking handler, or the error
+// formatted with %+v so wrapped errors that carry a trace keep it.
+func failureTrace(err error) string {
+	var p *panicError
+	if errors.As(err, &p) {
+		return string(p.stack)
+	}
+	if err == nil {
+		return ""
+	}
+	return fmt.Sprintf("%+v", err)
+}
+
+// SetFailedJobStore replaces the store failed jobs are recorded in.
+func (m *Manager) SetFailedJobStore(store FailedJobStore) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failed = store
+}
+
+// FailedJobStore returns the store failed jobs are recorded in.
+func (m *Manager) FailedJobStore() FailedJobStore {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.failed
+}
+
+// Fail records a failed job.
+func (m *Manager) Fail(job NamedJob, err error) error {
+	if m == nil {
+		return nil
+	}
+	_, logErr := m.logFailure(m.defaultQueue, job, err)
+	return logErr
+}
+
+// logFailure records a failed job and returns its UUID.
+func (m *Manager) logFailure(queueName string, job NamedJob, err error) (string, error) {
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+	}
+	id := uuid.New()
+	if logErr := m.FailedJobStore().Log(&FailedJob{
+		UUID:     id,
+		Queue:    queueName,
+		Job:      job,
+		Error:    msg,
+		Trace:    failureTrace(err),
+		FailedAt: time.Now(),
+	}); logErr != nil {
+		return "", fmt.Errorf("queue: record failed job [%s]: %w", job.Name, logErr)
+	}
+	return id, nil
+}
+
+// Failed returns the recorded failed jobs.
+func (m *Manager) Failed() []FailedJob {
+	jobs, _ := m.FailedJobStore().All()
+	return jobs
+}
+
+// ClearFailed removes recorded failures.
+func (m *Manager) ClearFailed() {
+	_ = m.FailedJobStore().Flush()
+}
+
+// RetryFailed pushes a failed job, by id or UUID, back onto the queue it
+// failed on with its attempts reset, and forgets the failure.
+func (m *Manager) RetryFailed(id string) error {
+	store := m.FailedJobStore()
+	failed, err := store.Find(id)
+	if err != nil {
+		return err
+	}
+	q := m.Queue(failed.Queue)
+	if q == nil {
+		q = m.Queue()
+	}
+	job := failed.Job
+	job.Attempts = 0
+	if err := m.pushJob(q, job); err != nil {
+		return err
+	}
+	return store.Forget(failed.UUID)
+}
@@ -0,0 +1,223 @@
+package queue_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zatrano/framework/core/queue"
+)
+
+func setupFailedQueue(t *testing.T) (*sql.DB, *queue.Manager) {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	jobs := queue.NewDatabaseQueue(db, "jobs")
+	if err := jobs.EnsureTable(); err != nil {
+		t.Fatal(err)
+	}
+	failed := queue.NewDatabaseFailedJobStore(db, "sqlite", "failed_jobs")
+	if err := failed.EnsureTable(); err != nil {
+		t.Fatal(err)
+	}
+	m := queue.NewManager("database", map[string]queue.Queue{"database": jobs})
+	m.SetFailedJobStore(failed)
+	return db, m
+}
+
+// postgresStyleDriver is sqlite that rejects ? placeholders the way lib/pq
+// does, so stores must bind $1, $2, ... for the pgsql driver.
+type postgresStyleDriver struct{ driver.Driver }
+
+func (d postgresStyleDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	return postgresStyleConn{conn}, err
+}
+
+type postgresStyleConn struct{ driver.Conn }
+
+func (c postgresStyleConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.Contains(query, "?") {
+		return nil, fmt.Errorf(`pq: syntax error at or near "?" in %q`, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+var registerPostgresStyle sync.Once
+
+// openPostgresStyleDB opens an in-memory database that only accepts $n placeholders.
+func openPostgresStyleDB(t *testing.T) *sql.DB {
+	t.Helper()
+	registerPostgresStyle.Do(func() {
+		sqlite, _ := sql.Open("sqlite", ":memory:")
+		sql.Register("sqlite-pgsql", postgresStyleDriver{sqlite.Driver()})
+		_ = sqlite.Close()
+	})
+	db, err := sql.Open("sqlite-pgsql", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	return db
+}
+
+func TestDatabaseFailedJobStoreBindsPostgresPlaceholders(t *testing.T) {
+	db := openPostgresStyleDB(t)
+	defer db.Close()
+
+	store := queue.NewDatabaseFailedJobStore(db, "pgsql", "failed_jobs")
+	if err := store.EnsureTable(); err != nil {
+		t.Fatal(err)
+	}
+	job := &queue.FailedJob{UUID: "u-1", Queue: "default", Job: queue.NamedJob{Name: "send"}, Error: "boom", FailedAt: time.Now()}
+	if err := store.Log(job); err != nil || job.ID != 1 {
+		t.Fatalf("log id=%d err=%v", job.ID, err)
+	}
+	if found, err := store.Find("1"); err != nil || found.UUID != "u-1" {
+		t.Fatalf("find=%+v err=%v", found, err)
+	}
+	if err := store.Forget("u-1"); err != nil {
+		t.Fatal(err)
+	}
+	if all, err := store.All(); err != nil || len(all) != 0 {
+		t.Fatalf("all=%v err=%v", all, err)
+	}
+}
+
+func TestWorkRetriesThenRecordsFailedJob(t *testing.T) {
+	db, m := setupFailedQueue(t)
+	defer db.Close()
+
+	calls := 0
+	m.Register("report.build", func(payload map[string]any) error {
+		calls++
+		if payload["ok"] == true {
+			return nil
+		}
+		return errors.New("boom")
+	})
+	if err := m.Queue().Push(queue.NamedJob{Name: "report.build", MaxTries: 3}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := m.Work(); err == nil || err.Error() != "boom" {
+			t.Fatalf("attempt %d err=%v", i+1, err)
+		}
+		if i < 2 && len(m.Failed()) != 0 {
+			t.Fatalf("failed too early after attempt %d", i+1)
+		}
+	}
+	if err := m.Work(); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected empty queue, got %v", err)
+	}
+	failed := m.Failed()
+	if calls != 3 || len(failed) != 1 || failed[0].Error != "boom" || failed[0].UUID == "" || failed[0].Queue != "database" || failed[0].Job.Attempts != 3 {
+		t.Fatalf("calls=%d failed=%+v", calls, failed)
+	}
+
+	if _, err := db.Exec(`UPDATE failed_jobs SET payload = '{"name":"report.build","payload":{"ok":true},"attempts":3,"max_tries":3}'`); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RetryFailed(failed[0].UUID); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Work(); err != nil || calls != 4 || len(m.Failed()) != 0 {
+		t.Fatalf("retry err=%v calls=%d failed=%+v", err, calls, m.Failed())
+	}
+	if err := m.FailedJobStore().Forget("missing"); !errors.Is(err, queue.ErrFailedJobNotFound) {
+		t.Fatalf("forget missing err=%v", err)
+	}
+}
+
+func TestWorkBackoffTimeoutAndPanics(t *testing.T) {
+	db, m := setupFailedQueue(t)
+	defer db.Close()
+
+	m.Register("slow", func(map[string]any) error {
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	})
+	m.Register("panics", func(map[string]any) error {
+		panic("kaboom")
+	})
+	_ = m.Queue().Push(queue.NamedJob{Name: "slow", Timeout: 10 * time.Millisecond, MaxTries: 2, Backoff: []time.Duration{time.Hour}})
+	if err := m.Work(); !errors.Is(err, queue.ErrJobTimeout) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	if err := m.Work(); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected job held back by backoff, got %v", err)
+	}
+	if size, _ := m.Queue().Size(); size != 1 {
+		t.Fatalf("size=%d", size)
+	}
+
+	_ = m.Queue().Clear()
+	_ = m.Queue().Push(queue.NamedJob{Name: "panics", RetryUntil: time.Now().Add(-time.Second), MaxTries: 5})
+	if err := m.Work(); err == nil || err.Error() != "job panicked: kaboom" {
+		t.Fatalf("err=%v", err)
+	}
+	failed := m.Failed()
+	if len(failed) != 1 || failed[0].Trace == "" {
+		t.Fatalf("failed=%+v", failed)
+	}
+	m.ClearFailed()
+	if len(m.Failed()) != 0 {
+		t.Fatal("expected flushed failed jobs")
+	}
+}
+
+func TestTimedOutPlainHandlerFinishesBeforeRetry(t *testing.T) {
+	db, m := setupFailedQueue(t)
+	defer db.Close()
+
+	var running atomic.Int32
+	m.Register("stubborn", func(map[string]any) error {
+		running.Add(1)
+		defer running.Add(-1)
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+	_ = m.Queue().Push(queue.NamedJob{Name: "stubborn", Timeout: 5 * time.Millisecond, MaxTries: 2})
+	for i := 0; i < 2; i++ {
+		if err := m.Work(); !errors.Is(err, queue.ErrJobTimeout) {
+			t.Fatalf("attempt %d err=%v", i+1, err)
+		}
+		if n := running.Load(); n != 0 {
+			t.Fatalf("attempt %d released with %d handler(s) still running", i+1, n)
+		}
+	}
+	if failed := m.Failed(); len(failed) != 1 || !strings.Contains(failed[0].Error, "timed out") {
+		t.Fatalf("failed=%+v", failed)
+	}
+}
+
+func TestWorkKeepsJobWhenFailureCannotBeRecorded(t *testing.T) {
+	db, m := setupFailedQueue(t)
+	defer db.Close()
+	if _, err := db.Exec(`DROP TABLE failed_jobs`); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Register("report.build", func(map[string]any) error { return errors.New("boom") })
+	if err := m.Queue().Push(queue.NamedJob{Name: "report.build"}); err != nil {
+		t.Fatal(err)
+	}
+	err := m.Work()
+	if err == nil || !strings.Contains(err.Error(), "boom") || !strings.Contains(err.Error(), "record failed job") {
+		t.Fatalf("err=%v", err)
+	}
+	if size, _ := m.Queue().Size(); size != 1 {
+		t.Fatalf("job lost: size=%d", size)
+	}
+}
@@ -0,0 +1,361 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zatrano/framework/core/database/query"
+	"github.com/zatrano/framework/core/lock"
+	"github.com/zatrano/framework/core/ratelimit"
+	"github.com/zatrano/framework/core/support/uuid"
+)
+
+// ErrDuplicateJob is returned when pushing a ShouldBeUnique job whose key is
+// still held by a pending job.
+var ErrDuplicateJob = errors.New("duplicate unique job")
+
+// JobMiddleware wraps the handling of a job. Calling next runs the rest of
+// the chain and the handler; returning Release(delay) puts the job back on
+// the queue without using up an attempt.
+type JobMiddleware interface {
+	Handle(ctx context.Context, m *Manager, job NamedJob, next func(context.Context) error) error
+}
+
+// pushHook is implemented by middleware that acts when a job is pushed.
+type pushHook interface {
+	pushing(m *Manager, job NamedJob) error
+}
+
+// doneHook is implemented by middleware that acts once a job has succeeded
+// or failed for good.
+type doneHook interface {
+	done(m *Manager, job NamedJob)
+}
+
+// Use adds middleware to every job pushed or processed under name:
+//
+//	app.Queue().Use("billing.sync",
+//		queue.ShouldBeUnique("billing:{account_id}", time.Hour),
+//		queue.RateLimited("billing"),
+//	)
+//
+// Push hooks only run for jobs pushed through the manager (Push, PushOn,
+// Batch, PushChain), not for Queue().Push.
+func (m *Manager) Use(name string, middleware ...JobMiddleware) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.middleware == nil {
+		m.middleware = map[string][]JobMiddleware{}
+	}
+	m.middleware[name] = append(m.middleware[name], middleware...)
+}
+
+func (m *Manager) middlewareFor(name string) []JobMiddleware {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.middleware[name]
+}
+
+// pushJob runs the job's push hooks, then pushes it onto q. Batch jobs
+// pushed onto a SyncQueue run through syncBatchJob.
+func (m *Manager) pushJob(q Queue, job NamedJob, delay ...time.Duration) error {
+	if job.UUID == "" {
+		job.UUID = uuid.New()
+	}
+	middleware := m.middlewareFor(job.Name)
+	for i, mw := range middleware {
+		hook, ok := mw.(pushHook)
+		if !ok {
+			continue
+		}
+		if err := hook.pushing(m, job); err != nil {
+			m.jobDone(middleware[:i], job)
+			return err
+		}
+	}
+	_, isSync := q.(*SyncQueue)
+	var err error
+	if isSync && job.BatchID != "" {
+		err = m.syncBatchJob(q, job, delay...)
+	} else {
+		err = q.Push(job, delay...)
+	}
+	if err != nil || isSync {
+		m.jobDone(middleware, job)
+	}
+	return err
+}
+
+// jobDone runs the done hooks of middleware.
+func (m *Manager) jobDone(middleware []JobMiddleware, job NamedJob) {
+	for _, mw := range middleware {
+		if hook, ok := mw.(doneHook); ok {
+			hook.done(m, job)
+		}
+	}
+}
+
+// releaseError asks the worker to put the job back on the queue.
+type releaseError struct {
+	delay time.Duration
+}
+
+func (e *releaseError) Error() string { return fmt.Sprintf("job released for %s", e.delay) }
+
+// Release returns an error that makes the worker put the job back on the
+// queue after delay without counting the attempt, for middleware and
+// handlers that cannot run the job yet.
+func Release(delay time.Duration) error {
+	return &releaseError{delay: delay}
+}
+
+// Locker provides the locks behind ShouldBeUnique and WithoutOverlapping.
+// Acquire must be atomic, and Release only frees a lock still held by owner,
+// so a job whose lock expired cannot release another job's lock.
+type Locker interface {
+	Acquire(key, owner string, ttl time.Duration) bool
+	Release(key, owner string)
+}
+
+// SetLocker replaces the locker. The default only locks within the process,
+// so a job pushed by one process and worked by another needs a shared
+// locker: DatabaseLocker or RedisLocker.
+func (m *Manager) SetLocker(locker Locker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.locker = locker
+}
+
+// Locker returns the locker used by job middleware.
+func (m *Manager) Locker() Locker {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.locker
+}
+
+// SetRateLimiter sets the limiter whose named limits RateLimited uses.
+func (m *Manager) SetRateLimiter(limiter *ratelimit.Limiter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limiter = limiter
+}
+
+// LockLocker adapts a process-local lock.Manager.
+type LockLocker struct {
+	locks *lock.Manager
+}
+
+// NewLockLocker creates a locker backed by locks.
+func NewLockLocker(locks *lock.Manager) *LockLocker {
+	return &LockLocker{locks: locks}
+}
+
+// Acquire obtains the lock for ttl.
+func (l *LockLocker) Acquire(key, owner string, ttl time.Duration) bool {
+	return l.locks.Restore(key, owner, ttl).Acquire()
+}
+
+// Release frees the lock if owner holds it.
+func (l *LockLocker) Release(key, owner string) {
+	l.locks.Restore(key, owner).Release()
+}
+
+// releaseLockScript deletes KEYS[1] only while it holds the owner ARGV[1].
+var releaseLockScript = redis.NewScript(`
+if redis.call('get', KEYS[1]) == ARGV[1] then
+	return redis.call('del', KEYS[1])
+end
+return 0
+`)
+
+// RedisLocker keeps locks in Redis with SET NX, so they are shared between
+// the processes pushing and working jobs.
+type RedisLocker struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisLocker creates a Redis-backed locker; keys are stored under prefix
+// (default "zatrano:queue:locks:").
+func NewRedisLocker(client *redis.Client, prefix string) *RedisLocker {
+	if prefix == "" {
+		prefix = "zatrano:queue:locks:"
+	}
+	return &RedisLocker{client: client, prefix: prefix}
+}
+
+// Acquire obtains the lock for ttl.
+func (l *RedisLocker) Acquire(key, owner string, ttl time.Duration) bool {
+	ok, err := l.client.SetNX(context.Background(), l.prefix+key, owner, ttl).Result()
+	return err == nil && ok
+}
+
+// Release frees the lock if owner holds it.
+func (l *RedisLocker) Release(key, owner string) {
+	_ = releaseLockScript.Run(context.Background(), l.client, []string{l.prefix + key}, owner).Err()
+}
+
+// DatabaseLocker keeps locks as rows of a table (default "job_locks"), so
+// they are shared by every process on the database. The primary key on name
+// makes Acquire atomic; an expired row is deleted before inserting.
+type DatabaseLocker struct {
+	db     *sql.DB
+	driver string
+	table  string
+}
+
+// NewDatabaseLocker creates a database-backed locker. The driver name picks
+// the placeholder style, as for query.New.
+func NewDatabaseLocker(db *sql.DB, driver, table string) *DatabaseLocker {
+	if table == "" {
+		table = "job_locks"
+	}
+	return &DatabaseLocker{db: db, driver: driver, table: table}
+}
+
+// EnsureTable creates the job locks table with SQLite DDL, for tests and
+// SQLite apps; other drivers use the job_locks migration.
+func (l *DatabaseLocker) EnsureTable() error {
+	_, err := l.db.Exec(fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	name VARCHAR(255) PRIMARY KEY,
+	owner VARCHAR(255) NOT NULL,
+	expires_at BIGINT NOT NULL
+)`, l.table))
+	return err
+}
+
+// Acquire obtains the lock for ttl.
+func (l *DatabaseLocker) Acquire(key, owner string, ttl time.Duration) bool {
+	now := time.Now()
+	_, _ = l.db.Exec(l.bind(fmt.Sprintf(`DELETE FROM %s WHERE name = ? AND expires_at <= ?`, l.table)), key, now.UnixMilli())
+	_, err := l.db.Exec(l.bind(fmt.Sprintf(`INSERT INTO %s (name, owner, expires_at) VALUES (?, ?, ?)`, l.table)),
+		key, owner, now.Add(ttl).UnixMilli())
+	return err == nil
+}
+
+// Release frees the lock if owner holds it.
+func (l *DatabaseLocker) Release(key, owner string) {
+	_, _ = l.db.Exec(l.bind(fmt.Sprintf(`DELETE FROM %s WHERE name = ? AND owner = ?`, l.table)), key, owner)
+}
+
+func (l *DatabaseLocker) bind(stmt string) string {
+	return query.Rebind(l.driver, stmt)
+}
+
+var keyField = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// jobKey prefixes the job name to key, with {field} replaced by the job's
+// payload values.
+func jobKey(job NamedJob, key string) string {
+	key = keyField.ReplaceAllStringFunc(key, func(match string) string {
+		return fmt.Sprint(job.Payload[match[1:len(match)-1]])
+	})
+	return job.Name + ":" + key
+}
+
+type uniqueMiddleware struct {
+	key string
+	ttl time.Duration
+}
+
+// ShouldBeUnique refuses to push a job with ErrDuplicateJob while another
+// job with the same key is pending or running. {field} placeholders in key
+// are replaced by payload values; the lock expires after ttl at the latest.
+func ShouldBeUnique(key string, ttl time.Duration) JobMiddleware {
+	return &uniqueMiddleware{key: key, ttl: ttl}
+}
+
+func (u *uniqueMiddleware) Handle(ctx context.Context, m *Manager, job NamedJob, next func(context.Context) error) error {
+	return next(ctx)
+}
+
+func (u *uniqueMiddleware) pushing(m *Manager, job NamedJob) error {
+	key := "unique:" + jobKey(job, u.key)
+	if !m.Locker().Acquire(key, job.UUID, u.ttl) {
+		return fmt.Errorf("%w: [%s]", ErrDuplicateJob, key)
+	}
+	return nil
+}
+
+func (u *uniqueMiddleware) done(m *Manager, job NamedJob) {
+	m.Locker().Release("unique:"+jobKey(job, u.key), job.UUID)
+}
+
+type rateLimitedMiddleware struct {
+	name string
+	key  string
+}
+
+// RateLimited releases jobs beyond the ratelimit.Limiter limit registered
+// under name (see Limiter.For; its Key function is not used). The budget is
+// shared by every job using the limit, or split per key when one is given.
+func RateLimited(name string, key ...string) JobMiddleware {
+	r := &rateLimitedMiddleware{name: name}
+	if len(key) > 0 {
+		r.key = key[0]
+	}
+	return r
+}
+
+func (r *rateLimitedMiddleware) Handle(ctx context.Context, m *Manager, job NamedJob, next func(context.Context) error) error {
+	m.mu.RLock()
+	limiter := m.limiter
+	m.mu.RUnlock()
+	if limiter == nil {
+		return next(ctx)
+	}
+	limit, ok := limiter.Limit(r.name)
+	if !ok {
+		return fmt.Errorf("rate limiter [%s] not defined", r.name)
+	}
+	bucket := "queue:" + r.name
+	if r.key != "" {
+		bucket += ":" + jobKey(job, r.key)
+	}
+	if limiter.TooManyAttempts(bucket, limit.MaxAttempts) {
+		wait := time.Duration(limiter.AvailableIn(bucket)) * time.Second
+		if wait < time.Second {
+			wait = time.Second
+		}
+		return Release(wait)
+	}
+	limiter.Hit(bucket, limit.Decay)
+	return next(ctx)
+}
+
+type overlapMiddleware struct {
+	key          string
+	releaseAfter time.Duration
+}
+
+// WithoutOverlapping runs one job per key at a time; a job reserved while
+// another holds the key is released for releaseAfter (default one second).
+// The lock is held until the job returns; it only expires, an hour past the
+// job's Timeout, if the worker dies mid-job.
+func WithoutOverlapping(key string, releaseAfter ...time.Duration) JobMiddleware {
+	o := &overlapMiddleware{key: key, releaseAfter: time.Second}
+	if len(releaseAfter) > 0 {
+		o.releaseAfter = releaseAfter[0]
+	}
+	return o
+}
+
+func (o *overlapMiddleware) Handle(ctx context.Context, m *Manager, job NamedJob, next func(context.Context) error) error {
+	key := "overlap:" + jobKey(job, o.key)
+	ttl := time.Hour
+	if job.Timeout > 0 {
+		ttl += job.Timeout
+	}
+	locker, owner := m.Locker(), uuid.New()
+	if !locker.Acquire(key, owner, ttl) {
+		return Release(o.releaseAfter)
+	}
+	defer locker.Release(key, owner)
+	return next(ctx)
+}
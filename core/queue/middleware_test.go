@@ -0,0 +1,196 @@
+package queue_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zatrano/framework/core/lock"
+	"github.com/zatrano/framework/core/queue"
+	"github.com/zatrano/framework/core/ratelimit"
+)
+
+func TestShouldBeUniquePreventsDuplicatePushes(t *testing.T) {
+	db, m := setupFailedQueue(t)
+	defer db.Close()
+
+	charged := map[any]int{}
+	m.Register("billing.sync", func(payload map[string]any) error {
+		charged[payload["account_id"]]++
+		return nil
+	})
+	m.Use("billing.sync", queue.ShouldBeUnique("{account_id}", time.Hour))
+
+	if err := m.Push("billing.sync", map[string]any{"account_id": 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Push("billing.sync", map[string]any{"account_id": 7}); !errors.Is(err, queue.ErrDuplicateJob) {
+		t.Fatalf("expected duplicate, got %v", err)
+	}
+	if err := m.Push("billing.sync", map[string]any{"account_id": 8}); err != nil {
+		t.Fatal(err)
+	}
+	queue.NewWorker(m, queue.WorkerOptions{StopWhenEmpty: true}).Run(context.Background())
+	if charged[float64(7)] != 1 || charged[float64(8)] != 1 {
+		t.Fatalf("charged=%v", charged)
+	}
+	if err := m.Push("billing.sync", map[string]any{"account_id": 7}); err != nil {
+		t.Fatalf("lock not released after processing: %v", err)
+	}
+}
+
+func TestRateLimitedAndWithoutOverlappingRelease(t *testing.T) {
+	db, m := setupFailedQueue(t)
+	defer db.Close()
+
+	limiter := ratelimit.New()
+	limiter.For("exports", ratelimit.Limit{MaxAttempts: 2, Decay: time.Hour})
+	m.SetRateLimiter(limiter)
+	ran := 0
+	m.Register("export", func(map[string]any) error {
+		ran++
+		return nil
+	})
+	m.Use("export", queue.RateLimited("exports"))
+	for i := 0; i < 3; i++ {
+		_ = m.Push("export", nil)
+	}
+	queue.NewWorker(m, queue.WorkerOptions{StopWhenEmpty: true}).Run(context.Background())
+	if ran != 2 {
+		t.Fatalf("ran=%d", ran)
+	}
+	if size, _ := m.Queue().Size(); size != 1 || len(m.Failed()) != 0 {
+		t.Fatalf("size=%d failed=%v", size, m.Failed())
+	}
+	_ = m.Queue().Clear()
+
+	inside := make(chan struct{})
+	proceed := make(chan struct{})
+	m.RegisterContext("invoice", func(ctx context.Context, payload map[string]any) error {
+		if payload["first"] == true {
+			close(inside)
+			<-proceed
+		}
+		return nil
+	})
+	m.Use("invoice", queue.WithoutOverlapping("{customer}", time.Hour))
+	_ = m.Push("invoice", map[string]any{"customer": "c1", "first": true})
+	_ = m.Push("invoice", map[string]any{"customer": "c1"})
+	_ = m.Push("invoice", map[string]any{"customer": "c2"})
+
+	done := make(chan error, 1)
+	go func() { done <- m.Work() }()
+	<-inside
+	if err := m.Work(); err != nil {
+		t.Fatalf("overlapping job err=%v", err)
+	}
+	if err := m.Work(); err != nil {
+		t.Fatalf("other customer err=%v", err)
+	}
+	close(proceed)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Work(); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected released job held back, got %v", err)
+	}
+	if size, _ := m.Queue().Size(); size != 1 {
+		t.Fatalf("size=%d", size)
+	}
+}
+
+func TestShouldBeUniqueConcurrentPushes(t *testing.T) {
+	db, m := setupFailedQueue(t)
+	defer db.Close()
+	m.Register("billing.sync", func(map[string]any) error { return nil })
+	m.Use("billing.sync", queue.ShouldBeUnique("{account_id}", time.Hour))
+
+	var pushed, duplicates atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := m.Push("billing.sync", map[string]any{"account_id": 7})
+			switch {
+			case err == nil:
+				pushed.Add(1)
+			case errors.Is(err, queue.ErrDuplicateJob):
+				duplicates.Add(1)
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if pushed.Load() != 1 || duplicates.Load() != 19 {
+		t.Fatalf("pushed=%d duplicates=%d", pushed.Load(), duplicates.Load())
+	}
+}
+
+func TestLockerReleaseRequiresOwner(t *testing.T) {
+	locker := queue.NewLockLocker(lock.New())
+	if !locker.Acquire("k", "first", time.Hour) || locker.Acquire("k", "second", time.Hour) {
+		t.Fatal("expected only the first owner to acquire")
+	}
+	locker.Release("k", "second")
+	if locker.Acquire("k", "second", time.Hour) {
+		t.Fatal("lock released by a different owner")
+	}
+	locker.Release("k", "first")
+	if !locker.Acquire("k", "second", time.Hour) {
+		t.Fatal("lock not released by its owner")
+	}
+}
+
+func TestDatabaseLockerReleaseRequiresOwnerAndExpires(t *testing.T) {
+	db, _ := setupFailedQueue(t)
+	defer db.Close()
+	locker := queue.NewDatabaseLocker(db, "sqlite", "job_locks")
+	if err := locker.EnsureTable(); err != nil {
+		t.Fatal(err)
+	}
+	if !locker.Acquire("k", "first", time.Hour) || locker.Acquire("k", "second", time.Hour) {
+		t.Fatal("expected only the first owner to acquire")
+	}
+	locker.Release("k", "second")
+	if locker.Acquire("k", "second", time.Hour) {
+		t.Fatal("lock released by a different owner")
+	}
+	locker.Release("k", "first")
+	if !locker.Acquire("k", "second", -time.Second) {
+		t.Fatal("lock not released by its owner")
+	}
+	if !locker.Acquire("k", "third", time.Hour) {
+		t.Fatal("expired lock not taken over")
+	}
+}
+
+func TestShouldBeUniqueSharedAcrossManagers(t *testing.T) {
+	db, web := setupFailedQueue(t)
+	defer db.Close()
+	if err := queue.NewDatabaseLocker(db, "sqlite", "job_locks").EnsureTable(); err != nil {
+		t.Fatal(err)
+	}
+	worker := queue.NewManager("database", map[string]queue.Queue{"database": web.Queue()})
+	for _, m := range []*queue.Manager{web, worker} {
+		m.SetLocker(queue.NewDatabaseLocker(db, "sqlite", "job_locks"))
+		m.Register("billing.sync", func(map[string]any) error { return nil })
+		m.Use("billing.sync", queue.ShouldBeUnique("{account_id}", time.Hour))
+	}
+
+	if err := web.Push("billing.sync", map[string]any{"account_id": 7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := worker.Push("billing.sync", map[string]any{"account_id": 7}); !errors.Is(err, queue.ErrDuplicateJob) {
+		t.Fatalf("expected duplicate across managers, got %v", err)
+	}
+	queue.NewWorker(worker, queue.WorkerOptions{StopWhenEmpty: true}).Run(context.Background())
+	if err := web.Push("billing.sync", map[string]any{"account_id": 7}); err != nil {
+		t.Fatalf("lock not released by the worker: %v", err)
+	}
+}
@@ -1,11 +1,16 @@
 package queue
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/zatrano/framework/core/lock"
+	"github.com/zatrano/framework/core/ratelimit"
 )
 
 // Job is a unit of queued work.
@@ -19,10 +24,64 @@ type HandlerFunc func() error
 // Handle runs the function.
 func (f HandlerFunc) Handle() error { return f() }
 
-// NamedJob is a serializable job payload.
+// NamedJob is a serializable job payload. The retry fields are optional:
+//
+//	queue.NamedJob{
+//		Name:     "podcast.publish",
+//		Payload:  map[string]any{"id": 7},
+//		MaxTries: 3,
+//		Backoff:  []time.Duration{10 * time.Second, time.Minute},
+//		Timeout:  2 * time.Minute,
+//	}
 type NamedJob struct {
 	Name    string         `json:"name"`
 	Payload map[string]any `json:"payload"`
+	// Attempts counts how many times a worker has reserved the job.
+	Attempts int `json:"attempts,omitempty"`
+	// MaxTries is the number of attempts before the job is moved to the
+	// failed jobs; zero uses the manager default (SetMaxTries).
+	MaxTries int `json:"max_tries,omitempty"`
+	// Backoff is the delay before each retry; the last entry is reused for
+	// later attempts. Empty retries immediately.
+	Backoff []time.Duration `json:"backoff,omitempty"`
+	// Timeout fails an attempt that runs longer and cancels the context
+	// passed to RegisterContext handlers. Plain handlers cannot be
+	// interrupted: the attempt fails once they return.
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// RetryUntil keeps retrying a failing job until this time, regardless
+	// of MaxTries.
+	RetryUntil time.Time `json:"retry_until,omitzero"`
+	// UUID identifies the job; it is assigned when the job is pushed through
+	// the manager and owns the job's ShouldBeUnique lock.
+	UUID string `json:"uuid,omitempty"`
+	// BatchID is set on jobs dispatched with Manager.Batch.
+	BatchID string `json:"batch_id,omitempty"`
+}
+
+// backoffFor returns the delay before retrying after the given attempt.
+func (j NamedJob) backoffFor(attempt int) time.Duration {
+	if len(j.Backoff) == 0 {
+		return 0
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt > len(j.Backoff) {
+		attempt = len(j.Backoff)
+	}
+	return j.Backoff[attempt-1]
+}
+
+// exhausted reports whether a failed job should stop being retried.
+func (j NamedJob) exhausted(defaultTries int, now time.Time) bool {
+	if !j.RetryUntil.IsZero() {
+		return !now.Before(j.RetryUntil)
+	}
+	tries := j.MaxTries
+	if tries <= 0 {
+		tries = defaultTries
+	}
+	return j.Attempts >= tries
 }
 
 // Queue pushes and pops jobs.
@@ -102,6 +161,8 @@ func (q *SyncQueue) Clear() error { return nil }
 type DatabaseQueue struct {
 	db       *sql.DB
 	table    string
+	queue    string
+	root     *DatabaseQueue
 	handlers map[string]func(map[string]any) error
 	mu       sync.RWMutex
 }
@@ -114,10 +175,21 @@ func NewDatabaseQueue(db *sql.DB, table string) *DatabaseQueue {
 	return &DatabaseQueue{
 		db:       db,
 		table:    table,
+		queue:    "default",
 		handlers: make(map[string]func(map[string]any) error),
 	}
 }
 
+// OnQueue returns the named queue stored in the same table; it shares this
+// queue's handlers.
+func (q *DatabaseQueue) OnQueue(name string) Queue {
+	root := q
+	if q.root != nil {
+		root = q.root
+	}
+	return &DatabaseQueue{db: q.db, table: q.table, queue: name, root: root}
+}
+
 // EnsureTable creates the jobs table if needed.
 func (q *DatabaseQueue) EnsureTable() error {
 	_, err := q.db.Exec(fmt.Sprintf(`
@@ -134,6 +206,10 @@ CREATE TABLE IF NOT EXISTS %s (
 
 // Register registers a job handler.
 func (q *DatabaseQueue) Register(name string, handler func(map[string]any) error) {
+	if q.root != nil {
+		q.root.Register(name, handler)
+		return
+	}
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	q.handlers[name] = handler
@@ -141,6 +217,9 @@ func (q *DatabaseQueue) Register(name string, handler func(map[string]any) error
 
 // Handler returns a registered handler.
 func (q *DatabaseQueue) Handler(name string) (func(map[string]any) error, bool) {
+	if q.root != nil {
+		return q.root.Handler(name)
+	}
 	q.mu.RLock()
 	defer q.mu.RUnlock()
 	handler, ok := q.handlers[name]
@@ -161,28 +240,33 @@ func (q *DatabaseQueue) Push(job NamedJob, delay ...time.Duration) error {
 	available := now.Add(wait)
 	_, err = q.db.Exec(
 		fmt.Sprintf(`INSERT INTO %s (queue, payload, available_at, created_at) VALUES (?, ?, ?, ?)`, q.table),
-		"default", string(raw), available.Format("2006-01-02 15:04:05"), now.Format("2006-01-02 15:04:05"),
+		q.queue, string(raw), available.Format("2006-01-02 15:04:05"), now.Format("2006-01-02 15:04:05"),
 	)
 	return err
 }
 
-// Pop reserves the next available job.
+// Pop reserves the next available job. The reservation only succeeds if the
+// row is still unreserved, so concurrent workers never share a job.
 func (q *DatabaseQueue) Pop() (*ReservedJob, error) {
-	now := time.Now().Format("2006-01-02 15:04:05")
-	row := q.db.QueryRow(fmt.Sprintf(`
-SELECT id, payload FROM %s
-WHERE reserved_at IS NULL AND available_at <= ?
-ORDER BY id ASC LIMIT 1`, q.table), now)
-
 	var id int64
 	var payload string
-	if err := row.Scan(&id, &payload); err != nil {
-		return nil, err
-	}
+	for {
+		now := time.Now().Format("2006-01-02 15:04:05")
+		row := q.db.QueryRow(fmt.Sprintf(`
+SELECT id, payload FROM %s
+WHERE queue = ? AND reserved_at IS NULL AND available_at <= ?
+ORDER BY id ASC LIMIT 1`, q.table), q.queue, now)
+		if err := row.Scan(&id, &payload); err != nil {
+			return nil, err
+		}
 
-	_, err := q.db.Exec(fmt.Sprintf(`UPDATE %s SET reserved_at = ? WHERE id = ?`, q.table), now, id)
-	if err != nil {
-		return nil, err
+		res, err := q.db.Exec(fmt.Sprintf(`UPDATE %s SET reserved_at = ? WHERE id = ? AND reserved_at IS NULL`, q.table), now, id)
+		if err != nil {
+			return nil, err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 1 {
+			break
+		}
 	}
 
 	var job NamedJob
@@ -190,34 +274,36 @@ ORDER BY id ASC LIMIT 1`, q.table), now)
 		return nil, err
 	}
 
-	return &ReservedJob{
-		ID:  id,
-		Job: job,
-		delete: func() error {
-			_, err := q.db.Exec(fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, q.table), id)
-			return err
-		},
-		release: func(delay time.Duration) error {
-			available := time.Now().Add(delay).Format("2006-01-02 15:04:05")
-			_, err := q.db.Exec(
-				fmt.Sprintf(`UPDATE %s SET reserved_at = NULL, available_at = ? WHERE id = ?`, q.table),
-				available, id,
-			)
+	reserved := &ReservedJob{ID: id, Job: job}
+	reserved.delete = func() error {
+		_, err := q.db.Exec(fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, q.table), id)
+		return err
+	}
+	reserved.release = func(delay time.Duration) error {
+		raw, err := json.Marshal(reserved.Job)
+		if err != nil {
 			return err
-		},
-	}, nil
+		}
+		available := time.Now().Add(delay).Format("2006-01-02 15:04:05")
+		_, err = q.db.Exec(
+			fmt.Sprintf(`UPDATE %s SET payload = ?, reserved_at = NULL, available_at = ? WHERE id = ?`, q.table),
+			string(raw), available, id,
+		)
+		return err
+	}
+	return reserved, nil
 }
 
 // Size returns pending jobs count.
 func (q *DatabaseQueue) Size() (int, error) {
 	var count int
-	err := q.db.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE reserved_at IS NULL`, q.table)).Scan(&count)
+	err := q.db.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE queue = ? AND reserved_at IS NULL`, q.table), q.queue).Scan(&count)
 	return count, err
 }
 
-// Clear deletes all jobs.
+// Clear deletes all jobs on the queue.
 func (q *DatabaseQueue) Clear() error {
-	_, err := q.db.Exec(fmt.Sprintf(`DELETE FROM %s`, q.table))
+	_, err := q.db.Exec(fmt.Sprintf(`DELETE FROM %s WHERE queue = ?`, q.table), q.queue)
 	return err
 }
 
@@ -226,27 +312,70 @@ type Manager struct {
 	defaultQueue string
 	queues       map[string]Queue
 	handlers     map[string]func(map[string]any) error
+	ctxHandlers  map[string]ContextHandler
 	mu           sync.RWMutex
-	failedMu     sync.Mutex
-	failed       []FailedJob
+	failed       FailedJobStore
+	batches      BatchStore
+	maxTries     int
+	dispatcher   Dispatcher
+	middleware   map[string][]JobMiddleware
+	locker       Locker
+	limiter      *ratelimit.Limiter
 }
 
-// NewManager creates a queue manager.
+// ContextHandler is a job handler that receives a context, cancelled when
+// the job's Timeout elapses.
+type ContextHandler func(ctx context.Context, payload map[string]any) error
+
+// NamedQueues is implemented by drivers holding several named queues on one
+// connection, like the "high" and "default" queues of a jobs table.
+type NamedQueues interface {
+	OnQueue(name string) Queue
+}
+
+// DefaultMaxTries is the number of attempts for jobs without MaxTries.
+const DefaultMaxTries = 1
+
+// NewManager creates a queue manager. Failed jobs and batches are kept in
+// memory until SetFailedJobStore and SetBatchStore are called.
 func NewManager(defaultQueue string, queues map[string]Queue) *Manager {
 	return &Manager{
 		defaultQueue: defaultQueue,
 		queues:       queues,
 		handlers:     make(map[string]func(map[string]any) error),
+		ctxHandlers:  make(map[string]ContextHandler),
+		failed:       NewMemoryFailedJobStore(),
+		batches:      NewMemoryBatchStore(),
+		locker:       NewLockLocker(lock.New()),
+		maxTries:     DefaultMaxTries,
 	}
 }
 
-// Queue returns a named queue.
+// SetMaxTries sets the attempts allowed for jobs that do not set MaxTries.
+func (m *Manager) SetMaxTries(n int) {
+	if n < 1 {
+		n = 1
+	}
+	m.mu.Lock()
+	m.maxTries = n
+	m.mu.Unlock()
+}
+
+// Queue returns a named queue: a configured connection ("database",
+// "redis"), or else a queue of that name on the default connection when its
+// driver implements NamedQueues.
 func (m *Manager) Queue(name ...string) Queue {
 	queueName := m.defaultQueue
 	if len(name) > 0 && name[0] != "" {
 		queueName = name[0]
 	}
-	return m.queues[queueName]
+	if q, ok := m.queues[queueName]; ok {
+		return q
+	}
+	if named, ok := m.queues[m.defaultQueue].(NamedQueues); ok {
+		return named.OnQueue(queueName)
+	}
+	return nil
 }
 
 // Register registers a job handler on all capable queues.
@@ -267,9 +396,30 @@ func (m *Manager) Register(name string, handler func(map[string]any) error) {
 	}
 }
 
+// RegisterContext registers a job handler that receives a context carrying
+// the job's Timeout. Queues that run jobs outside Work (SyncQueue, Chain) call
+// it with context.Background().
+func (m *Manager) RegisterContext(name string, handler ContextHandler) {
+	m.Register(name, func(payload map[string]any) error {
+		return handler(context.Background(), payload)
+	})
+	m.mu.Lock()
+	m.ctxHandlers[name] = handler
+	m.mu.Unlock()
+}
+
 // Push pushes a named job onto the default queue.
 func (m *Manager) Push(name string, payload map[string]any, delay ...time.Duration) error {
-	return m.Queue().Push(NamedJob{Name: name, Payload: payload}, delay...)
+	return m.pushJob(m.Queue(), NamedJob{Name: name, Payload: payload}, delay...)
+}
+
+// PushOn pushes a named job onto the given queue.
+func (m *Manager) PushOn(queueName, name string, payload map[string]any, delay ...time.Duration) error {
+	q := m.Queue(queueName)
+	if q == nil {
+		return fmt.Errorf("queue [%s] is not configured", queueName)
+	}
+	return m.pushJob(q, NamedJob{Name: name, Payload: payload}, delay...)
 }
 
 // Handler returns a registered handler.
@@ -280,30 +430,122 @@ func (m *Manager) Handler(name string) (func(map[string]any) error, bool) {
 	return handler, ok
 }
 
-// Work pops and processes one job.
+// Work pops and processes one job. A failed attempt is released with the
+// job's backoff until its tries (or RetryUntil) run out; the job is then
+// deleted from the queue and recorded in the failed job store.
 func (m *Manager) Work(queueName ...string) error {
-	q := m.Queue(queueName...)
+	return m.WorkContext(context.Background(), queueName...)
+}
+
+// WorkContext is Work with a parent context for the job's handler.
+func (m *Manager) WorkContext(ctx context.Context, queueName ...string) error {
+	name := m.defaultQueue
+	if len(queueName) > 0 && queueName[0] != "" {
+		name = queueName[0]
+	}
+	q := m.Queue(name)
+	if q == nil {
+		return fmt.Errorf("queue [%s] is not configured", name)
+	}
 	job, err := q.Pop()
 	if err != nil {
 		return err
 	}
-	handler, ok := m.Handler(job.Job.Name)
+	return m.process(ctx, name, q, job)
+}
+
+// process runs a reserved job and deletes, releases or fails it.
+func (m *Manager) process(ctx context.Context, queueName string, q Queue, job *ReservedJob) error {
+	job.Job.Attempts++
+	m.dispatch(EventJobProcessing, JobEvent{Queue: queueName, ID: job.ID, Job: job.Job, At: time.Now()})
+	start := time.Now()
+	middleware := m.middlewareFor(job.Job.Name)
+	if m.batchCancelled(job.Job) {
+		if err := job.Delete(); err != nil {
+			return err
+		}
+		m.jobDone(middleware, job.Job)
+		m.batchJobSucceeded(job.Job)
+		return nil
+	}
+	m.mu.RLock()
+	tries := m.maxTries
+	m.mu.RUnlock()
+	if previous := job.Job; previous.Attempts > 1 {
+		previous.Attempts--
+		if previous.exhausted(tries, time.Now()) {
+			return m.attemptFailed(queueName, job, fmt.Errorf("%w: [%s]", ErrMaxAttemptsExceeded, job.Job.Name))
+		}
+	}
+	handler, ok := m.contextHandler(q, job.Job.Name)
+	if !ok {
+		return m.attemptFailed(queueName, job, fmt.Errorf("no handler for job [%s]", job.Job.Name))
+	}
+	run := func(ctx context.Context) error { return runJob(ctx, handler, job.Job) }
+	for i := len(middleware) - 1; i >= 0; i-- {
+		mw, next := middleware[i], run
+		run = func(ctx context.Context) error { return mw.Handle(ctx, m, job.Job, next) }
+	}
+	if err := run(ctx); err != nil {
+		var release *releaseError
+		if errors.As(err, &release) {
+			job.Job.Attempts--
+			return job.Release(release.delay)
+		}
+		return m.attemptFailed(queueName, job, err)
+	}
+	if err := job.Delete(); err != nil {
+		return err
+	}
+	m.jobDone(middleware, job.Job)
+	m.batchJobSucceeded(job.Job)
+	m.dispatch(EventJobProcessed, JobEvent{Queue: queueName, ID: job.ID, Job: job.Job, Duration: time.Since(start), At: time.Now()})
+	return nil
+}
+
+// contextHandler resolves the handler for a job name: context handlers
+// first, then manager handlers, then handlers registered on the queue.
+func (m *Manager) contextHandler(q Queue, name string) (ContextHandler, bool) {
+	m.mu.RLock()
+	handler, ok := m.ctxHandlers[name]
+	m.mu.RUnlock()
+	if ok {
+		return handler, true
+	}
+	plain, ok := m.Handler(name)
 	if !ok {
 		switch typed := q.(type) {
 		case *DatabaseQueue:
-			handler, ok = typed.Handler(job.Job.Name)
+			plain, ok = typed.Handler(name)
 		case *RedisQueue:
-			handler, ok = typed.Handler(job.Job.Name)
+			plain, ok = typed.Handler(name)
 		}
 	}
 	if !ok {
-		_ = job.Release(5 * time.Second)
-		return fmt.Errorf("no handler for job [%s]", job.Job.Name)
+		return nil, false
 	}
-	if err := handler(job.Job.Payload); err != nil {
-		m.Fail(job.Job, err)
-		_ = job.Release(5 * time.Second)
+	return func(_ context.Context, payload map[string]any) error { return plain(payload) }, true
+}
+
+// attemptFailed releases job for another attempt, or moves it to the failed
+// jobs once it is exhausted. It returns err.
+func (m *Manager) attemptFailed(queueName string, job *ReservedJob, err error) error {
+	m.mu.RLock()
+	tries := m.maxTries
+	m.mu.RUnlock()
+	if !job.Job.exhausted(tries, time.Now()) {
+		_ = job.Release(job.Job.backoffFor(job.Job.Attempts))
 		return err
 	}
-	return job.Delete()
+	failedID, logErr := m.logFailure(queueName, job.Job, err)
+	if logErr != nil {
+		// Keep the job rather than lose it; it fails again once reserved.
+		_ = job.Release(job.Job.backoffFor(job.Job.Attempts))
+		return errors.Join(err, logErr)
+	}
+	_ = job.Delete()
+	m.jobDone(m.middlewareFor(job.Job.Name), job.Job)
+	m.batchJobFailed(job.Job, failedID)
+	m.dispatch(EventJobFailed, JobEvent{Queue: queueName, ID: job.ID, Job: job.Job, Err: err, At: time.Now()})
+	return err
 }
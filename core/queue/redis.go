@@ -2,21 +2,32 @@ package queue
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
-	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-// RedisQueue is a Redis list-backed queue.
+// DefaultRetryAfter is how long a RedisQueue reservation lasts before the
+// job is handed to another worker.
+const DefaultRetryAfter = 90 * time.Second
+
+// RedisQueue is a Redis list-backed queue. Delayed and released jobs wait in
+// the "<key>:delayed" sorted set until they are due, and reserved jobs are
+// kept in "<key>:reserved" until they are deleted or released; a reservation
+// older than the retry-after window (a crashed worker) is returned to the
+// queue, like a stale reserved_at row in DatabaseQueue.
 type RedisQueue struct {
-	client   *redis.Client
-	key      string
-	handlers map[string]func(map[string]any) error
-	mu       sync.RWMutex
+	client     *redis.Client
+	key        string
+	retryAfter time.Duration
+	root       *RedisQueue
+	handlers   map[string]func(map[string]any) error
+	mu         sync.RWMutex
 }
 
 // NewRedisQueue creates a Redis queue.
@@ -25,14 +36,44 @@ func NewRedisQueue(client *redis.Client, key string) *RedisQueue {
 		key = "zatrano:queues:default"
 	}
 	return &RedisQueue{
-		client:   client,
-		key:      key,
-		handlers: make(map[string]func(map[string]any) error),
+		client:     client,
+		key:        key,
+		retryAfter: DefaultRetryAfter,
+		handlers:   make(map[string]func(map[string]any) error),
+	}
+}
+
+// SetRetryAfter sets how long a reservation lasts (default 90s). It should
+// exceed the longest job, or a slow job runs twice; jobs whose Timeout is
+// longer extend their own reservation.
+func (q *RedisQueue) SetRetryAfter(d time.Duration) {
+	if d > 0 {
+		q.retryAfter = d
+	}
+}
+
+// OnQueue returns the named queue, stored under the same key prefix (for
+// "zatrano:queues:default", "zatrano:queues:<name>"); it shares this queue's
+// handlers.
+func (q *RedisQueue) OnQueue(name string) Queue {
+	root := q
+	if q.root != nil {
+		root = q.root
+	}
+	return &RedisQueue{
+		client:     q.client,
+		key:        q.key[:strings.LastIndex(q.key, ":")+1] + name,
+		retryAfter: q.retryAfter,
+		root:       root,
 	}
 }
 
 // Register registers a job handler.
 func (q *RedisQueue) Register(name string, handler func(map[string]any) error) {
+	if q.root != nil {
+		q.root.Register(name, handler)
+		return
+	}
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	q.handlers[name] = handler
@@ -40,6 +81,9 @@ func (q *RedisQueue) Register(name string, handler func(map[string]any) error) {
 
 // Handler returns a registered handler.
 func (q *RedisQueue) Handler(name string) (func(map[string]any) error, bool) {
+	if q.root != nil {
+		return q.root.Handler(name)
+	}
 	q.mu.RLock()
 	defer q.mu.RUnlock()
 	handler, ok := q.handlers[name]
@@ -47,18 +91,65 @@ func (q *RedisQueue) Handler(name string) (func(map[string]any) error, bool) {
 }
 
 type redisPayload struct {
+	// Attempts counts reservations. popScript increments it in the
+	// reserved copy, so a job reaped from a crashed worker still has the
+	// attempt counted; it must stay the first field.
+	Attempts    int       `json:"attempts"`
 	Job         NamedJob  `json:"job"`
 	AvailableAt time.Time `json:"available_at"`
 	ID          string    `json:"id"`
 }
 
-// Push stores a job.
+// migrateScript moves the members of the sorted set KEYS[1] scored at or
+// before ARGV[1] onto the list KEYS[2], oldest first.
+var migrateScript = redis.NewScript(`
+local jobs = redis.call('zrangebyscore', KEYS[1], '-inf', ARGV[1])
+if #jobs > 0 then
+	redis.call('zremrangebyrank', KEYS[1], 0, #jobs - 1)
+	for i = 1, #jobs, 100 do
+		redis.call('lpush', KEYS[2], unpack(jobs, i, math.min(i + 99, #jobs)))
+	end
+end
+return #jobs
+`)
+
+// popScript takes the next job off the list KEYS[1], increments its leading
+// attempts field and reserves it in KEYS[2] until ARGV[1].
+var popScript = redis.NewScript(`
+local job = redis.call('rpop', KEYS[1])
+if not job then
+	return false
+end
+local attempts, rest = string.match(job, '^{"attempts":(%d+),(.*)$')
+if attempts then
+	job = '{"attempts":' .. (tonumber(attempts) + 1) .. ',' .. rest
+end
+redis.call('zadd', KEYS[2], ARGV[1], job)
+return job
+`)
+
+// releaseScript moves the reservation ARGV[1] from KEYS[1] to the delayed set
+// KEYS[2] as ARGV[2], due at ARGV[3]. A reservation that already expired has
+// been returned to the queue and is left alone.
+var releaseScript = redis.NewScript(`
+if redis.call('zrem', KEYS[1], ARGV[1]) == 1 then
+	redis.call('zadd', KEYS[2], ARGV[3], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+func (q *RedisQueue) delayedKey() string  { return q.key + ":delayed" }
+func (q *RedisQueue) reservedKey() string { return q.key + ":reserved" }
+
+// Push stores a job; a delayed job waits in the delayed set until it is due.
 func (q *RedisQueue) Push(job NamedJob, delay ...time.Duration) error {
 	wait := time.Duration(0)
 	if len(delay) > 0 {
 		wait = delay[0]
 	}
 	payload := redisPayload{
+		Attempts:    job.Attempts,
 		Job:         job,
 		AvailableAt: time.Now().Add(wait),
 		ID:          strconv.FormatInt(time.Now().UnixNano(), 10),
@@ -67,56 +158,96 @@ func (q *RedisQueue) Push(job NamedJob, delay ...time.Duration) error {
 	if err != nil {
 		return err
 	}
-	return q.client.LPush(context.Background(), q.key, raw).Err()
+	ctx := context.Background()
+	if wait > 0 {
+		return q.client.ZAdd(ctx, q.delayedKey(), redis.Z{Score: redisScore(payload.AvailableAt), Member: raw}).Err()
+	}
+	return q.client.LPush(ctx, q.key, raw).Err()
+}
+
+// MigrateExpired moves due delayed jobs and expired reservations back onto
+// the queue and returns how many were moved. Pop calls it before reserving,
+// so running workers reap the jobs of crashed ones.
+func (q *RedisQueue) MigrateExpired() (int, error) {
+	ctx := context.Background()
+	now := redisScore(time.Now())
+	total := 0
+	for _, set := range []string{q.delayedKey(), q.reservedKey()} {
+		n, err := migrateScript.Run(ctx, q.client, []string{set, q.key}, now).Int()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
 }
 
-// Pop reserves the next available job.
+// Pop reserves the next available job until the retry-after window elapses.
 func (q *RedisQueue) Pop() (*ReservedJob, error) {
 	ctx := context.Background()
+	if _, err := q.MigrateExpired(); err != nil {
+		return nil, err
+	}
 	for {
-		raw, err := q.client.RPop(ctx, q.key).Bytes()
+		expires := time.Now().Add(q.retryAfter)
+		raw, err := popScript.Run(ctx, q.client, []string{q.key, q.reservedKey()}, redisScore(expires)).Text()
 		if err == redis.Nil {
-			return nil, fmt.Errorf("sql: no rows in result set")
+			return nil, sql.ErrNoRows
 		}
 		if err != nil {
 			return nil, err
 		}
 
 		var payload redisPayload
-		if err := json.Unmarshal(raw, &payload); err != nil {
+		if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+			_ = q.client.ZRem(ctx, q.reservedKey(), raw).Err()
 			continue
 		}
-		if time.Now().Before(payload.AvailableAt) {
-			_ = q.client.LPush(ctx, q.key, raw).Err()
-			return nil, fmt.Errorf("sql: no rows in result set")
+		if payload.Job.Timeout >= q.retryAfter {
+			expires = time.Now().Add(payload.Job.Timeout + q.retryAfter)
+			_ = q.client.ZAddXX(ctx, q.reservedKey(), redis.Z{Score: redisScore(expires), Member: raw}).Err()
 		}
 
+		// process counts the attempt in Job.Attempts itself.
+		payload.Job.Attempts = payload.Attempts - 1
 		id, _ := strconv.ParseInt(payload.ID, 10, 64)
-		return &ReservedJob{
-			ID:  id,
-			Job: payload.Job,
-			delete: func() error {
-				return nil
-			},
-			release: func(delay time.Duration) error {
-				payload.AvailableAt = time.Now().Add(delay)
-				raw, err := json.Marshal(payload)
-				if err != nil {
-					return err
-				}
-				return q.client.LPush(ctx, q.key, raw).Err()
-			},
-		}, nil
+		reserved := &ReservedJob{ID: id, Job: payload.Job}
+		reserved.delete = func() error {
+			return q.client.ZRem(ctx, q.reservedKey(), raw).Err()
+		}
+		reserved.release = func(delay time.Duration) error {
+			next := payload
+			next.Job = reserved.Job
+			next.Attempts = reserved.Job.Attempts
+			next.AvailableAt = time.Now().Add(delay)
+			released, err := json.Marshal(next)
+			if err != nil {
+				return err
+			}
+			return releaseScript.Run(ctx, q.client, []string{q.reservedKey(), q.delayedKey()}, raw, released, redisScore(next.AvailableAt)).Err()
+		}
+		return reserved, nil
 	}
 }
 
-// Size returns queue size.
+// Size returns the number of waiting jobs, delayed ones included.
 func (q *RedisQueue) Size() (int, error) {
-	n, err := q.client.LLen(context.Background(), q.key).Result()
-	return int(n), err
+	ctx := context.Background()
+	pipe := q.client.Pipeline()
+	ready := pipe.LLen(ctx, q.key)
+	delayed := pipe.ZCard(ctx, q.delayedKey())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(ready.Val() + delayed.Val()), nil
 }
 
-// Clear deletes all jobs.
+// Clear deletes all jobs, reserved ones included.
 func (q *RedisQueue) Clear() error {
-	return q.client.Del(context.Background(), q.key).Err()
+	return q.client.Del(context.Background(), q.key, q.delayedKey(), q.reservedKey()).Err()
+}
+
+// redisScore converts t to a sorted set score in milliseconds.
+func redisScore(t time.Time) float64 {
+	return float64(t.UnixMilli())
 }
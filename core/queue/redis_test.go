@@ -0,0 +1,131 @@
+package queue_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/zatrano/framework/core/queue"
+)
+
+func setupRedisQueue(t *testing.T) (*redis.Client, *queue.RedisQueue) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client, queue.NewRedisQueue(client, "test:queues:default")
+}
+
+func TestRedisQueueDelayedJobs(t *testing.T) {
+	client, q := setupRedisQueue(t)
+	ctx := context.Background()
+
+	if err := q.Push(queue.NamedJob{Name: "later"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Push(queue.NamedJob{Name: "soon"}, 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if n := client.ZCard(ctx, "test:queues:default:delayed").Val(); n != 2 {
+		t.Fatalf("delayed=%d", n)
+	}
+	if size, _ := q.Size(); size != 2 {
+		t.Fatalf("size=%d", size)
+	}
+	if _, err := q.Pop(); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected no due job, got %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	job, err := q.Pop()
+	if err != nil || job.Job.Name != "soon" {
+		t.Fatalf("job=%+v err=%v", job, err)
+	}
+	if n := client.ZCard(ctx, "test:queues:default:reserved").Val(); n != 1 {
+		t.Fatalf("reserved=%d", n)
+	}
+	if err := job.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if n := client.ZCard(ctx, "test:queues:default:reserved").Val(); n != 0 {
+		t.Fatalf("reserved after delete=%d", n)
+	}
+	if size, _ := q.Size(); size != 1 {
+		t.Fatalf("size=%d", size)
+	}
+}
+
+func TestRedisQueueReleaseAndReap(t *testing.T) {
+	client, q := setupRedisQueue(t)
+	ctx := context.Background()
+	q.SetRetryAfter(30 * time.Millisecond)
+
+	if err := q.Push(queue.NamedJob{Name: "sync", Payload: map[string]any{"id": 7}}); err != nil {
+		t.Fatal(err)
+	}
+	job, err := q.Pop()
+	if err != nil || job.Job.Attempts != 0 {
+		t.Fatalf("job=%+v err=%v", job, err)
+	}
+	job.Job.Attempts++
+	if err := job.Release(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if client.ZCard(ctx, "test:queues:default:reserved").Val() != 0 || client.ZCard(ctx, "test:queues:default:delayed").Val() != 1 {
+		t.Fatal("released job not moved to the delayed set")
+	}
+	if err := q.Clear(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.Push(queue.NamedJob{Name: "sync"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Pop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Pop(); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("reserved job handed out twice: %v", err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if n, err := q.MigrateExpired(); err != nil || n != 1 {
+		t.Fatalf("reaped=%d err=%v", n, err)
+	}
+	reaped, err := q.Pop()
+	if err != nil || reaped.Job.Attempts != 1 {
+		t.Fatalf("reaped=%+v err=%v", reaped, err)
+	}
+}
+
+func TestRedisQueueFailsJobsThatKeepCrashing(t *testing.T) {
+	_, q := setupRedisQueue(t)
+	q.SetRetryAfter(10 * time.Millisecond)
+	m := queue.NewManager("redis", map[string]queue.Queue{"redis": q})
+	ran := 0
+	m.Register("oom", func(map[string]any) error {
+		ran++
+		return nil
+	})
+	if err := m.Push("oom", nil); err != nil {
+		t.Fatal(err)
+	}
+	// The worker dies after reserving the job: nothing deletes or releases it.
+	if _, err := q.Pop(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if err := m.Work(); !errors.Is(err, queue.ErrMaxAttemptsExceeded) {
+		t.Fatalf("err=%v", err)
+	}
+	if ran != 0 || len(m.Failed()) != 1 {
+		t.Fatalf("ran=%d failed=%v", ran, m.Failed())
+	}
+	if size, _ := q.Size(); size != 0 {
+		t.Fatalf("size=%d", size)
+	}
+}
@@ -0,0 +1,204 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	EventJobProcessing = "queue.job_processing"
+	EventJobProcessed  = "queue.job_processed"
+	EventJobFailed     = "queue.job_failed"
+)
+
+// Dispatcher receives queue lifecycle events; *events.Dispatcher implements it.
+type Dispatcher interface {
+	Dispatch(name string, event any) error
+}
+
+// JobEvent is the payload of the queue lifecycle events. Err is set on
+// JobFailed, Duration on JobProcessed.
+type JobEvent struct {
+	Queue    string
+	ID       int64
+	Job      NamedJob
+	Err      error
+	Duration time.Duration
+	At       time.Time
+}
+
+type JobProcessing = JobEvent
+type JobProcessed = JobEvent
+
+// JobFailed is dispatched when a job runs out of attempts and is recorded as failed.
+type JobFailed = JobEvent
+
+// SetDispatcher wires the dispatcher that receives JobProcessing,
+// JobProcessed and JobFailed events.
+func (m *Manager) SetDispatcher(d Dispatcher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dispatcher = d
+}
+
+func (m *Manager) dispatch(name string, event JobEvent) {
+	m.mu.RLock()
+	d := m.dispatcher
+	m.mu.RUnlock()
+	if d != nil {
+		_ = d.Dispatch(name, event)
+	}
+}
+
+// WorkerOptions configures a Worker. Zero values mean no limit.
+type WorkerOptions struct {
+	// Queues are checked in priority order for every job; empty uses the
+	// default queue.
+	Queues []string
+	// Concurrency is the number of jobs processed at once (default 1).
+	Concurrency int
+	// Sleep is the pause after finding every queue empty (default 3s).
+	Sleep time.Duration
+	// MaxJobs stops the worker after processing this many jobs.
+	MaxJobs int
+	// MaxTime stops the worker after running this long.
+	MaxTime time.Duration
+	// MemoryMB stops the worker once the memory obtained from the OS
+	// exceeds this many megabytes.
+	MemoryMB uint64
+	// StopWhenEmpty stops the worker once every queue is empty.
+	StopWhenEmpty bool
+	// OnError receives errors from failed jobs and unreachable queues.
+	OnError func(err error)
+}
+
+// Worker processes jobs until it is stopped or reaches a limit.
+type Worker struct {
+	manager   *Manager
+	opts      WorkerOptions
+	claimed   atomic.Int64
+	processed atomic.Int64
+}
+
+// NewWorker creates a worker for the manager's queues.
+func NewWorker(m *Manager, opts WorkerOptions) *Worker {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if opts.Sleep <= 0 {
+		opts.Sleep = 3 * time.Second
+	}
+	if len(opts.Queues) == 0 {
+		opts.Queues = []string{m.defaultQueue}
+	}
+	return &Worker{manager: m, opts: opts}
+}
+
+// Processed returns the number of jobs the worker has processed.
+func (w *Worker) Processed() int64 {
+	return w.processed.Load()
+}
+
+// Run processes jobs until ctx is cancelled or a limit is reached, then
+// waits for in-flight jobs to finish. Cancelling ctx does not cancel running
+// jobs; their handlers only see their own Timeout. Wire SIGTERM with
+// signal.NotifyContext for a graceful shutdown.
+func (w *Worker) Run(ctx context.Context) {
+	jobCtx := context.WithoutCancel(ctx)
+	if w.opts.MaxTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.opts.MaxTime)
+		defer cancel()
+	}
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx, jobCtx, stop)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) loop(ctx, jobCtx context.Context, stop context.CancelFunc) {
+	for ctx.Err() == nil {
+		if w.opts.MaxJobs > 0 && w.claimed.Add(1) > int64(w.opts.MaxJobs) {
+			stop()
+			return
+		}
+		ran := w.runNext(jobCtx)
+		if !ran {
+			if w.opts.MaxJobs > 0 {
+				w.claimed.Add(-1)
+			}
+			if w.opts.StopWhenEmpty {
+				stop()
+				return
+			}
+			sleep(ctx, w.opts.Sleep)
+			continue
+		}
+		if w.opts.MaxJobs > 0 && w.processed.Load() >= int64(w.opts.MaxJobs) {
+			stop()
+		}
+		if w.opts.MemoryMB > 0 && memoryMB() >= w.opts.MemoryMB {
+			stop()
+		}
+	}
+}
+
+// runNext processes the first available job of the highest priority queue
+// and reports whether one was found.
+func (w *Worker) runNext(ctx context.Context) bool {
+	for _, name := range w.opts.Queues {
+		q := w.manager.Queue(name)
+		if q == nil {
+			continue
+		}
+		job, err := q.Pop()
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			w.report(err)
+			continue
+		}
+		err = w.manager.process(ctx, name, q, job)
+		w.processed.Add(1)
+		if err != nil {
+			w.report(err)
+		}
+		return true
+	}
+	return false
+}
+
+func (w *Worker) report(err error) {
+	if w.opts.OnError != nil {
+		w.opts.OnError(err)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+func memoryMB() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.Sys / 1024 / 1024
+}
@@ -0,0 +1,108 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zatrano/framework/core/events"
+	"github.com/zatrano/framework/core/queue"
+)
+
+func TestWorkerPriorityConcurrencyAndEvents(t *testing.T) {
+	db, m := setupFailedQueue(t)
+	defer db.Close()
+
+	dispatcher := events.New()
+	m.SetDispatcher(dispatcher)
+	var mu sync.Mutex
+	seen := map[string]int{}
+	for _, name := range []string{queue.EventJobProcessing, queue.EventJobProcessed, queue.EventJobFailed} {
+		dispatcher.Listen(name, func(event any) error {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[name]++
+			return nil
+		})
+	}
+
+	var order []string
+	m.Register("record", func(payload map[string]any) error {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, payload["queue"].(string))
+		return nil
+	})
+	m.RegisterContext("hang", func(ctx context.Context, payload map[string]any) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	_ = m.PushOn("low", "record", map[string]any{"queue": "low"})
+	_ = m.PushOn("high", "record", map[string]any{"queue": "high"})
+	_ = m.PushOn("high", "record", map[string]any{"queue": "high"})
+
+	worker := queue.NewWorker(m, queue.WorkerOptions{Queues: []string{"high", "low"}, StopWhenEmpty: true})
+	worker.Run(context.Background())
+	if worker.Processed() != 3 || len(order) != 3 || order[0] != "high" || order[1] != "high" || order[2] != "low" {
+		t.Fatalf("processed=%d order=%v", worker.Processed(), order)
+	}
+
+	for i := 0; i < 4; i++ {
+		_ = m.Queue().Push(queue.NamedJob{Name: "record", Payload: map[string]any{"queue": "default"}})
+	}
+	_ = m.Queue().Push(queue.NamedJob{Name: "hang", Timeout: 20 * time.Millisecond})
+	var errs []error
+	worker = queue.NewWorker(m, queue.WorkerOptions{Concurrency: 3, StopWhenEmpty: true, OnError: func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}})
+	worker.Run(context.Background())
+	if worker.Processed() != 5 || len(errs) != 1 || !errors.Is(errs[0], queue.ErrJobTimeout) {
+		t.Fatalf("processed=%d errs=%v", worker.Processed(), errs)
+	}
+	if seen[queue.EventJobProcessing] != 8 || seen[queue.EventJobProcessed] != 7 || seen[queue.EventJobFailed] != 1 {
+		t.Fatalf("seen=%v", seen)
+	}
+}
+
+func TestWorkerLimitsAndGracefulStop(t *testing.T) {
+	db, m := setupFailedQueue(t)
+	defer db.Close()
+
+	started := make(chan struct{}, 10)
+	finished := 0
+	m.Register("slow", func(map[string]any) error {
+		started <- struct{}{}
+		time.Sleep(50 * time.Millisecond)
+		finished++
+		return nil
+	})
+	for i := 0; i < 5; i++ {
+		_ = m.Queue().Push(queue.NamedJob{Name: "slow"})
+	}
+
+	worker := queue.NewWorker(m, queue.WorkerOptions{MaxJobs: 2, Sleep: time.Millisecond})
+	worker.Run(context.Background())
+	if worker.Processed() != 2 {
+		t.Fatalf("max jobs processed=%d", worker.Processed())
+	}
+	<-started
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	worker = queue.NewWorker(m, queue.WorkerOptions{Sleep: time.Millisecond})
+	go func() {
+		<-started
+		cancel()
+	}()
+	worker.Run(ctx)
+	if worker.Processed() != 1 || finished != 3 {
+		t.Fatalf("graceful stop processed=%d finished=%d", worker.Processed(), finished)
+	}
+	if size, _ := m.Queue().Size(); size != 2 {
+		t.Fatalf("size=%d", size)
+	}
+}
@@ -63,6 +63,14 @@ func (l *Limiter) Has(name string) bool {
 	return ok
 }
 
+// Limit returns a named limit registered with For.
+func (l *Limiter) Limit(name string) (Limit, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limit, ok := l.named[name]
+	return limit, ok
+}
+
 // Named returns middleware for a previously registered limit.
 func (l *Limiter) Named(name string) routing.MiddlewareFunc {
 	l.mu.Lock()
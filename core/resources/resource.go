@@ -2,26 +2,35 @@ package resources
 
 import (
 	"github.com/zatrano/framework/core/http"
+	"github.com/zatrano/framework/core/orm"
 	"github.com/zatrano/framework/core/pagination"
 )
 
-// Transformer converts a model into an API array.
+// Transformer converts a model into an API array. A nil Transformer uses
+// orm.ToMap, so the model's Hidden, Visible and Appends apply.
 type Transformer[T any] func(item T) map[string]any
 
 // Make transforms a single item.
 func Make[T any](item T, transform Transformer[T]) map[string]any {
-	return transform(item)
+	return transform.apply(item)
 }
 
 // Collection transforms many items.
 func Collection[T any](items []T, transform Transformer[T]) []map[string]any {
 	out := make([]map[string]any, 0, len(items))
 	for _, item := range items {
-		out = append(out, transform(item))
+		out = append(out, transform.apply(item))
 	}
 	return out
 }
 
+func (t Transformer[T]) apply(item T) map[string]any {
+	if t == nil {
+		return orm.ToMap(item)
+	}
+	return t(item)
+}
+
 // Wrap wraps data under a "data" key.
 func Wrap(data any) map[string]any {
 	return map[string]any{"data": data}
@@ -50,19 +59,20 @@ func Paginate[T any](page *pagination.LengthAware[T], transform Transformer[T])
 	}
 }
 
-// JSON returns a JSON response for a single transformed item.
+// JSON returns a JSON response for a single transformed item. Models left in
+// the transformed output are encoded with orm.Serialize, like nil transformers.
 func JSON[T any](item T, transform Transformer[T]) *http.Response {
-	return http.JSON(Wrap(Make(item, transform)))
+	return http.JSON(orm.Serialize(Wrap(Make(item, transform))))
 }
 
 // JSONCollection returns a JSON response for a collection.
 func JSONCollection[T any](items []T, transform Transformer[T]) *http.Response {
-	return http.JSON(WrapCollection(items, transform))
+	return http.JSON(orm.Serialize(WrapCollection(items, transform)))
 }
 
 // JSONPaginated returns a JSON response for a paginator.
 func JSONPaginated[T any](page *pagination.LengthAware[T], transform Transformer[T]) *http.Response {
-	return http.JSON(Paginate(page, transform))
+	return http.JSON(orm.Serialize(Paginate(page, transform)))
 }
 
 // Merge merges additional attributes into a resource array.
@@ -16,3 +16,23 @@ func TestWrapAndCollection(t *testing.T) {
 		t.Fatalf("unexpected resource: %#v", out)
 	}
 }
+
+type secretUser struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+func (secretUser) Hidden() []string { return []string{"password"} }
+
+func TestNilTransformerUsesModelSerialization(t *testing.T) {
+	out := resources.Make(secretUser{Name: "ada", Password: "hash"}, nil)
+	if out["name"] != "ada" || out["password"] != nil {
+		t.Fatalf("resource=%v", out)
+	}
+	resp := resources.JSONCollection([]secretUser{{Name: "bob", Password: "hash"}}, func(u secretUser) map[string]any {
+		return map[string]any{"user": u}
+	})
+	if body := string(resp.Content()); body != `{"data":[{"user":{"name":"bob"}}]}` {
+		t.Fatalf("body=%s", body)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/zatrano/framework/core/cache"
 	"github.com/zatrano/framework/core/circuit"
 	appcontext "github.com/zatrano/framework/core/context"
+	"github.com/zatrano/framework/core/database/schema"
 	"github.com/zatrano/framework/core/docs"
 	"github.com/zatrano/framework/core/encryption"
 	"github.com/zatrano/framework/core/enums"
@@ -185,10 +186,12 @@ func (app *Application) bootSupportServices() error {
 
 	app.cache = cache.NewManager(env.Get("CACHE_STORE", "file"), stores)
 	app.container.Instance("cache", app.cache)
+	orm.SetCacheStore(app.cache.Store())
 
 	app.events = events.New()
 	app.container.Instance("events", app.events)
 	orm.SetDispatcher(app.events)
+	http.SetJSONTransformer(orm.Serialize)
 
 	logMailer := mail.NewLogMailer(app.logger)
 	smtpMailer := mail.NewSMTPMailer(mail.SMTPConfig{
@@ -223,9 +226,43 @@ func (app *Application) bootSupportServices() error {
 		}
 	}
 	if redisClient != nil {
-		queues["redis"] = queue.NewRedisQueue(redisClient, "zatrano:queues:default")
+		redisQueue := queue.NewRedisQueue(redisClient, "zatrano:queues:default")
+		redisQueue.SetRetryAfter(time.Duration(env.GetInt("QUEUE_RETRY_AFTER", 90)) * time.Second)
+		queues["redis"] = redisQueue
 	}
 	app.queue = queue.NewManager(connection, queues)
+	app.queue.SetDispatcher(app.Events())
+	if redisClient != nil {
+		app.queue.SetLocker(queue.NewRedisLocker(redisClient, "zatrano:queue:locks:"))
+	}
+	if app.db != nil {
+		db, err := app.db.DB()
+		driver, driverErr := app.db.DriverName()
+		if err == nil && driverErr == nil {
+			// failed_jobs, job_batches and job_locks are created by their migrations;
+			// until then the queue keeps them in memory.
+			tables := schema.New(db, driver)
+			migrated := func(table string) bool {
+				ok, err := tables.HasTable(table)
+				if !ok && app.logger != nil {
+					if err == nil {
+						err = fmt.Errorf("table does not exist, run migrate")
+					}
+					app.logger.Warningf("queue: %s unavailable, keeping it in memory: %v", table, err)
+				}
+				return ok
+			}
+			if migrated("failed_jobs") {
+				app.queue.SetFailedJobStore(queue.NewDatabaseFailedJobStore(db, driver, "failed_jobs"))
+			}
+			if migrated("job_batches") {
+				app.queue.SetBatchStore(queue.NewDatabaseBatchStore(db, driver, "job_batches"))
+			}
+			if redisClient == nil && migrated("job_locks") {
+				app.queue.SetLocker(queue.NewDatabaseLocker(db, driver, "job_locks"))
+			}
+		}
+	}
 	app.container.Instance("queue", app.queue)
 
 	authManager := auth.NewManager(app.config.GetString("auth.defaults.guard", "web"))
@@ -386,6 +423,9 @@ func (app *Application) bootSupportServices() error {
 
 	app.metrics = observability.New()
 	app.container.Instance("metrics", app.metrics)
+	if app.db != nil {
+		app.db.SetMetrics(app.metrics)
+	}
 
 	app.health = health.New()
 	if app.db != nil {
@@ -403,6 +443,7 @@ func (app *Application) bootSupportServices() error {
 	app.container.Instance("health", app.health)
 
 	app.rateLimiter = ratelimit.New()
+	app.queue.SetRateLimiter(app.rateLimiter)
 	app.rateLimiter.For("api", ratelimit.Limit{MaxAttempts: 60, Decay: time.Minute})
 	app.rateLimiter.For("login", ratelimit.Limit{MaxAttempts: 5, Decay: time.Minute})
 	app.container.Instance("rateLimiter", app.rateLimiter)
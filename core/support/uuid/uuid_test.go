@@ -2,6 +2,7 @@ package uuid_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/zatrano/framework/core/support/uuid"
 )
@@ -25,3 +26,27 @@ func TestUUID(t *testing.T) {
 		t.Fatal("expected invalid")
 	}
 }
+
+func TestULID(t *testing.T) {
+	first := uuid.NewULID()
+	if len(first) != 26 || !uuid.IsULID(first) {
+		t.Fatalf("invalid ulid: %s", first)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if second := uuid.NewULID(); second <= first {
+		t.Fatalf("ulids not time ordered: %s <= %s", second, first)
+	}
+	var max [16]byte
+	for i := range max {
+		max[i] = 0xff
+	}
+	if got := uuid.FormatULID(max); got != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Fatalf("max ulid=%s", got)
+	}
+	if uuid.FormatULID([16]byte{}) != "00000000000000000000000000" {
+		t.Fatal("zero ulid")
+	}
+	if uuid.IsULID("8ZZZZZZZZZZZZZZZZZZZZZZZZZ") || uuid.IsULID("not-a-ulid") {
+		t.Fatal("expected invalid ulid")
+	}
+}
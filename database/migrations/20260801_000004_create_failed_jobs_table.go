@@ -0,0 +1,26 @@
+package migrations
+
+import "github.com/zatrano/framework/core/database/schema"
+
+// CreateFailedJobsTable creates the failed_jobs table.
+type CreateFailedJobsTable struct{}
+
+func (m *CreateFailedJobsTable) Name() string {
+	return "20260801_000004_create_failed_jobs_table"
+}
+
+func (m *CreateFailedJobsTable) Up(s *schema.Builder) error {
+	return s.Create("failed_jobs", func(table *schema.Blueprint) {
+		table.ID()
+		table.String("uuid", 36).Unique()
+		table.String("queue").Default("default")
+		table.Text("payload")
+		table.Text("exception")
+		table.Text("trace").Nullable()
+		table.Timestamp("failed_at")
+	})
+}
+
+func (m *CreateFailedJobsTable) Down(s *schema.Builder) error {
+	return s.DropIfExists("failed_jobs")
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import "github.com/zatrano/framework/core/database/schema"
+
+// CreateJobBatchesTable creates the job_batches table.
+type CreateJobBatchesTable struct{}
+
+func (m *CreateJobBatchesTable) Name() string {
+	return "20260801_000005_create_job_batches_table"
+}
+
+func (m *CreateJobBatchesTable) Up(s *schema.Builder) error {
+	return s.Create("job_batches", func(table *schema.Blueprint) {
+		table.String("id", 36)
+		table.String("name").Default("")
+		table.Integer("total_jobs")
+		table.Integer("pending_jobs")
+		table.Integer("failed_jobs")
+		table.Text("failed_job_ids")
+		table.Text("options")
+		table.Timestamp("cancelled_at").Nullable()
+		table.Timestamp("created_at")
+		table.Timestamp("finished_at").Nullable()
+		table.Primary("id")
+	})
+}
+
+func (m *CreateJobBatchesTable) Down(s *schema.Builder) error {
+	return s.DropIfExists("job_batches")
+}
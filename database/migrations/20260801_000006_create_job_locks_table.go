@@ -0,0 +1,23 @@
+package migrations
+
+import "github.com/zatrano/framework/core/database/schema"
+
+// CreateJobLocksTable creates the job_locks table.
+type CreateJobLocksTable struct{}
+
+func (m *CreateJobLocksTable) Name() string {
+	return "20260801_000006_create_job_locks_table"
+}
+
+func (m *CreateJobLocksTable) Up(s *schema.Builder) error {
+	return s.Create("job_locks", func(table *schema.Blueprint) {
+		table.String("name")
+		table.String("owner")
+		table.BigInteger("expires_at")
+		table.Primary("name")
+	})
+}
+
+func (m *CreateJobLocksTable) Down(s *schema.Builder) error {
+	return s.DropIfExists("job_locks")
+}
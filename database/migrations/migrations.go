@@ -7,5 +7,8 @@ func All() []migration.Migration {
 	return []migration.Migration{
 		&CreateJobsTable{},
 		&CreateNotificationsTable{},
+		&CreateFailedJobsTable{},
+		&CreateJobBatchesTable{},
+		&CreateJobLocksTable{},
 	}
 }